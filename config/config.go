@@ -0,0 +1,512 @@
+// Package config loads the settings the API needs to start. Every setting
+// has a default, which an optional YAML file overrides, which environment
+// variables (also read from an optional .env file) override, which command
+// line flags override. The result is checked before anything connects.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the settings. Each field names its YAML key, environment
+// variable and flag.
+type Config struct {
+	MongoURI            string        `yaml:"mongo_uri" env:"MONGODB_URL" flag:"mongo-uri" usage:"MongoDB connection string"`
+	MongoDatabase       string        `yaml:"mongo_database" env:"MONGODB_DATABASE" flag:"mongo-database" usage:"MongoDB database name"`
+	MongoConnectTimeout time.Duration `yaml:"mongo_connect_timeout" env:"MONGODB_CONNECT_TIMEOUT" flag:"mongo-connect-timeout" usage:"time allowed to connect to MongoDB"`
+	Port                string        `yaml:"port" env:"PORT" flag:"port" usage:"HTTP port"`
+	SecretKey           string        `yaml:"secret_key" env:"SECRET_KEY" flag:"secret-key" usage:"key that signs tokens"`
+	AccessTokenTTL      time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL" flag:"access-token-ttl" usage:"lifetime of access tokens"`
+	RefreshTokenTTL     time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" flag:"refresh-token-ttl" usage:"lifetime of refresh tokens and sessions"`
+	BcryptCost          int           `yaml:"bcrypt_cost" env:"BCRYPT_COST" flag:"bcrypt-cost" usage:"bcrypt cost of password hashes"`
+	DefaultPageLimit    int           `yaml:"default_page_limit" env:"DEFAULT_PAGE_LIMIT" flag:"default-page-limit" usage:"items per page when no limit is given"`
+	MaxPageLimit        int           `yaml:"max_page_limit" env:"MAX_PAGE_LIMIT" flag:"max-page-limit" usage:"largest limit a list accepts"`
+	DBTimeout           time.Duration `yaml:"db_timeout" env:"DB_TIMEOUT" flag:"db-timeout" usage:"deadline of the database work of one request"`
+	ShutdownTimeout     time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"time in-flight requests get to finish on shutdown"`
+	ShutdownDrainDelay  time.Duration `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY" flag:"shutdown-drain-delay" usage:"time /readyz reports not ready before the server stops accepting requests"`
+	LogLevel            string        `yaml:"log_level" env:"LOG_LEVEL" flag:"log-level" usage:"lowest level logged: debug, info, warn or error"`
+	MongoIndexTimeout   time.Duration `yaml:"mongo_index_timeout" env:"MONGODB_INDEX_TIMEOUT" flag:"mongo-index-timeout" usage:"time allowed to create the indexes at startup"`
+	TrustedProxies      []string      `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"IPs or CIDRs of proxies whose X-Forwarded-For is believed"`
+
+	AppURL                  string   `yaml:"app_url" env:"APP_URL" flag:"app-url" usage:"public address links in emails point to"`
+	SMTPHost                string   `yaml:"smtp_host" env:"SMTP_HOST" flag:"smtp-host" usage:"SMTP server; emails are logged when empty"`
+	SMTPPort                string   `yaml:"smtp_port" env:"SMTP_PORT" flag:"smtp-port" usage:"SMTP port"`
+	SMTPUsername            string   `yaml:"smtp_username" env:"SMTP_USERNAME" flag:"smtp-username" usage:"SMTP user, empty for servers without authentication"`
+	SMTPPassword            string   `yaml:"smtp_password" env:"SMTP_PASSWORD" flag:"smtp-password" usage:"SMTP password"`
+	MailFrom                string   `yaml:"mail_from" env:"MAIL_FROM" flag:"mail-from" usage:"sender address of account emails"`
+	DefaultPhoneCountryCode string   `yaml:"default_phone_country_code" env:"DEFAULT_PHONE_COUNTRY_CODE" flag:"default-phone-country-code" usage:"country code of phone numbers given without one"`
+	TOTPIssuer              string   `yaml:"totp_issuer" env:"TOTP_ISSUER" flag:"totp-issuer" usage:"name authenticator apps show for the account"`
+	UnverifiedRestrictions  []string `yaml:"unverified_restrictions" env:"UNVERIFIED_RESTRICTIONS" flag:"unverified-restrictions" usage:"actions unverified accounts may not perform, or none"`
+
+	PasswordMinLength      int    `yaml:"password_min_length" env:"PASSWORD_MIN_LENGTH" flag:"password-min-length" usage:"shortest allowed password"`
+	PasswordMaxLength      int    `yaml:"password_max_length" env:"PASSWORD_MAX_LENGTH" flag:"password-max-length" usage:"longest allowed password, at most 72"`
+	PasswordRequireUpper   bool   `yaml:"password_require_upper" env:"PASSWORD_REQUIRE_UPPER" flag:"password-require-upper" usage:"require an uppercase letter"`
+	PasswordRequireLower   bool   `yaml:"password_require_lower" env:"PASSWORD_REQUIRE_LOWER" flag:"password-require-lower" usage:"require a lowercase letter"`
+	PasswordRequireDigit   bool   `yaml:"password_require_digit" env:"PASSWORD_REQUIRE_DIGIT" flag:"password-require-digit" usage:"require a digit"`
+	PasswordRequireSymbol  bool   `yaml:"password_require_symbol" env:"PASSWORD_REQUIRE_SYMBOL" flag:"password-require-symbol" usage:"require a symbol"`
+	PasswordRejectPersonal bool   `yaml:"password_reject_personal" env:"PASSWORD_REJECT_PERSONAL" flag:"password-reject-personal" usage:"reject passwords containing the user's name or email"`
+	PasswordRejectBreached bool   `yaml:"password_reject_breached" env:"PASSWORD_REJECT_BREACHED" flag:"password-reject-breached" usage:"reject known breached passwords"`
+	BreachedPasswordsFile  string `yaml:"breached_passwords_file" env:"BREACHED_PASSWORDS_FILE" flag:"breached-passwords-file" usage:"extra breached passwords, one per line or as SHA-1 hashes"`
+
+	LoginFailureWindow    time.Duration `yaml:"login_failure_window" env:"LOGIN_FAILURE_WINDOW" flag:"login-failure-window" usage:"time after which failed logins are forgotten"`
+	LoginLockoutBase      time.Duration `yaml:"login_lockout_base" env:"LOGIN_LOCKOUT_BASE" flag:"login-lockout-base" usage:"first lockout once the failure limit is reached"`
+	LoginLockoutMax       time.Duration `yaml:"login_lockout_max" env:"LOGIN_LOCKOUT_MAX" flag:"login-lockout-max" usage:"longest lockout"`
+	LoginMaxFailures      int           `yaml:"login_max_failures" env:"LOGIN_MAX_FAILURES" flag:"login-max-failures" usage:"failed logins per account before a lockout"`
+	LoginMaxFailuresPerIP int           `yaml:"login_max_failures_per_ip" env:"LOGIN_MAX_FAILURES_PER_IP" flag:"login-max-failures-per-ip" usage:"failed logins per client IP before a lockout"`
+	SignupMaxPerIP        int           `yaml:"signup_max_per_ip" env:"SIGNUP_MAX_PER_IP" flag:"signup-max-per-ip" usage:"sign-ups per client IP within the sign-up window"`
+	SignupWindow          time.Duration `yaml:"signup_window" env:"SIGNUP_WINDOW" flag:"signup-window" usage:"window the sign-up limit applies to"`
+
+	VerificationResendInterval time.Duration `yaml:"verification_resend_interval" env:"VERIFICATION_RESEND_INTERVAL" flag:"verification-resend-interval" usage:"wait between verification emails"`
+	PasswordResetTTL           time.Duration `yaml:"password_reset_ttl" env:"PASSWORD_RESET_TTL" flag:"password-reset-ttl" usage:"lifetime of password reset links"`
+	PasswordResetInterval      time.Duration `yaml:"password_reset_interval" env:"PASSWORD_RESET_INTERVAL" flag:"password-reset-interval" usage:"wait between password reset emails"`
+	MFAChallengeTTL            time.Duration `yaml:"mfa_challenge_ttl" env:"MFA_CHALLENGE_TTL" flag:"mfa-challenge-ttl" usage:"time the second login step may take"`
+	AccountDeletionGrace       time.Duration `yaml:"account_deletion_grace" env:"ACCOUNT_DELETION_GRACE" flag:"account-deletion-grace" usage:"time a deleted account can still be restored"`
+	AccountDeletionInterval    time.Duration `yaml:"account_deletion_interval" env:"ACCOUNT_DELETION_INTERVAL" flag:"account-deletion-interval" usage:"how often scheduled deletions are carried out"`
+	DataExportTTL              time.Duration `yaml:"data_export_ttl" env:"DATA_EXPORT_TTL" flag:"data-export-ttl" usage:"time a finished export can be downloaded"`
+	DataExportInterval         time.Duration `yaml:"data_export_interval" env:"DATA_EXPORT_INTERVAL" flag:"data-export-interval" usage:"how often the export job looks for work"`
+
+	// OIDCProviders are listed by name in OIDC_PROVIDERS; each provider
+	// NAME is then read from the OIDC_NAME_* variables.
+	OIDCProviders []OIDCProvider `yaml:"oidc_providers"`
+}
+
+// OIDCProvider configures one OpenID Connect login provider. RedirectURL
+// defaults to APP_URL/auth/oidc/name/callback and Scopes to openid, email
+// and profile.
+type OIDCProvider struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// unverifiedActions are the actions UNVERIFIED_RESTRICTIONS may name.
+var unverifiedActions = map[string]bool{
+	"post": true, "comment": true, "like": true, "follow": true, "message": true, "report": true,
+}
+
+// Defaults returns the settings used when nothing else is configured.
+func Defaults() Config {
+	return Config{
+		MongoDatabase:       "cluster0",
+		MongoConnectTimeout: 10 * time.Second,
+		Port:                "8000",
+		AccessTokenTTL:      24 * time.Hour,
+		RefreshTokenTTL:     168 * time.Hour,
+		BcryptCost:          14,
+		DefaultPageLimit:    10,
+		MaxPageLimit:        100,
+		DBTimeout:           100 * time.Second,
+		ShutdownTimeout:     30 * time.Second,
+		ShutdownDrainDelay:  5 * time.Second,
+		LogLevel:            "info",
+		MongoIndexTimeout:   30 * time.Second,
+
+		AppURL:                 "http://localhost:8000",
+		SMTPPort:               "1025",
+		MailFrom:               "no-reply@localhost",
+		TOTPIssuer:             "Social Media API",
+		UnverifiedRestrictions: []string{"post", "comment", "message"},
+
+		PasswordMinLength:      8,
+		PasswordMaxLength:      72,
+		PasswordRequireUpper:   true,
+		PasswordRequireLower:   true,
+		PasswordRequireDigit:   true,
+		PasswordRejectPersonal: true,
+		PasswordRejectBreached: true,
+
+		LoginFailureWindow:    15 * time.Minute,
+		LoginLockoutBase:      time.Minute,
+		LoginLockoutMax:       time.Hour,
+		LoginMaxFailures:      5,
+		LoginMaxFailuresPerIP: 20,
+		SignupMaxPerIP:        10,
+		SignupWindow:          time.Hour,
+
+		VerificationResendInterval: time.Minute,
+		PasswordResetTTL:           30 * time.Minute,
+		PasswordResetInterval:      time.Minute,
+		MFAChallengeTTL:            5 * time.Minute,
+		AccountDeletionGrace:       30 * 24 * time.Hour,
+		AccountDeletionInterval:    time.Hour,
+		DataExportTTL:              7 * 24 * time.Hour,
+		DataExportInterval:         time.Minute,
+	}
+}
+
+// App is the configuration of the running process. It holds the defaults
+// until main calls MustLoad, which is what tests run with.
+var App = Defaults()
+
+// MustLoad loads App from the process arguments and environment, and exits
+// when the configuration is invalid.
+func MustLoad() {
+	config, err := Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	App = config
+}
+
+// Load builds the configuration from the command line arguments, the
+// environment and the files they point to, and validates it. The YAML file
+// is given with -config or CONFIG_FILE, the env file with -env-file or
+// ENV_FILE and defaults to .env; a missing default .env file is not an
+// error.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fromFlags := Defaults()
+	flags := flag.NewFlagSet("social-media-api", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", getenv("CONFIG_FILE"), "YAML configuration file")
+	envFile := flags.String("env-file", getenv("ENV_FILE"), "file of environment variables")
+	if err := bindFlags(flags, &fromFlags); err != nil {
+		return Config{}, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	dotenv, err := readEnvFile(*envFile)
+	if err != nil {
+		return Config{}, err
+	}
+	// variables that are set win over the file, as with godotenv.Load
+	lookup := func(key string) string {
+		if value := getenv(key); value != "" {
+			return value
+		}
+		return dotenv[key]
+	}
+
+	config := Defaults()
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return Config{}, err
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return Config{}, fmt.Errorf("reading %s: %w", *configFile, err)
+		}
+	}
+
+	if err := applyEnv(&config, lookup); err != nil {
+		return Config{}, err
+	}
+	applyOIDCEnv(&config, lookup)
+
+	// flags win, but only those actually given
+	set := reflect.ValueOf(&config).Elem()
+	given := reflect.ValueOf(&fromFlags).Elem()
+	flags.Visit(func(f *flag.Flag) {
+		for i := 0; i < set.NumField(); i++ {
+			if set.Type().Field(i).Tag.Get("flag") == f.Name {
+				set.Field(i).Set(given.Field(i))
+			}
+		}
+	})
+
+	config.complete()
+	return config, config.Validate()
+}
+
+// readEnvFile reads the variables of the env file, or of .env when path is
+// empty, in which case a missing file is not an error. Variables that are not
+// set in the process environment are also exported to it, for the settings
+// other packages read themselves, such as OTEL_*.
+func readEnvFile(path string) (map[string]string, error) {
+	name := path
+	if name == "" {
+		name = ".env"
+	}
+	variables, err := godotenv.Read(name)
+	if path == "" && errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	for key, value := range variables {
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+	return variables, nil
+}
+
+// complete fills in the settings whose defaults depend on other settings.
+func (c *Config) complete() {
+	c.AppURL = strings.TrimRight(c.AppURL, "/")
+	c.DefaultPhoneCountryCode = strings.TrimPrefix(c.DefaultPhoneCountryCode, "+")
+	if len(c.UnverifiedRestrictions) == 1 && c.UnverifiedRestrictions[0] == "none" {
+		c.UnverifiedRestrictions = nil
+	}
+	for i := range c.OIDCProviders {
+		p := &c.OIDCProviders[i]
+		p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+		if p.RedirectURL == "" {
+			p.RedirectURL = c.AppURL + "/auth/oidc/" + p.Name + "/callback"
+		}
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		}
+	}
+}
+
+// applyOIDCEnv replaces the providers of the YAML file with those listed in
+// OIDC_PROVIDERS, when it is set.
+func applyOIDCEnv(config *Config, getenv func(string) string) {
+	names := splitList(getenv("OIDC_PROVIDERS"))
+	if len(names) == 0 {
+		return
+	}
+	config.OIDCProviders = nil
+	for _, name := range names {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config.OIDCProviders = append(config.OIDCProviders, OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSpace(getenv(prefix + "ISSUER")),
+			ClientID:     strings.TrimSpace(getenv(prefix + "CLIENT_ID")),
+			ClientSecret: strings.TrimSpace(getenv(prefix + "CLIENT_SECRET")),
+			RedirectURL:  strings.TrimSpace(getenv(prefix + "REDIRECT_URL")),
+			Scopes:       strings.Fields(getenv(prefix + "SCOPES")),
+		})
+	}
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bindFlags defines a flag for every field of config that names one.
+func bindFlags(flags *flag.FlagSet, config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, usage := field.Tag.Get("flag"), field.Tag.Get("usage")
+		if name == "" {
+			continue
+		}
+		switch ptr := v.Field(i).Addr().Interface().(type) {
+		case *string:
+			flags.StringVar(ptr, name, *ptr, usage)
+		case *int:
+			flags.IntVar(ptr, name, *ptr, usage)
+		case *bool:
+			flags.BoolVar(ptr, name, *ptr, usage)
+		case *time.Duration:
+			flags.DurationVar(ptr, name, *ptr, usage)
+		case *[]string:
+			flags.Func(name, usage+" (comma separated)", func(value string) error {
+				*ptr = splitList(value)
+				return nil
+			})
+		default:
+			return fmt.Errorf("config field %s has an unsupported type", field.Name)
+		}
+	}
+	return nil
+}
+
+// applyEnv overrides the fields whose environment variable is set.
+func applyEnv(config *Config, getenv func(string) string) error {
+	v := reflect.ValueOf(config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		raw := strings.TrimSpace(getenv(key))
+		if raw == "" {
+			continue
+		}
+		switch ptr := v.Field(i).Addr().Interface().(type) {
+		case *string:
+			*ptr = raw
+		case *int:
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%s must be a whole number", key)
+			}
+			*ptr = value
+		case *bool:
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s must be true or false", key)
+			}
+			*ptr = value
+		case *time.Duration:
+			value, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("%s must be a duration such as 30s or 24h", key)
+			}
+			*ptr = value
+		case *[]string:
+			*ptr = splitList(raw)
+		}
+	}
+	return nil
+}
+
+// Validate reports every setting that is missing or out of range.
+func (c Config) Validate() error {
+	var problems []error
+	if !strings.HasPrefix(c.MongoURI, "mongodb://") && !strings.HasPrefix(c.MongoURI, "mongodb+srv://") {
+		problems = append(problems, errors.New("MONGODB_URL must be a mongodb:// or mongodb+srv:// connection string"))
+	}
+	if c.MongoDatabase == "" {
+		problems = append(problems, errors.New("MONGODB_DATABASE must not be empty"))
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, errors.New("PORT must be a number between 1 and 65535"))
+	}
+	if c.SecretKey == "" {
+		problems = append(problems, errors.New("SECRET_KEY must be set"))
+	}
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"MONGODB_CONNECT_TIMEOUT", c.MongoConnectTimeout},
+		{"ACCESS_TOKEN_TTL", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", c.RefreshTokenTTL},
+		{"DB_TIMEOUT", c.DBTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"MONGODB_INDEX_TIMEOUT", c.MongoIndexTimeout},
+		{"LOGIN_FAILURE_WINDOW", c.LoginFailureWindow},
+		{"LOGIN_LOCKOUT_BASE", c.LoginLockoutBase},
+		{"SIGNUP_WINDOW", c.SignupWindow},
+		{"VERIFICATION_RESEND_INTERVAL", c.VerificationResendInterval},
+		{"PASSWORD_RESET_TTL", c.PasswordResetTTL},
+		{"PASSWORD_RESET_INTERVAL", c.PasswordResetInterval},
+		{"MFA_CHALLENGE_TTL", c.MFAChallengeTTL},
+		{"ACCOUNT_DELETION_GRACE", c.AccountDeletionGrace},
+		{"ACCOUNT_DELETION_INTERVAL", c.AccountDeletionInterval},
+		{"DATA_EXPORT_TTL", c.DataExportTTL},
+		{"DATA_EXPORT_INTERVAL", c.DataExportInterval},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			problems = append(problems, fmt.Errorf("%s must be positive", d.key))
+		}
+	}
+	if c.ShutdownDrainDelay < 0 {
+		problems = append(problems, errors.New("SHUTDOWN_DRAIN_DELAY must not be negative"))
+	}
+	if c.RefreshTokenTTL < c.AccessTokenTTL {
+		problems = append(problems, errors.New("REFRESH_TOKEN_TTL must not be shorter than ACCESS_TOKEN_TTL"))
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		problems = append(problems, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		problems = append(problems, errors.New("LOG_LEVEL must be debug, info, warn or error"))
+	}
+	if c.DefaultPageLimit < 1 || c.MaxPageLimit < c.DefaultPageLimit {
+		problems = append(problems, errors.New("DEFAULT_PAGE_LIMIT must be at least 1 and at most MAX_PAGE_LIMIT"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Errorf("TRUSTED_PROXIES holds %q, which is not an IP address or CIDR range", proxy))
+			}
+		}
+	}
+	if c.LoginLockoutMax < c.LoginLockoutBase {
+		problems = append(problems, errors.New("LOGIN_LOCKOUT_MAX must not be shorter than LOGIN_LOCKOUT_BASE"))
+	}
+	limits := []struct {
+		key   string
+		value int
+	}{
+		{"LOGIN_MAX_FAILURES", c.LoginMaxFailures},
+		{"LOGIN_MAX_FAILURES_PER_IP", c.LoginMaxFailuresPerIP},
+		{"SIGNUP_MAX_PER_IP", c.SignupMaxPerIP},
+	}
+	for _, l := range limits {
+		if l.value < 1 {
+			problems = append(problems, fmt.Errorf("%s must be at least 1", l.key))
+		}
+	}
+	// bcrypt ignores everything after 72 bytes
+	if c.PasswordMinLength < 1 || c.PasswordMaxLength > 72 || c.PasswordMinLength > c.PasswordMaxLength {
+		problems = append(problems, errors.New("PASSWORD_MIN_LENGTH must be at least 1 and PASSWORD_MAX_LENGTH at most 72 and not below it"))
+	}
+	if c.BreachedPasswordsFile != "" {
+		if _, err := os.Stat(c.BreachedPasswordsFile); err != nil {
+			problems = append(problems, fmt.Errorf("BREACHED_PASSWORDS_FILE cannot be read: %w", err))
+		}
+	}
+	if !validURL(c.AppURL) {
+		problems = append(problems, errors.New("APP_URL must be an http:// or https:// address"))
+	}
+	if c.SMTPHost != "" {
+		if port, err := strconv.Atoi(c.SMTPPort); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, errors.New("SMTP_PORT must be a number between 1 and 65535"))
+		}
+		if !strings.Contains(c.MailFrom, "@") {
+			problems = append(problems, errors.New("MAIL_FROM must be an email address"))
+		}
+	}
+	if code := c.DefaultPhoneCountryCode; code != "" {
+		if _, err := strconv.Atoi(code); err != nil || len(code) > 3 || code[0] == '0' {
+			problems = append(problems, errors.New("DEFAULT_PHONE_COUNTRY_CODE must be a country calling code such as +1 or +44"))
+		}
+	}
+	if c.TOTPIssuer == "" || strings.Contains(c.TOTPIssuer, ":") {
+		problems = append(problems, errors.New("TOTP_ISSUER must not be empty or contain a colon"))
+	}
+	for _, action := range c.UnverifiedRestrictions {
+		if !unverifiedActions[action] {
+			problems = append(problems, fmt.Errorf("UNVERIFIED_RESTRICTIONS names the unknown action %q", action))
+		}
+	}
+	seen := map[string]bool{}
+	for _, p := range c.OIDCProviders {
+		switch {
+		case p.Name == "" || seen[p.Name]:
+			problems = append(problems, fmt.Errorf("OIDC provider names must be unique and not empty, got %q", p.Name))
+		case !validURL(p.Issuer):
+			problems = append(problems, fmt.Errorf("the issuer of OIDC provider %s must be an http:// or https:// address", p.Name))
+		case p.ClientID == "":
+			problems = append(problems, fmt.Errorf("the client ID of OIDC provider %s must be set", p.Name))
+		case !validURL(p.RedirectURL):
+			problems = append(problems, fmt.Errorf("the redirect URL of OIDC provider %s must be an http:// or https:// address", p.Name))
+		}
+		seen[p.Name] = true
+	}
+	return errors.Join(problems...)
+}
+
+// validURL reports whether value is an absolute http or https URL.
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
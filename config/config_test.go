@@ -0,0 +1,234 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnv returns a getenv that only knows the required settings and vars.
+func fakeEnv(vars map[string]string) func(string) string {
+	env := map[string]string{
+		"MONGODB_URL": "mongodb://localhost:27017",
+		"SECRET_KEY":  "test-secret",
+	}
+	for key, value := range vars {
+		env[key] = value
+	}
+	return func(key string) string { return env[key] }
+}
+
+// writeFile writes content to a file in a temporary directory.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	config, err := Load(nil, fakeEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Defaults()
+	want.MongoURI = "mongodb://localhost:27017"
+	want.SecretKey = "test-secret"
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want the defaults %+v", config, want)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "port: \"8100\"\nlog_level: debug\nmax_page_limit: 50\n")
+	envFile := writeFile(t, "test.env", "PORT=8300\nMAX_PAGE_LIMIT=60\n")
+	// keep the env file from leaking into the environment of other tests
+	t.Setenv("PORT", "")
+	t.Setenv("MAX_PAGE_LIMIT", "")
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		port  string
+		limit int
+	}{
+		{"defaults", nil, nil, "8000", 100},
+		{"YAML over defaults", nil, map[string]string{"CONFIG_FILE": yamlFile}, "8100", 50},
+		{"env file over YAML", []string{"-env-file", envFile}, map[string]string{"CONFIG_FILE": yamlFile}, "8300", 60},
+		{"environment over env file", []string{"-env-file", envFile}, map[string]string{"CONFIG_FILE": yamlFile, "PORT": "8200"}, "8200", 60},
+		{"flags over environment", []string{"-env-file", envFile, "-port", "8400"}, map[string]string{"CONFIG_FILE": yamlFile, "PORT": "8200"}, "8400", 60},
+		{"-config over CONFIG_FILE", []string{"-config", yamlFile}, map[string]string{"CONFIG_FILE": "missing.yaml"}, "8100", 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Load(tt.args, fakeEnv(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Port != tt.port || config.MaxPageLimit != tt.limit {
+				t.Errorf("got port %s and max page limit %d, want %s and %d", config.Port, config.MaxPageLimit, tt.port, tt.limit)
+			}
+			// only the YAML file sets the log level, and nothing overrides it
+			wantLevel := "info"
+			if tt.env["CONFIG_FILE"] == yamlFile || len(tt.args) > 1 && tt.args[1] == yamlFile {
+				wantLevel = "debug"
+			}
+			if config.LogLevel != wantLevel {
+				t.Errorf("got log level %s, want %s", config.LogLevel, wantLevel)
+			}
+		})
+	}
+}
+
+func TestLoadParsesValues(t *testing.T) {
+	env := fakeEnv(map[string]string{
+		"DB_TIMEOUT":                 "5s",
+		"PASSWORD_REQUIRE_SYMBOL":    "true",
+		"TRUSTED_PROXIES":            " 10.0.0.1, ,10.1.0.0/16 ",
+		"APP_URL":                    "https://social.example.com/",
+		"DEFAULT_PHONE_COUNTRY_CODE": "+44",
+	})
+	config, err := Load([]string{"-unverified-restrictions", "none", "-bcrypt-cost", "10", "-shutdown-timeout", "1m"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DBTimeout != 5*time.Second || config.ShutdownTimeout != time.Minute {
+		t.Errorf("got durations %s and %s", config.DBTimeout, config.ShutdownTimeout)
+	}
+	if !config.PasswordRequireSymbol || config.BcryptCost != 10 {
+		t.Errorf("got require symbol %v and bcrypt cost %d", config.PasswordRequireSymbol, config.BcryptCost)
+	}
+	if !reflect.DeepEqual(config.TrustedProxies, []string{"10.0.0.1", "10.1.0.0/16"}) {
+		t.Errorf("got trusted proxies %q", config.TrustedProxies)
+	}
+	if config.UnverifiedRestrictions != nil {
+		t.Errorf("got unverified restrictions %q, want none", config.UnverifiedRestrictions)
+	}
+	if config.AppURL != "https://social.example.com" || config.DefaultPhoneCountryCode != "44" {
+		t.Errorf("got app URL %q and country code %q", config.AppURL, config.DefaultPhoneCountryCode)
+	}
+}
+
+func TestLoadOIDCProviders(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+oidc_providers:
+  - name: yaml
+    issuer: https://yaml.example.com
+    client_id: yaml-client
+`)
+
+	config, err := Load(nil, fakeEnv(map[string]string{"CONFIG_FILE": yamlFile, "APP_URL": "https://social.example.com/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.OIDCProviders) != 1 || config.OIDCProviders[0].Name != "yaml" {
+		t.Fatalf("got providers %+v, want the one from the YAML file", config.OIDCProviders)
+	}
+
+	env := fakeEnv(map[string]string{
+		"CONFIG_FILE":               yamlFile,
+		"APP_URL":                   "https://social.example.com/",
+		"OIDC_PROVIDERS":            "Google, corp",
+		"OIDC_GOOGLE_ISSUER":        "https://accounts.google.com",
+		"OIDC_GOOGLE_CLIENT_ID":     "google-client",
+		"OIDC_GOOGLE_CLIENT_SECRET": " google-secret ",
+		"OIDC_CORP_ISSUER":          "https://login.corp.example.com",
+		"OIDC_CORP_CLIENT_ID":       "corp-client",
+		"OIDC_CORP_REDIRECT_URL":    "https://corp.example.com/callback",
+		"OIDC_CORP_SCOPES":          "openid email groups",
+	})
+	config, err = Load(nil, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OIDCProvider{
+		{
+			Name:         "google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     "google-client",
+			ClientSecret: "google-secret",
+			RedirectURL:  "https://social.example.com/auth/oidc/google/callback",
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+		{
+			Name:        "corp",
+			Issuer:      "https://login.corp.example.com",
+			ClientID:    "corp-client",
+			RedirectURL: "https://corp.example.com/callback",
+			Scopes:      []string{"openid", "email", "groups"},
+		},
+	}
+	if !reflect.DeepEqual(config.OIDCProviders, want) {
+		t.Errorf("got providers %+v, want %+v", config.OIDCProviders, want)
+	}
+}
+
+func TestLoadRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		problem string
+	}{
+		{"no MongoDB URL", nil, map[string]string{"MONGODB_URL": "localhost:27017"}, "MONGODB_URL must be"},
+		{"no secret", nil, map[string]string{"SECRET_KEY": ""}, "SECRET_KEY must be set"},
+		{"port out of range", []string{"-port", "70000"}, nil, "PORT must be"},
+		{"not a number", nil, map[string]string{"BCRYPT_COST": "high"}, "BCRYPT_COST must be a whole number"},
+		{"bcrypt cost out of range", nil, map[string]string{"BCRYPT_COST": "40"}, "BCRYPT_COST must be between"},
+		{"not a duration", nil, map[string]string{"DB_TIMEOUT": "soon"}, "DB_TIMEOUT must be a duration"},
+		{"not a boolean", nil, map[string]string{"PASSWORD_REQUIRE_DIGIT": "sometimes"}, "PASSWORD_REQUIRE_DIGIT must be true or false"},
+		{"negative duration", nil, map[string]string{"SHUTDOWN_TIMEOUT": "-1s"}, "SHUTDOWN_TIMEOUT must be positive"},
+		{"refresh shorter than access", nil, map[string]string{"ACCESS_TOKEN_TTL": "2h", "REFRESH_TOKEN_TTL": "1h"}, "REFRESH_TOKEN_TTL must not be shorter"},
+		{"unknown log level", nil, map[string]string{"LOG_LEVEL": "loud"}, "LOG_LEVEL must be"},
+		{"page limits", nil, map[string]string{"DEFAULT_PAGE_LIMIT": "200"}, "DEFAULT_PAGE_LIMIT must be"},
+		{"bad proxy", nil, map[string]string{"TRUSTED_PROXIES": "10.0.0.1,proxy.local"}, `"proxy.local"`},
+		{"lockout limits", nil, map[string]string{"LOGIN_LOCKOUT_MAX": "30s"}, "LOGIN_LOCKOUT_MAX must not be shorter"},
+		{"failure limit", nil, map[string]string{"LOGIN_MAX_FAILURES": "0"}, "LOGIN_MAX_FAILURES must be at least 1"},
+		{"password longer than bcrypt reads", nil, map[string]string{"PASSWORD_MAX_LENGTH": "100"}, "PASSWORD_MAX_LENGTH at most 72"},
+		{"missing breached password file", nil, map[string]string{"BREACHED_PASSWORDS_FILE": "/nonexistent/breached.txt"}, "BREACHED_PASSWORDS_FILE cannot be read"},
+		{"relative app URL", nil, map[string]string{"APP_URL": "social.example.com"}, "APP_URL must be"},
+		{"SMTP without sender", nil, map[string]string{"SMTP_HOST": "mail.example.com", "MAIL_FROM": "nobody"}, "MAIL_FROM must be"},
+		{"country code", nil, map[string]string{"DEFAULT_PHONE_COUNTRY_CODE": "+0"}, "DEFAULT_PHONE_COUNTRY_CODE must be"},
+		{"issuer with a colon", nil, map[string]string{"TOTP_ISSUER": "Social: API"}, "TOTP_ISSUER must not"},
+		{"unknown restriction", nil, map[string]string{"UNVERIFIED_RESTRICTIONS": "post,dance"}, `unknown action "dance"`},
+		{"OIDC provider without client", nil, map[string]string{"OIDC_PROVIDERS": "corp", "OIDC_CORP_ISSUER": "https://login.corp.example.com"}, "client ID of OIDC provider corp"},
+		{"OIDC provider without issuer", nil, map[string]string{"OIDC_PROVIDERS": "corp", "OIDC_CORP_CLIENT_ID": "corp-client"}, "issuer of OIDC provider corp"},
+		{"duplicate OIDC provider", nil, map[string]string{"OIDC_PROVIDERS": "corp,Corp", "OIDC_CORP_ISSUER": "https://login.corp.example.com", "OIDC_CORP_CLIENT_ID": "corp-client"}, `unique and not empty, got "corp"`},
+		{"unknown flag", []string{"-colour"}, nil, "flag provided but not defined"},
+		{"missing config file", []string{"-config", "/nonexistent/config.yaml"}, nil, "/nonexistent/config.yaml"},
+		{"missing env file", []string{"-env-file", "/nonexistent/test.env"}, nil, "reading /nonexistent/test.env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.args, fakeEnv(tt.env))
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("got error %v, want one mentioning %q", err, tt.problem)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	_, err := Load(nil, fakeEnv(map[string]string{"MONGODB_URL": "", "SECRET_KEY": "", "PORT": "none"}))
+	if err == nil {
+		t.Fatal("invalid configuration was accepted")
+	}
+	for _, key := range []string{"MONGODB_URL", "SECRET_KEY", "PORT"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("%s is not reported in %q", key, err)
+		}
+	}
+}
+
+func TestLoadRejectsMalformedYAML(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "port: [8000\n")
+	if _, err := Load([]string{"-config", yamlFile}, fakeEnv(nil)); err == nil {
+		t.Error("malformed YAML was accepted")
+	}
+}
@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const emailChangeTTL = 24 * time.Hour
+
+// ChangePassword changes the logged in user's password
+// @Summary Change password
+// @Description Change the password of the logged in user. The current password is required and the new one must meet the password policy. Failed attempts count towards the login lockout. Every session is signed out, every API key is revoked and fresh tokens for a new session are returned.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param password body models.ChangePasswordInput true "Current and new password"
+// @Success 200 {object} models.TokenOutput
+// @Failure 400 {object} models.Error "Invalid request body or weak password"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Current password is incorrect"
+// @Failure 429 {object} models.Error "Too many failed attempts"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/password [put]
+func (h *AccountHandler) ChangePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.ChangePasswordInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !h.checkCurrentPassword(c, ctx, user, *input.Current_password, "Current password is incorrect") {
+			return
+		}
+		h.clearLoginFailures(ctx, *user.Email)
+		if rejectWeakPassword(c, "new_password", *input.New_password, *user.First_name, *user.Last_name, *user.Email) {
+			return
+		}
+
+		password := HashPassword(*input.New_password)
+		change := repository.UserChange{Set: bson.M{"password": password, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Password could not be changed")
+			return
+		}
+
+		if err := h.Users.RevokeCredentials(ctx, user.User_id); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Other sessions could not be signed out")
+			return
+		}
+		token, refreshToken, err := h.startSession(ctx, user, c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Session could not be started")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+	}
+}
+
+// ChangeEmail starts changing the logged in user's email address
+// @Summary Change email
+// @Description Request a new email address for the logged in user. The current password is required and failed attempts count towards the login lockout. The change only takes effect once the link sent to the new address is confirmed.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param email body models.ChangeEmailInput true "New email and current password"
+// @Success 200 {string} Confirmation email sent
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Password is incorrect"
+// @Failure 409 {object} models.Error "Email already in use"
+// @Failure 429 {object} models.Error "Too many failed attempts"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/email [put]
+func (h *AccountHandler) ChangeEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.ChangeEmailInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !h.checkCurrentPassword(c, ctx, user, *input.Password, "Password is incorrect") {
+			return
+		}
+		h.clearLoginFailures(ctx, *user.Email)
+		email := helper.NormalizeEmail(*input.Email)
+		input.Email = &email
+		if *input.Email == *user.Email {
+			helper.RespondError(c, http.StatusBadRequest, "This is already your email address")
+			return
+		}
+
+		_, err = h.Users.FindByEmail(ctx, *input.Email)
+		if err == nil {
+			respondConflict(c, "email")
+			return
+		}
+		if err != repository.ErrNotFound {
+			helper.RespondError(c, http.StatusInternalServerError, "error occured while checking for the email")
+			return
+		}
+
+		nonce := helper.NewNonce()
+		change := repository.UserChange{Set: bson.M{"pending_email": input.Email, "email_change_nonce": nonce, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Email could not be changed")
+			return
+		}
+
+		token, err := helper.GenerateActionToken(helper.PurposeChangeEmail, user.User_id, *input.Email, nonce, emailChangeTTL)
+		if err == nil {
+			link := helper.AppURL() + "/confirm-email?token=" + token
+			body := fmt.Sprintf("Hi %s,\n\nPlease confirm %s as the new email address of your account by opening the link below within 24 hours:\n\n%s\n", *user.First_name, *input.Email, link)
+			err = helper.Mail.Send(*input.Email, "Confirm your new email address", body)
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Confirmation email could not be sent")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Confirmation email sent to " + *input.Email})
+	}
+}
+
+// ConfirmEmailChange applies a pending email change
+// @Summary Confirm email change
+// @Description Confirm a new email address with the token sent to it. Every session of the account is signed out and every API key is revoked.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param token body models.VerifyEmailInput true "Confirmation token"
+// @Success 200 {string} Email changed successfully
+// @Failure 400 {object} models.Error "Invalid or expired token"
+// @Failure 409 {object} models.Error "Email already in use"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/email/confirm [post]
+func (h *AccountHandler) ConfirmEmailChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.VerifyEmailInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		claims, msg := helper.ValidateActionToken(*input.Token, helper.PurposeChangeEmail)
+		if msg != "" {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired confirmation link")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, claims.Uid)
+		pending := err == nil && user.Pending_email != nil && *user.Pending_email == claims.Email &&
+			user.Email_change_nonce != nil && *user.Email_change_nonce == claims.Id
+		if !pending {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired confirmation link")
+			return
+		}
+
+		_, err = h.Users.FindByEmail(ctx, claims.Email)
+		if err == nil {
+			respondConflict(c, "email")
+			return
+		}
+		if err != repository.ErrNotFound {
+			helper.RespondError(c, http.StatusInternalServerError, "error occured while checking for the email")
+			return
+		}
+
+		match := bson.M{"pending_email": claims.Email, "email_change_nonce": claims.Id}
+		change := repository.UserChange{
+			Set:   bson.M{"email": claims.Email, "email_verified": true, "updated_at": time.Now()},
+			Unset: []string{"pending_email", "email_change_nonce"},
+		}
+		err = h.Users.Update(ctx, claims.Uid, match, change)
+		if field := duplicateField(err); field != "" {
+			respondConflict(c, field)
+			return
+		}
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired confirmation link")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Email could not be changed")
+			return
+		}
+
+		if err := h.Users.RevokeCredentials(ctx, claims.Uid); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Existing sessions could not be signed out")
+			return
+		}
+
+		notice := fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s. If you did not do this, please contact support.\n", *user.First_name, claims.Email)
+		if err := helper.Mail.Send(*user.Email, "Your email address was changed", notice); err != nil {
+			slog.ErrorContext(ctx, "sending email change notice failed", "user_id", user.User_id, "error", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email changed successfully"})
+	}
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminHandler serves the admin endpoints that change accounts.
+type AdminHandler struct {
+	Users     repository.UserRepository
+	Throttles repository.ThrottleRepository
+	Sanctions repository.SanctionRepository
+	Deletions repository.DeletionRepository
+}
+
+// NewAdminHandler returns an AdminHandler using repos.
+func NewAdminHandler(repos repository.Repositories) *AdminHandler {
+	return &AdminHandler{
+		Users:     repos.Users,
+		Throttles: repos.Throttles,
+		Sanctions: repos.Sanctions,
+		Deletions: repos.Deletions,
+	}
+}
+
+// isModerator reports whether the caller has a moderator or admin role.
+func isModerator(c *gin.Context) bool {
+	role := c.GetString("role")
+	return role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// ownerFilter matches a document by ID, restricted to the caller's own
+// documents unless the caller is a moderator or admin.
+func ownerFilter(c *gin.Context, id primitive.ObjectID, UID primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": id}
+	if !isModerator(c) {
+		filter["user_id"] = UID
+	}
+	return filter
+}
+
+// ownerScope is ownerFilter for repositories: the caller's ID, or nil for
+// moderators and admins, who may touch any document.
+func ownerScope(c *gin.Context, UID primitive.ObjectID) *primitive.ObjectID {
+	if isModerator(c) {
+		return nil
+	}
+	return &UID
+}
+
+// SetUserRole changes the role of a user
+// @Summary Set a user's role
+// @Description Promote or demote a user to user, moderator or admin. The new role applies to the user's next request. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "User ID"
+// @Param role body models.RoleInput true "New role"
+// @Success 200 {string} Role updated successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/users/{id}/role [put]
+func (h *AdminHandler) SetUserRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		var input models.RoleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if userID == c.GetString("uid") && input.Role != models.RoleAdmin {
+			helper.RespondError(c, http.StatusBadRequest, "You cannot remove your own admin role")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		change := repository.UserChange{Set: bson.M{"role": input.Role, "updated_at": time.Now()}}
+		err := h.Users.Update(ctx, userID, nil, change)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Role could not be updated")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyHandler serves the API key endpoints from the repositories it is
+// given.
+type APIKeyHandler struct {
+	APIKeys repository.APIKeyRepository
+}
+
+// NewAPIKeyHandler returns an APIKeyHandler using repos.
+func NewAPIKeyHandler(repos repository.Repositories) *APIKeyHandler {
+	return &APIKeyHandler{APIKeys: repos.APIKeys}
+}
+
+const defaultAPIKeyDays = 90
+
+// CreateAPIKey creates a personal API key
+// @Summary Create an API key
+// @Description Create a named API key for scripts and integrations. Send it in the X-API-Key header. It only works on endpoints that accept one of its scopes and never has moderator or admin permissions. The key is only returned once. Expires after expires_in_days, 90 by default.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param key body models.APIKeyInput true "Name, scopes and expiry"
+// @Success 200 {object} models.APIKeyOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var input models.APIKeyInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if input.Expires_in_days == 0 {
+			input.Expires_in_days = defaultAPIKeyDays
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		key, prefix, hash := helper.NewAPIKey()
+
+		var apiKey models.APIKey
+		apiKey.ID = primitive.NewObjectID()
+		apiKey.User_ID = UID
+		apiKey.Name = input.Name
+		apiKey.Prefix = prefix
+		apiKey.Key_hash = hash
+		apiKey.Scopes = input.Scopes
+		apiKey.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		apiKey.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		apiKey.Expires_at = apiKey.Created_at.AddDate(0, 0, input.Expires_in_days)
+
+		if err := h.APIKeys.Create(ctx, apiKey); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "API key could not be created")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"key": key, "api_key": apiKey})
+	}
+}
+
+// GetAPIKeyList lists the API keys of the logged in user
+// @Summary Get API keys
+// @Description This endpoint retrieves the paginated API keys of the logged in user, newest first, including revoked and expired ones. Only the key prefix is shown.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of keys per page" default(10)
+// @Success 200 {object} models.APIKeyList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeyList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		apiKeys, err := h.APIKeys.ListByUser(ctx, UID, int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching API keys")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  apiKeys,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// RevokeAPIKey revokes an API key
+// @Summary Revoke an API key
+// @Description Revoke one of the logged in user's API keys. It stops working immediately.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "API key ID"
+// @Success 200 {string} API key revoked successfully
+// @Failure 400 {object} models.Error "Invalid API key ID"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "API key not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid API key ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.APIKeys.Revoke(ctx, id, UID, time.Now())
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "API key not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "API key could not be revoked")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+	}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// background counts the work handlers leave running after they respond,
+// such as sending email, so shutdown can wait for it.
+var background sync.WaitGroup
+
+// runInBackground runs task without holding up the response. Its context
+// keeps the request's values for logging and tracing but is not cancelled
+// when the request ends.
+func runInBackground(c *gin.Context, task func(ctx context.Context)) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		task(ctx)
+	}()
+}
+
+// WaitForBackground waits until the work started by handlers is done,
+// reporting false when ctx ends first.
+func WaitForBackground(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BlockHandler serves the block endpoints from the repositories it is given.
+type BlockHandler struct {
+	Blocks  repository.BlockRepository
+	Follows repository.FollowRepository
+}
+
+// NewBlockHandler returns a BlockHandler using repos.
+func NewBlockHandler(repos repository.Repositories) *BlockHandler {
+	return &BlockHandler{Blocks: repos.Blocks, Follows: repos.Follows}
+}
+
+// hiddenUserIDs returns the users whose content must not be shown to the caller.
+// Blocked users are always hidden; muted users only when includeMuted is set,
+// which is the case for the post feed. Anonymous callers hide nobody. Banned
+// users are left out by the repositories' Visible queries.
+func hiddenUserIDs(c *gin.Context, ctx context.Context, users repository.UserRepository, includeMuted bool) ([]primitive.ObjectID, error) {
+	uid, exists := c.Get("uid")
+	if !exists {
+		return users.HiddenUserIDs(ctx, nil, includeMuted)
+	}
+	UID, err := primitive.ObjectIDFromHex(uid.(string))
+	if err != nil {
+		return nil, err
+	}
+	return users.HiddenUserIDs(ctx, &UID, includeMuted)
+}
+
+// CreateBlock blocks another user
+// @Summary Block a user
+// @Description Block a user. Blocked users and the blocker no longer see each other's posts, comments and likes, and cannot comment, like, follow or message each other. Existing follows between the pair are removed.
+// @Tags Block
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param block body models.BlockInput true "User to block"
+// @Success 200 {object} models.CreateOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /blocks [post]
+func (h *BlockHandler) CreateBlock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var block models.Block
+		if err := c.ShouldBindJSON(&block); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(block); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if block.Blocked_ID == UID {
+			helper.RespondError(c, http.StatusBadRequest, "You cannot block yourself")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		already, err := h.Blocks.Exists(ctx, UID, block.Blocked_ID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking block")
+			return
+		}
+		if already {
+			helper.RespondError(c, http.StatusBadRequest, "You already blocked this user")
+			return
+		}
+
+		block.ID = primitive.NewObjectID()
+		block.Blocker_ID = UID
+		block.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		block.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := h.Blocks.Create(ctx, block); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "block item was not created")
+			return
+		}
+
+		if _, err := h.Follows.DeleteBetween(ctx, UID, block.Blocked_ID); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to remove follows")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"InsertedID": block.ID})
+	}
+}
+
+// DeleteBlock lifts a block by ID
+// @Summary Unblock a user
+// @Description This endpoint allows a user to delete one of their blocks by ID.
+// @Tags Block
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Block ID"
+// @Success 200 {string} Block deleted successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Block not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /blocks/{id} [delete]
+func (h *BlockHandler) DeleteBlock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid block ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Blocks.Delete(ctx, objectID, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Block not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete block")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Block deleted successfully"})
+	}
+}
+
+// GetBlockList lists the users the caller has blocked
+// @Summary Get a list of Blocks
+// @Description This endpoint retrieves a paginated list of the user's blocks.
+// @Tags Block
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of blocks per page" default(10)
+// @Success 200 {object} models.BlockList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /blocks [get]
+func (h *BlockHandler) GetBlockList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		blocks, err := h.Blocks.ListByBlocker(ctx, UID, int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching blocks")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  blocks,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
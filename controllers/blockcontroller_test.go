@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBlockRemovesFollowsAndStopsFollowing(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	charles := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+
+	w := s.request(t, http.MethodPost, "/follows", map[string]string{"following_id": charles.User_id}, ada.User_id)
+	expectStatus(t, w, http.StatusOK)
+	w = s.request(t, http.MethodPost, "/follows", map[string]string{"following_id": ada.User_id}, charles.User_id)
+	expectStatus(t, w, http.StatusOK)
+	w = s.request(t, http.MethodPost, "/follows", map[string]string{"following_id": charles.User_id}, ada.User_id)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = s.request(t, http.MethodPost, "/blocks", map[string]string{"blocked_id": ada.User_id}, charles.User_id)
+	expectStatus(t, w, http.StatusOK)
+	if len(s.store.Follows) != 0 {
+		t.Errorf("got %d follows after the block, want none", len(s.store.Follows))
+	}
+
+	// the block works both ways
+	w = s.request(t, http.MethodPost, "/follows", map[string]string{"following_id": charles.User_id}, ada.User_id)
+	expectStatus(t, w, http.StatusForbidden)
+	w = s.request(t, http.MethodPost, "/follows", map[string]string{"following_id": ada.User_id}, charles.User_id)
+	expectStatus(t, w, http.StatusForbidden)
+}
@@ -3,23 +3,33 @@ package controller
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
-	"social-media-api/database"
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/metrics"
 
 	"social-media-api/models"
+	"social-media-api/repository"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
-var commentCollection *mongo.Collection = database.OpenCollection(database.Client, "comment")
+
+// CommentHandler serves the comment endpoints from the repositories it is given.
+type CommentHandler struct {
+	Users    repository.UserRepository
+	Posts    repository.PostRepository
+	Comments repository.CommentRepository
+}
+
+// NewCommentHandler returns a CommentHandler using repos.
+func NewCommentHandler(repos repository.Repositories) *CommentHandler {
+	return &CommentHandler{Users: repos.Users, Posts: repos.Posts, Comments: repos.Comments}
+}
 
 
 // CreateComment creates a new comment
@@ -32,51 +42,68 @@ var commentCollection *mongo.Collection = database.OpenCollection(database.Clien
 // @Success 200 {object} models.Comment
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Blocked"
+// @Failure 404 {object} models.Error "Post not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Security ApiKeyAuth
 // @Router /comments [post]
-func CreateComment() gin.HandlerFunc {
+func (h *CommentHandler) CreateComment() gin.HandlerFunc {
     return func(c *gin.Context) {
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 		
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+        defer cancel()
         var comment models.Comment
-        if err := c.BindJSON(&comment); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        if err := c.ShouldBindJSON(&comment); err != nil {
+            helper.RespondInvalidInput(c, err)
             return
         }
 		UID, err := primitive.ObjectIDFromHex(uid.(string))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid UID"})
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
 			return
 		}
 		comment.User_ID = UID
+		comment.Hidden = false
 		
         comment.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
         comment.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
         comment.ID = primitive.NewObjectID()
-		fmt.Println(comment)
-		fmt.Println(comment.Post_ID)
 
         validationErr := validate.Struct(comment)
         if validationErr != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+            helper.RespondInvalidInput(c, validationErr)
             return
         }
 		
-        resultInsertionNumber, insertErr := commentCollection.InsertOne(ctx, comment)
+        post, err := h.Posts.FindByID(ctx, comment.Post_ID)
+        if err != nil {
+            helper.RespondError(c, http.StatusNotFound, "Post not found")
+            return
+        }
+        blocked, err := h.Users.IsBlocked(ctx, UID, post.User_id)
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Error checking blocks")
+            return
+        }
+        if blocked {
+            helper.RespondError(c, http.StatusForbidden, "You cannot interact with this user")
+            return
+        }
+
+        insertErr := h.Comments.Create(ctx, comment)
         if insertErr != nil {
             msg := fmt.Sprintf("comment item was not created")
-            c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+            helper.RespondError(c, http.StatusInternalServerError, msg)
             return
         }
-        defer cancel()
+        metrics.CommentsCreated.Inc()
 
-        c.JSON(http.StatusOK, resultInsertionNumber)
+        c.JSON(http.StatusOK, gin.H{"InsertedID": comment.ID})
 
     }
 }
@@ -93,20 +120,23 @@ func CreateComment() gin.HandlerFunc {
 // @Failure 404 {object} models.Error "Not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /comments/{id} [get]
-func GetCommentByID() gin.HandlerFunc {
+func (h *CommentHandler) GetCommentByID() gin.HandlerFunc {
     return func(c *gin.Context) {
         commentID := c.Param("id")
         objectID, err := primitive.ObjectIDFromHex(commentID)
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid comment ID")
             return
         }
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
-        var comment models.Comment
-        err = commentCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&comment)
+        comment, err := h.Comments.FindVisible(ctx, objectID)
+        if err == repository.ErrNotFound {
+            helper.RespondError(c, http.StatusNotFound, "Comment not found")
+            return
+        }
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Comment not found"})
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching comment")
             return
         }
 
@@ -116,7 +146,7 @@ func GetCommentByID() gin.HandlerFunc {
 
 // UpdateComment is the API used to update an existing comment
 // @Summary Update a Comment
-// @Description This endpoint allows a user to update an existing comment.
+// @Description This endpoint allows a user to update an existing comment. Moderators and admins can update any comment.
 // @Tags Comment
 // @Accept json
 // @Produce json
@@ -126,48 +156,45 @@ func GetCommentByID() gin.HandlerFunc {
 // @Success 200 {string} Comment updated successfully
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Comment not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /comments/{id} [put]
-func UpdateComment() gin.HandlerFunc {
+func (h *CommentHandler) UpdateComment() gin.HandlerFunc {
     return func(c *gin.Context) {
         commentID := c.Param("id")
         objectID, err := primitive.ObjectIDFromHex(commentID)
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid comment ID")
             return
         }
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
         UID, err := primitive.ObjectIDFromHex(uid.(string))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid UID"})
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
 			return
 		}
 
         var updatedComment models.Comment
-        if err := c.BindJSON(&updatedComment); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        if err := c.ShouldBindJSON(&updatedComment); err != nil {
+            helper.RespondInvalidInput(c, err)
             return
         }
 
         updatedComment.Updated_at = time.Now()
 
-        filter := bson.M{"_id": objectID, "user_id": UID}
-		update := bson.M{
-			"$set": bson.M{
-				"description": updatedComment.Description,
-				"updated_at":  updatedComment.Updated_at,
-			},
-		}
-        
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
-        result, err := commentCollection.UpdateOne(ctx, filter, update)
-        if err != nil || result.MatchedCount ==0 {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Comment could not be updated"})
+        err = h.Comments.Update(ctx, objectID, ownerScope(c, UID), updatedComment.Description, updatedComment.Updated_at)
+        if err == repository.ErrNotFound {
+            helper.RespondError(c, http.StatusNotFound, "Comment not found")
+            return
+        }
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Comment could not be updated")
             return
         }
         
@@ -178,7 +205,7 @@ func UpdateComment() gin.HandlerFunc {
 
 // DeleteComment is the API used to delete a comment by ID
 // @Summary Delete a Comment
-// @Description This endpoint allows a user to delete a comment by ID.
+// @Description This endpoint allows a user to delete a comment by ID. Moderators and admins can delete any comment.
 // @Tags Comment
 // @Accept json
 // @Produce json
@@ -187,32 +214,39 @@ func UpdateComment() gin.HandlerFunc {
 // @Success 200 {string} Comment deleted successfully
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Comment not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /comments/{id} [delete]
-func DeleteComment() gin.HandlerFunc {
+func (h *CommentHandler) DeleteComment() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		commentID := c.Param("id")
         objectID, err := primitive.ObjectIDFromHex(commentID)
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid comment ID")
             return
         }
 
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
         UID, err := primitive.ObjectIDFromHex(uid.(string))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid UID"})
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
 			return
 		}
 
-		filter := bson.M{"_id": objectID, "user_id": UID}
-		result, err := commentCollection.DeleteOne(context.Background(), filter)
-		if err != nil || result.DeletedCount == 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment or not authorized"})
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Comments.Delete(ctx, objectID, ownerScope(c, UID))
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Comment not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete comment")
 			return
 		}
 
@@ -232,39 +266,26 @@ func DeleteComment() gin.HandlerFunc {
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /comments [get]
-func GetCommentList() gin.HandlerFunc {
+func (h *CommentHandler) GetCommentList() gin.HandlerFunc {
     return func(c *gin.Context) {
         // Get pagination parameters from query string
-        page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-        if err != nil || page < 1 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
-            return
-        }
-
-        limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
-        if err != nil || limit < 1 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+        page, limit, ok := pagination(c)
+        if !ok {
             return
         }
 
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
 
-        // Define options for pagination
-        findOptions := options.Find()
-        findOptions.SetSkip(int64((page - 1) * limit))
-        findOptions.SetLimit(int64(limit))
-
-        var comments []models.Comment
-
-        cursor, err := commentCollection.Find(ctx, bson.M{}, findOptions)
+        hidden, err := hiddenUserIDs(c, ctx, h.Users, false)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching comments"})
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching blocked users")
             return
         }
 
-        if err = cursor.All(ctx, &comments); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding comments"})
+        comments, err := h.Comments.ListVisible(ctx, hidden, int64((page - 1) * limit), int64(limit))
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching comments")
             return
         }
 
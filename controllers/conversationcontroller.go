@@ -0,0 +1,569 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConversationHandler serves the direct message endpoints from the
+// repositories it is given.
+type ConversationHandler struct {
+	Users         repository.UserRepository
+	Follows       repository.FollowRepository
+	Conversations repository.ConversationRepository
+	Messages      repository.MessageRepository
+}
+
+// NewConversationHandler returns a ConversationHandler using repos.
+func NewConversationHandler(repos repository.Repositories) *ConversationHandler {
+	return &ConversationHandler{
+		Users:         repos.Users,
+		Follows:       repos.Follows,
+		Conversations: repos.Conversations,
+		Messages:      repos.Messages,
+	}
+}
+
+// canMessage reports whether sender may send direct messages to every recipient.
+// Nobody can message a user they blocked or were blocked by, and recipients who
+// restrict DMs to followers only accept messages from users following them.
+func (h *ConversationHandler) canMessage(ctx context.Context, sender primitive.ObjectID, recipients []primitive.ObjectID) (bool, error) {
+	for _, recipient := range recipients {
+		blocked, err := h.Users.IsBlocked(ctx, sender, recipient)
+		if err != nil || blocked {
+			return false, err
+		}
+	}
+
+	users, err := h.Users.FindByIDs(ctx, recipients)
+	if err != nil {
+		return false, err
+	}
+	for _, recipient := range users {
+		if !recipient.Dm_followers_only {
+			continue
+		}
+		ok, err := h.Follows.Exists(ctx, sender, recipient.ID)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// CreateConversation starts a one-to-one or small group conversation
+// @Summary Create a conversation
+// @Description Start a conversation with one or more users. A one-to-one conversation that already exists is returned instead of creating a new one.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param conversation body models.ConversationInput true "Conversation data"
+// @Success 200 {object} models.Conversation
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Recipient blocked or only accepts messages from followers"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations [post]
+func (h *ConversationHandler) CreateConversation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var input models.ConversationInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		// deduplicate participants and drop the caller, who is always added
+		seen := map[primitive.ObjectID]bool{UID: true}
+		var recipients []primitive.ObjectID
+		for _, id := range input.Participant_IDs {
+			if !seen[id] {
+				seen[id] = true
+				recipients = append(recipients, id)
+			}
+		}
+		if len(recipients) == 0 {
+			helper.RespondError(c, http.StatusBadRequest, "A conversation needs at least one other participant")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		participants, err := h.Users.FindByIDs(ctx, recipients)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking participants")
+			return
+		}
+		if len(participants) != len(recipients) {
+			helper.RespondError(c, http.StatusBadRequest, "One or more participants do not exist")
+			return
+		}
+
+		allowed, err := h.canMessage(ctx, UID, recipients)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking message settings")
+			return
+		}
+		if !allowed {
+			helper.RespondError(c, http.StatusForbidden, "A participant cannot receive messages from you")
+			return
+		}
+
+		if len(recipients) == 1 {
+			existing, err := h.Conversations.FindDirect(ctx, UID, recipients[0])
+			if err == nil {
+				c.JSON(http.StatusOK, existing)
+				return
+			}
+			if err != repository.ErrNotFound {
+				helper.RespondError(c, http.StatusInternalServerError, "Error fetching conversation")
+				return
+			}
+		}
+
+		var conversation models.Conversation
+		conversation.ID = primitive.NewObjectID()
+		conversation.Name = input.Name
+		conversation.Participants = append([]primitive.ObjectID{UID}, recipients...)
+		conversation.Is_group = len(recipients) > 1
+		conversation.Created_by = UID
+		conversation.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		conversation.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		conversation.Last_message_at = conversation.Created_at
+
+		if validationErr := validate.Struct(conversation); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		if err := h.Conversations.Create(ctx, conversation); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "conversation item was not created")
+			return
+		}
+
+		c.JSON(http.StatusOK, conversation)
+	}
+}
+
+// GetConversationList lists the caller's conversations with unread counts
+// @Summary Get a list of Conversations
+// @Description This endpoint retrieves a paginated list of the user's conversations, most recently active first, with the number of unread messages in each.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of conversations per page" default(10)
+// @Success 200 {object} models.ConversationList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations [get]
+func (h *ConversationHandler) GetConversationList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		found, err := h.Conversations.ListByParticipant(ctx, UID, int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching conversations")
+			return
+		}
+
+		ids := make([]primitive.ObjectID, len(found))
+		for i, conversation := range found {
+			ids[i] = conversation.ID
+		}
+		counts, err := h.Messages.CountUnread(ctx, ids, UID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error counting unread messages")
+			return
+		}
+
+		conversations := make([]models.ConversationOutput, len(found))
+		for i, conversation := range found {
+			conversations[i] = models.ConversationOutput{
+				ID:              conversation.ID,
+				Name:            conversation.Name,
+				Participants:    conversation.Participants,
+				Is_group:        conversation.Is_group,
+				Created_by:      conversation.Created_by,
+				Last_message_at: conversation.Last_message_at,
+				Unread_count:    counts[conversation.ID],
+				Created_at:      conversation.Created_at,
+				Updated_at:      conversation.Updated_at,
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  conversations,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// GetMessageList lists messages in a conversation using cursor pagination
+// @Summary Get messages in a Conversation
+// @Description This endpoint retrieves messages newest first. Pass the returned next_cursor as cursor to fetch older messages.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Conversation ID"
+// @Param cursor query string false "ID of the last message of the previous page"
+// @Param limit query int false "Number of messages per page" default(10)
+// @Success 200 {object} models.MessageList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Conversation not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations/{id}/messages [get]
+func (h *ConversationHandler) GetMessageList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conversationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid conversation ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		_, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var before *primitive.ObjectID
+		if after := c.Query("cursor"); after != "" {
+			cursorID, err := primitive.ObjectIDFromHex(after)
+			if err != nil {
+				helper.RespondError(c, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			before = &cursorID
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		if _, err := h.Conversations.FindForParticipant(ctx, conversationID, UID); err != nil {
+			helper.RespondError(c, http.StatusNotFound, "Conversation not found")
+			return
+		}
+
+		messages, err := h.Messages.List(ctx, conversationID, before, int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching messages")
+			return
+		}
+
+		nextCursor := ""
+		if len(messages) == limit {
+			nextCursor = messages[len(messages)-1].ID.Hex()
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":        messages,
+			"next_cursor": nextCursor,
+			"limit":       limit,
+		})
+	}
+}
+
+// CreateMessage sends a message to a conversation
+// @Summary Send a message
+// @Description This endpoint sends a message to a conversation the user takes part in.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Conversation ID"
+// @Param message body models.MessageInput true "Message data"
+// @Success 200 {object} models.Message
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Recipient blocked or only accepts messages from followers"
+// @Failure 404 {object} models.Error "Conversation not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations/{id}/messages [post]
+func (h *ConversationHandler) CreateMessage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conversationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid conversation ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var input models.MessageInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		conversation, err := h.Conversations.FindForParticipant(ctx, conversationID, UID)
+		if err != nil {
+			helper.RespondError(c, http.StatusNotFound, "Conversation not found")
+			return
+		}
+
+		var recipients []primitive.ObjectID
+		for _, id := range conversation.Participants {
+			if id != UID {
+				recipients = append(recipients, id)
+			}
+		}
+		allowed, err := h.canMessage(ctx, UID, recipients)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking message settings")
+			return
+		}
+		if !allowed {
+			helper.RespondError(c, http.StatusForbidden, "A participant cannot receive messages from you")
+			return
+		}
+
+		var message models.Message
+		message.ID = primitive.NewObjectID()
+		message.Conversation_ID = conversationID
+		message.Sender_ID = UID
+		message.Body = input.Body
+		message.Read_by = []primitive.ObjectID{UID}
+		message.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		message.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if validationErr := validate.Struct(message); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		if err := h.Messages.Create(ctx, message); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "message item was not created")
+			return
+		}
+
+		if err := h.Conversations.Touch(ctx, conversationID, message.Created_at); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to update conversation")
+			return
+		}
+
+		c.JSON(http.StatusOK, message)
+	}
+}
+
+// DeleteMessage deletes one of the caller's messages
+// @Summary Delete a message
+// @Description This endpoint allows the sender to delete a message from a conversation.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Conversation ID"
+// @Param messageId path string true "Message ID"
+// @Success 200 {string} Message deleted successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Message not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations/{id}/messages/{messageId} [delete]
+func (h *ConversationHandler) DeleteMessage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conversationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid conversation ID")
+			return
+		}
+		messageID, err := primitive.ObjectIDFromHex(c.Param("messageId"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid message ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Messages.Delete(ctx, messageID, conversationID, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Message not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete message")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Message deleted successfully"})
+	}
+}
+
+// MarkConversationRead records a read receipt for every message in a conversation
+// @Summary Mark a conversation as read
+// @Description This endpoint marks all messages in the conversation as read by the user.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {string} Conversation marked as read
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Conversation not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations/{id}/read [post]
+func (h *ConversationHandler) MarkConversationRead() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conversationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid conversation ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		if _, err := h.Conversations.FindForParticipant(ctx, conversationID, UID); err != nil {
+			helper.RespondError(c, http.StatusNotFound, "Conversation not found")
+			return
+		}
+
+		if err := h.Messages.MarkRead(ctx, conversationID, UID); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to mark conversation as read")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Conversation marked as read"})
+	}
+}
+
+// UpdateMessageSettings changes who may send the caller direct messages
+// @Summary Update direct message settings
+// @Description This endpoint lets a user restrict direct messages to their followers.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param settings body models.MessageSettingsInput true "Message settings"
+// @Success 200 {string} Message settings updated successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /conversations/settings [put]
+func (h *ConversationHandler) UpdateMessageSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.MessageSettingsInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		change := repository.UserChange{Set: bson.M{"dm_followers_only": *input.Dm_followers_only, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, uid.(string), nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Message settings could not be updated")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Message settings updated successfully"})
+	}
+}
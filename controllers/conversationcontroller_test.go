@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMessageListCapsTheLimit(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	charles := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+	now := time.Now()
+	conversation := models.Conversation{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{ada.ID, charles.ID}, Created_by: ada.ID, Created_at: now}
+	s.store.Conversations = append(s.store.Conversations, conversation)
+	body := "Have you seen the engine?"
+	for i := 0; i < config.App.MaxPageLimit+1; i++ {
+		s.store.Messages = append(s.store.Messages, models.Message{ID: primitive.NewObjectID(), Conversation_ID: conversation.ID, Sender_ID: ada.ID, Body: &body, Created_at: now})
+	}
+
+	conversations := NewConversationHandler(s.store.Repositories())
+	s.router.GET("/conversations/:id/messages", func(c *gin.Context) {
+		c.Set("uid", ada.User_id)
+	}, conversations.GetMessageList())
+
+	path := fmt.Sprintf("/conversations/%s/messages?limit=%d", conversation.ID.Hex(), config.App.MaxPageLimit+1)
+	w := s.send(t, http.MethodGet, path, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var page struct {
+		Data        []models.Message `json:"data"`
+		Next_cursor string           `json:"next_cursor"`
+		Limit       int              `json:"limit"`
+	}
+	decode(t, w, &page)
+	if page.Limit != config.App.MaxPageLimit || len(page.Data) != config.App.MaxPageLimit {
+		t.Errorf("got limit %d with %d messages, want both capped at %d", page.Limit, len(page.Data), config.App.MaxPageLimit)
+	}
+	if page.Next_cursor == "" {
+		t.Error("got no cursor to the remaining message")
+	}
+
+	w = s.send(t, http.MethodGet, fmt.Sprintf("/conversations/%s/messages?limit=0", conversation.ID.Hex()), nil, "")
+	expectStatus(t, w, http.StatusBadRequest)
+}
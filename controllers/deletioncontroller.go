@@ -0,0 +1,429 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// deletionClaimTTL is how long a purge may run before another worker may
+// pick the account up again. Every purge step can safely run twice.
+const deletionClaimTTL = time.Hour
+
+// deletedMessageBody replaces the text of messages sent by deleted accounts,
+// so conversations keep their shape for the other participants.
+const deletedMessageBody = "This message was deleted"
+
+// DeleteAccount schedules the logged in user's account for deletion
+// @Summary Delete account
+// @Description Schedule the logged in user's account for deletion. Every session is signed out, every API key is revoked and the account can be restored at /users/restore until the grace period (30 days by default) ends. After that the account, posts, comments, likes, follows, blocks, mutes, notifications, sessions, API keys and data exports are removed, messages are anonymized, and an audit record without personal data is kept. A current code or recovery code is required when two-factor authentication is enabled. Failed attempts count towards the login lockout. Accounts created through an OpenID Connect provider set a password with the forgotten password flow first.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param deletion body models.AccountDeletionInput true "Password and, with two-factor authentication, a code"
+// @Success 202 {object} models.AccountDeletion
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Wrong password or code"
+// @Failure 429 {object} models.Error "Too many failed attempts"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me [delete]
+func (h *AccountHandler) DeleteAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.AccountDeletionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !h.checkCurrentPassword(c, ctx, user, *input.Password, "Password is incorrect") {
+			return
+		}
+		if user.Totp_enabled {
+			if input.Code == "" && input.Recovery_code == "" {
+				helper.RespondError(c, http.StatusBadRequest, "code or recovery_code is required")
+				return
+			}
+			valid, err := h.verifySecondFactor(ctx, user, input.Code, input.Recovery_code)
+			if err != nil {
+				helper.RespondError(c, http.StatusInternalServerError, "Code could not be checked")
+				return
+			}
+			if !valid {
+				h.recordLoginFailure(ctx, *user.Email, c.ClientIP())
+				helper.RespondError(c, http.StatusForbidden, "Invalid authentication code")
+				return
+			}
+		}
+		h.clearLoginFailures(ctx, *user.Email)
+
+		var deletion models.AccountDeletion
+		deletion.ID = primitive.NewObjectID()
+		deletion.User_ID = user.ID
+		deletion.Status = models.DeletionStatusScheduled
+		deletion.Requested_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		deletion.Scheduled_for = deletion.Requested_at.Add(config.App.AccountDeletionGrace)
+		deletion.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		deletion.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		change := repository.UserChange{Set: bson.M{"deletion_scheduled_at": deletion.Scheduled_for, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Account deletion could not be scheduled")
+			return
+		}
+		if err := h.Deletions.Create(ctx, deletion); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Account deletion could not be recorded")
+			return
+		}
+
+		if err := h.Users.RevokeCredentials(ctx, user.User_id); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Existing sessions could not be signed out")
+			return
+		}
+
+		notice := fmt.Sprintf("Hi %s,\n\nYour account will be deleted on %s. Until then you can restore it by logging in at %s/users/restore.\n", *user.First_name, deletion.Scheduled_for.Format(time.RFC1123), helper.AppURL())
+		if err := helper.Mail.Send(*user.Email, "Your account will be deleted", notice); err != nil {
+			slog.ErrorContext(ctx, "sending account deletion notice failed", "user_id", user.User_id, "error", err)
+		}
+
+		c.JSON(http.StatusAccepted, deletion)
+	}
+}
+
+// RestoreAccount cancels a scheduled account deletion
+// @Summary Restore a deleted account
+// @Description Cancel the deletion of an account during its grace period and log in. Failed attempts count towards the login lockout. Accounts with two-factor authentication also send a current code or a recovery code, and nothing is restored until it is checked.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Param user body models.AccountRestoreInput true "Login credentials and, with two-factor authentication, a code"
+// @Success 200 {object} models.User "User information with tokens"
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Login, password or code is incorrect"
+// @Failure 403 {object} models.Error "Account suspended or banned"
+// @Failure 410 {object} models.Error "The account is already being deleted"
+// @Failure 429 {object} models.Error "Too many failed attempts"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/restore [post]
+func (h *AccountHandler) RestoreAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.AccountRestoreInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		email := helper.NormalizeEmail(*input.Email)
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		ip := c.ClientIP()
+		if h.rejectIfLocked(c, ctx, accountThrottleKey(email), ipThrottleKey(ip)) {
+			return
+		}
+
+		user, err := h.Users.FindByEmail(ctx, email)
+		passwordIsValid := false
+		if err == nil && user.Deletion_scheduled_at != nil {
+			passwordIsValid, _ = VerifyPassword(*input.Password, *user.Password)
+		} else {
+			VerifyPassword(*input.Password, dummyPasswordHash())
+		}
+		if !passwordIsValid {
+			h.recordLoginFailure(ctx, email, ip)
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidCredentials, "login or password is incorrect")
+			return
+		}
+
+		// the password alone must not be enough to stop a deletion the
+		// owner asked for with their second factor
+		if user.Totp_enabled {
+			if input.Code == "" && input.Recovery_code == "" {
+				helper.RespondError(c, http.StatusBadRequest, "code or recovery_code is required")
+				return
+			}
+			valid, err := h.verifySecondFactor(ctx, user, input.Code, input.Recovery_code)
+			if err != nil {
+				helper.RespondError(c, http.StatusInternalServerError, "Code could not be checked")
+				return
+			}
+			if !valid {
+				h.recordLoginFailure(ctx, email, ip)
+				helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidCredentials, "Invalid authentication code")
+				return
+			}
+		}
+
+		// once the purge has started the account cannot be brought back
+		match := bson.M{"deletion_started_at": nil}
+		change := repository.UserChange{
+			Set:   bson.M{"updated_at": time.Now()},
+			Unset: []string{"deletion_scheduled_at"},
+		}
+		err = h.Users.Update(ctx, user.User_id, match, change)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusGone, "The account is already being deleted")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Account could not be restored")
+			return
+		}
+
+		if err := h.Deletions.MarkRestored(ctx, user.ID, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "marking account deletion as restored failed", "user_id", user.User_id, "error", err)
+		}
+
+		user.Deletion_scheduled_at = nil
+		h.clearLoginFailures(ctx, email)
+		if restriction := helper.AccountRestriction(user); restriction != "" {
+			helper.RespondErrorCode(c, http.StatusForbidden, helper.CodeAccountRestricted, restriction)
+			return
+		}
+		h.respondWithSession(c, user)
+	}
+}
+
+// GetAccountDeletionList lists account deletion records
+// @Summary Get account deletions
+// @Description This endpoint retrieves the paginated audit records of account deletions, newest first, with what was removed or anonymized.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param status query string false "Only deletions with this status (scheduled, restored or completed)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of deletions per page" default(10)
+// @Success 200 {object} models.AccountDeletionList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/account-deletions [get]
+func (h *AdminHandler) GetAccountDeletionList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		deletions, err := h.Deletions.List(ctx, c.Query("status"), int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching account deletions")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  deletions,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// RunAccountDeletion carries out scheduled account deletions every
+// ACCOUNT_DELETION_INTERVAL until ctx is cancelled. It is started from main.
+func RunAccountDeletion(ctx context.Context, repos repository.Repositories) {
+	setWorkerRunning(workerAccountDeletion, true)
+	defer setWorkerRunning(workerAccountDeletion, false)
+
+	for {
+		if n, err := PurgeDeletedAccounts(ctx, repos); err != nil {
+			slog.ErrorContext(ctx, "purging deleted accounts failed", "purged", n, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(config.App.AccountDeletionInterval):
+		}
+	}
+}
+
+// PurgeDeletedAccounts deletes every account whose grace period has ended
+// and returns how many were purged. Each account is claimed before it is
+// purged so several API instances can run the job at once.
+func PurgeDeletedAccounts(ctx context.Context, repos repository.Repositories) (int, error) {
+	purged := 0
+	for ctx.Err() == nil {
+		found, err := purgeNextAccount(ctx, repos)
+		if err != nil || !found {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, ctx.Err()
+}
+
+// purgeNextAccount claims and purges one account that is due, reporting
+// whether there was one.
+func purgeNextAccount(parent context.Context, repos repository.Repositories) (bool, error) {
+	var ctx, cancel = context.WithTimeout(parent, config.App.DBTimeout)
+	defer cancel()
+
+	user, err := repos.Users.ClaimDeletion(ctx, time.Now(), deletionClaimTTL)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := purgeAccount(ctx, repos, user); err != nil {
+		return false, fmt.Errorf("purging %s: %w", user.User_id, err)
+	}
+	return true, nil
+}
+
+// purgeAccount removes the user's data, anonymizes what other users still
+// need, records what was done and finally deletes the user.
+func purgeAccount(ctx context.Context, repos repository.Repositories, user models.User) error {
+	UID := user.ID
+	removed := map[string]int64{}
+	anonymized := map[string]int64{}
+
+	remove := func(name string, deleteMany func() (int64, error)) error {
+		n, err := deleteMany()
+		if err != nil {
+			return fmt.Errorf("removing %s: %w", name, err)
+		}
+		removed[name] += n
+		return nil
+	}
+	anonymize := func(name string, updateMany func() (int64, error)) error {
+		n, err := updateMany()
+		if err != nil {
+			return fmt.Errorf("anonymizing %s: %w", name, err)
+		}
+		anonymized[name] += n
+		return nil
+	}
+
+	// comments and likes on the user's posts go with the posts
+	var postIDs []primitive.ObjectID
+	posts, err := repos.Posts.ListByUser(ctx, UID)
+	if err != nil {
+		return fmt.Errorf("finding posts: %w", err)
+	}
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	if len(postIDs) > 0 {
+		if err := remove("comments", func() (int64, error) { return repos.Comments.DeleteOnPosts(ctx, postIDs) }); err != nil {
+			return err
+		}
+		if err := remove("likes", func() (int64, error) { return repos.Likes.DeleteOnPosts(ctx, postIDs) }); err != nil {
+			return err
+		}
+	}
+
+	steps := []struct {
+		name       string
+		deleteMany func() (int64, error)
+	}{
+		{"posts", func() (int64, error) { return repos.Posts.DeleteByUser(ctx, UID) }},
+		{"comments", func() (int64, error) { return repos.Comments.DeleteByUser(ctx, UID) }},
+		{"likes", func() (int64, error) { return repos.Likes.DeleteByUser(ctx, UID) }},
+		{"follows", func() (int64, error) { return repos.Follows.DeleteByUser(ctx, UID) }},
+		{"blocks", func() (int64, error) { return repos.Blocks.DeleteByUser(ctx, UID) }},
+		{"mutes", func() (int64, error) { return repos.Mutes.DeleteByUser(ctx, UID) }},
+		{"notifications", func() (int64, error) { return repos.Notifications.DeleteByUser(ctx, UID) }},
+		{"sessions", func() (int64, error) { return repos.Sessions.DeleteByUser(ctx, UID) }},
+		{"api_keys", func() (int64, error) { return repos.APIKeys.DeleteByUser(ctx, UID) }},
+		{"login_throttles", func() (int64, error) { return repos.Throttles.Clear(ctx, accountThrottleKey(*user.Email)) }},
+		{"lockout_events", func() (int64, error) {
+			return repos.Throttles.DeleteLockouts(ctx, models.LockoutScopeAccount, *user.Email)
+		}},
+	}
+	for _, step := range steps {
+		if err := remove(step.name, step.deleteMany); err != nil {
+			return err
+		}
+	}
+
+	exports, err := removeDataExports(ctx, repos.Exports, UID)
+	if err != nil {
+		return fmt.Errorf("removing data exports: %w", err)
+	}
+	removed["data_exports"] = exports
+
+	// other participants keep their conversations, without the user's words
+	anonymizeSteps := []struct {
+		name       string
+		updateMany func() (int64, error)
+	}{
+		{"messages", func() (int64, error) { return repos.Messages.Anonymize(ctx, UID, deletedMessageBody, time.Now()) }},
+		{"message_receipts", func() (int64, error) { return repos.Messages.RemoveReader(ctx, UID) }},
+		{"conversations", func() (int64, error) { return repos.Conversations.RemoveParticipant(ctx, UID, time.Now()) }},
+		{"reports", func() (int64, error) { return repos.Reports.AnonymizeReporter(ctx, UID, time.Now()) }},
+	}
+	for _, step := range anonymizeSteps {
+		if err := anonymize(step.name, step.updateMany); err != nil {
+			return err
+		}
+	}
+
+	if err := repos.Users.Delete(ctx, UID); err != nil {
+		return fmt.Errorf("removing the user: %w", err)
+	}
+	removed["users"] = 1
+
+	now := time.Now()
+	var deletion models.AccountDeletion
+	deletion.ID = primitive.NewObjectID()
+	deletion.User_ID = UID
+	deletion.Status = models.DeletionStatusCompleted
+	deletion.Requested_at = now
+	deletion.Scheduled_for = *user.Deletion_scheduled_at
+	deletion.Completed_at = &now
+	deletion.Removed = removed
+	deletion.Anonymized = anonymized
+	deletion.Created_at = now
+	deletion.Updated_at = now
+
+	// the record written at request time is completed, or created when it is missing
+	if err := repos.Deletions.Complete(ctx, deletion); err != nil {
+		return fmt.Errorf("recording the deletion: %w", err)
+	}
+
+	slog.InfoContext(ctx, "purged account", "user_id", user.User_id, "removed", removed, "anonymized", anonymized)
+	return nil
+}
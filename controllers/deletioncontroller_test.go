@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	charles := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+
+	due := time.Now().Add(-time.Minute)
+	s.store.Users[0].Deletion_scheduled_at = &due
+	s.store.Deletions = append(s.store.Deletions, models.AccountDeletion{
+		ID:            primitive.NewObjectID(),
+		User_ID:       ada.ID,
+		Status:        models.DeletionStatusScheduled,
+		Scheduled_for: due,
+	})
+	s.store.Follows = append(s.store.Follows,
+		models.Follow{ID: primitive.NewObjectID(), Follower_ID: ada.ID, Following_ID: charles.ID},
+		models.Follow{ID: primitive.NewObjectID(), Follower_ID: charles.ID, Following_ID: ada.ID},
+	)
+	conversation := models.Conversation{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{ada.ID, charles.ID}}
+	s.store.Conversations = append(s.store.Conversations, conversation)
+	body := "See you at the Royal Society"
+	s.store.Messages = append(s.store.Messages, models.Message{
+		ID:              primitive.NewObjectID(),
+		Conversation_ID: conversation.ID,
+		Sender_ID:       ada.ID,
+		Body:            &body,
+		Read_by:         []primitive.ObjectID{ada.ID, charles.ID},
+	})
+	s.store.Reports = append(s.store.Reports, models.Report{ID: primitive.NewObjectID(), Reporter_ID: ada.ID})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	purged, err := PurgeDeletedAccounts(ctx, s.store.Repositories())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("purged %d accounts, want 1", purged)
+	}
+
+	if len(s.store.Users) != 1 || s.store.Users[0].ID != charles.ID {
+		t.Error("the deleted user is still stored")
+	}
+	if len(s.store.Follows) != 0 {
+		t.Errorf("got %d follows, want none", len(s.store.Follows))
+	}
+	message := s.store.Messages[0]
+	if *message.Body != deletedMessageBody || len(message.Read_by) != 1 {
+		t.Errorf("message was not anonymized: %q read by %v", *message.Body, message.Read_by)
+	}
+	if participants := s.store.Conversations[0].Participants; len(participants) != 1 || participants[0] != charles.ID {
+		t.Errorf("got participants %v, want only the other user", participants)
+	}
+	if s.store.Reports[0].Reporter_ID != primitive.NilObjectID {
+		t.Error("the report still names its reporter")
+	}
+
+	deletion := s.store.Deletions[0]
+	if deletion.Status != models.DeletionStatusCompleted || len(s.store.Deletions) != 1 {
+		t.Fatalf("got deletion records %+v, want the scheduled one completed", s.store.Deletions)
+	}
+	if deletion.Removed["follows"] != 2 || deletion.Anonymized["messages"] != 1 || deletion.Anonymized["conversations"] != 1 {
+		t.Errorf("got removed %v and anonymized %v", deletion.Removed, deletion.Anonymized)
+	}
+}
+
+func TestRestoreAccountChecksSecondFactorFirst(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	codes, hashes := helper.NewRecoveryCodes()
+	secret := "JBSWY3DPEHPK3PXP"
+	scheduled := time.Now().Add(time.Hour)
+	s.store.Users[0].Totp_enabled = true
+	s.store.Users[0].Totp_secret = &secret
+	s.store.Users[0].Recovery_codes = hashes
+	s.store.Users[0].Deletion_scheduled_at = &scheduled
+	s.store.Deletions = append(s.store.Deletions, models.AccountDeletion{
+		ID:            primitive.NewObjectID(),
+		User_ID:       ada.ID,
+		Status:        models.DeletionStatusScheduled,
+		Scheduled_for: scheduled,
+	})
+
+	input := map[string]string{"email": "ada@example.com", "Password": "Analytical-Engine-1843"}
+	w := s.request(t, http.MethodPost, "/users/restore", input, "")
+	expectStatus(t, w, http.StatusBadRequest)
+
+	input["recovery_code"] = "00000-00000"
+	w = s.request(t, http.MethodPost, "/users/restore", input, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+	if s.store.Users[0].Deletion_scheduled_at == nil || s.store.Deletions[0].Status != models.DeletionStatusScheduled {
+		t.Fatal("the password alone cancelled the deletion")
+	}
+
+	input["recovery_code"] = codes[0]
+	w = s.request(t, http.MethodPost, "/users/restore", input, "")
+	expectStatus(t, w, http.StatusOK)
+	if s.store.Users[0].Deletion_scheduled_at != nil {
+		t.Error("the deletion is still scheduled")
+	}
+	if s.store.Deletions[0].Status != models.DeletionStatusRestored {
+		t.Errorf("got deletion status %q, want restored", s.store.Deletions[0].Status)
+	}
+}
@@ -0,0 +1,475 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExportHandler serves the data export endpoints from the repositories it
+// is given.
+type ExportHandler struct {
+	Exports repository.ExportRepository
+}
+
+// NewExportHandler returns an ExportHandler using repos.
+func NewExportHandler(repos repository.Repositories) *ExportHandler {
+	return &ExportHandler{Exports: repos.Exports}
+}
+
+// exportClaimTTL is how long an export may run before another worker may
+// start it again.
+const exportClaimTTL = time.Hour
+
+// exportURL returns the download link of a finished export. The link is
+// signed and stops working when the export expires.
+func exportURL(export models.DataExport) (string, error) {
+	token, err := helper.GenerateActionToken(helper.PurposeDataExport, export.User_ID.Hex(), "", export.ID.Hex(), time.Until(*export.Expires_at))
+	if err != nil {
+		return "", err
+	}
+	return helper.AppURL() + "/users/exports/download?token=" + token, nil
+}
+
+// RequestDataExport starts an export of the logged in user's data
+// @Summary Export my data
+// @Description Start building a ZIP of JSON files with the logged in user's profile, posts, comments, likes, follows, blocks, mutes, conversations with their messages, notifications, the reports the user made, sessions and API keys. The export runs in the background; poll /users/me/exports/{id} until its status is ready and download it from the returned link before it expires (7 days by default). While an export is waiting or running it is returned instead of starting another.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Success 202 {object} models.DataExport
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/exports [post]
+func (h *ExportHandler) RequestDataExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		export, err := h.Exports.FindActive(ctx, UID)
+		if err == nil {
+			c.JSON(http.StatusAccepted, export)
+			return
+		}
+		if err != repository.ErrNotFound {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking for running exports")
+			return
+		}
+
+		export.ID = primitive.NewObjectID()
+		export.User_ID = UID
+		export.Status = models.ExportStatusPending
+		export.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		export.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := h.Exports.Create(ctx, export); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Export could not be started")
+			return
+		}
+
+		// wake the job rather than waiting for its next run; it is the one
+		// shutdown waits for
+		select {
+		case exportRequested <- struct{}{}:
+		default:
+		}
+
+		c.JSON(http.StatusAccepted, export)
+	}
+}
+
+// GetDataExport returns the status of an export
+// @Summary Get export status
+// @Description Get the status of one of the logged in user's exports. Once it is ready the response includes a download link that works until the export expires.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Export ID"
+// @Success 200 {object} models.DataExport
+// @Failure 400 {object} models.Error "Invalid export ID"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Export not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/exports/{id} [get]
+func (h *ExportHandler) GetDataExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid export ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		export, err := h.Exports.FindByID(ctx, id, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Export not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching export")
+			return
+		}
+
+		if export.Status == models.ExportStatusReady && export.Expires_at.After(time.Now()) {
+			export.Download_url, err = exportURL(export)
+			if err != nil {
+				helper.RespondError(c, http.StatusInternalServerError, "Download link could not be created")
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, export)
+	}
+}
+
+// DownloadDataExport downloads a finished export
+// @Summary Download an export
+// @Description Download the ZIP file of a finished export. The signed link comes from the export status and needs no other authentication.
+// @Tags Account
+// @Produce application/zip
+// @Param token query string true "Download token from the export status"
+// @Success 200 {file} file "ZIP of JSON files"
+// @Failure 400 {object} models.Error "Invalid or expired link"
+// @Failure 410 {object} models.Error "The export has expired"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/exports/download [get]
+func (h *ExportHandler) DownloadDataExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, msg := helper.ValidateActionToken(c.Query("token"), helper.PurposeDataExport)
+		if msg != "" {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired download link")
+			return
+		}
+		id, err := primitive.ObjectIDFromHex(claims.Id)
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired download link")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(claims.Uid)
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired download link")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		export, err := h.Exports.FindByID(ctx, id, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired download link")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching export")
+			return
+		}
+		if export.Status != models.ExportStatusReady || export.File_ID == nil || !export.Expires_at.After(time.Now()) {
+			helper.RespondError(c, http.StatusGone, "The export has expired")
+			return
+		}
+
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"data-export-%s.zip\"", export.Created_at.Format("2006-01-02")))
+		c.Header("Content-Length", fmt.Sprint(export.Size))
+		c.Status(http.StatusOK)
+		if _, err := h.Exports.WriteFile(ctx, *export.File_ID, c.Writer); err != nil {
+			slog.ErrorContext(ctx, "streaming export failed", "export_id", export.ID.Hex(), "error", err)
+		}
+	}
+}
+
+// exportRequested wakes RunDataExports when an export is requested.
+var exportRequested = make(chan struct{}, 1)
+
+// RunDataExports builds waiting exports and removes expired ones every
+// DATA_EXPORT_INTERVAL, and whenever an export is requested, until ctx is
+// cancelled. It is started from main.
+func RunDataExports(ctx context.Context, repos repository.Repositories) {
+	setWorkerRunning(workerDataExport, true)
+	defer setWorkerRunning(workerDataExport, false)
+
+	for {
+		if err := ProcessDataExports(ctx, repos); err != nil {
+			slog.ErrorContext(ctx, "processing data exports failed", "error", err)
+		}
+		if err := ExpireDataExports(ctx, repos); err != nil {
+			slog.ErrorContext(ctx, "expiring data exports failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-exportRequested:
+		case <-time.After(config.App.DataExportInterval):
+		}
+	}
+}
+
+// ProcessDataExports builds every export that is waiting. Each export is
+// claimed first so several API instances can run the job at once.
+func ProcessDataExports(ctx context.Context, repos repository.Repositories) error {
+	for ctx.Err() == nil {
+		found, err := processNextDataExport(ctx, repos)
+		if err != nil || !found {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// processNextDataExport claims and builds one waiting export, reporting
+// whether there was one. A failed export is marked as such rather than
+// returned as an error, so one bad export does not hold up the rest.
+func processNextDataExport(parent context.Context, repos repository.Repositories) (bool, error) {
+	var ctx, cancel = context.WithTimeout(parent, config.App.DBTimeout)
+	defer cancel()
+
+	export, err := repos.Exports.Claim(ctx, time.Now(), exportClaimTTL)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	fileID, size, err := buildDataExport(ctx, repos, export)
+	if err != nil && parent.Err() != nil {
+		// stopped by shutdown; hand the export back for the next run
+		releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(parent), 5*time.Second)
+		defer cancelRelease()
+		if err := repos.Exports.Release(releaseCtx, export.ID, time.Now()); err != nil {
+			slog.ErrorContext(releaseCtx, "releasing interrupted export failed", "export_id", export.ID.Hex(), "error", err)
+		}
+		return true, parent.Err()
+	}
+	now := time.Now()
+	if err != nil {
+		slog.ErrorContext(ctx, "building export failed", "export_id", export.ID.Hex(), "error", err)
+		export.Status = models.ExportStatusFailed
+		export.Error = "The export could not be built, please request a new one"
+	} else {
+		expiresAt := now.Add(config.App.DataExportTTL)
+		export.Status = models.ExportStatusReady
+		export.File_ID = &fileID
+		export.Size = size
+		export.Expires_at = &expiresAt
+	}
+	export.Completed_at = &now
+	export.Updated_at = now
+
+	if err := repos.Exports.Finish(ctx, export); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// buildDataExport writes the user's data to a ZIP of JSON files and stores
+// it with the export repository, returning the file ID and size. Messages are
+// exported for every conversation the user takes part in, since the user can
+// read all of them. Reports about the user, moderation decisions and
+// sanctions are left out: they belong to the moderators' records and could
+// identify whoever made a report.
+func buildDataExport(ctx context.Context, repos repository.Repositories, export models.DataExport) (primitive.ObjectID, int64, error) {
+	UID := export.User_ID
+
+	user, err := repos.Users.FindByID(ctx, UID.Hex())
+	if err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("loading the user: %w", err)
+	}
+	// credentials are not personal data worth handing out
+	user.Password = nil
+	user.Token = nil
+	user.Refresh_token = nil
+
+	posts, err := repos.Posts.ListByUser(ctx, UID)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	comments, err := repos.Comments.ListByUser(ctx, UID)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	likes, err := repos.Likes.ListByUser(ctx, UID)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	following, err := repos.Follows.List(ctx, &UID, nil, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	followers, err := repos.Follows.List(ctx, nil, &UID, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	blocks, err := repos.Blocks.ListByBlocker(ctx, UID, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	mutes, err := repos.Mutes.ListByMuter(ctx, UID, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	conversations, err := repos.Conversations.ListByParticipant(ctx, UID, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	conversationIDs := make([]primitive.ObjectID, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+	}
+	messages, err := repos.Messages.ListByConversations(ctx, conversationIDs)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	notifications, err := repos.Notifications.List(ctx, UID, false, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	reports, err := repos.Reports.ListByReporter(ctx, UID)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	for i := range reports {
+		reports[i].Moderator_ID = nil
+	}
+
+	sessions, err := repos.Sessions.ListByUser(ctx, UID)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	apiKeys, err := repos.APIKeys.ListByUser(ctx, UID, 0, 0)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"posts.json", posts},
+		{"comments.json", comments},
+		{"likes.json", likes},
+		{"following.json", following},
+		{"followers.json", followers},
+		{"blocks.json", blocks},
+		{"mutes.json", mutes},
+		{"conversations.json", conversations},
+		{"messages.json", messages},
+		{"notifications.json", notifications},
+		{"reports.json", reports},
+		{"sessions.json", sessions},
+		{"api_keys.json", apiKeys},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return primitive.NilObjectID, 0, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return primitive.NilObjectID, 0, fmt.Errorf("writing %s: %w", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	size := int64(buf.Len())
+	fileID, err := repos.Exports.SaveFile(ctx, export.ID.Hex()+".zip", &buf)
+	if err != nil {
+		return primitive.NilObjectID, 0, fmt.Errorf("storing the export: %w", err)
+	}
+	return fileID, size, nil
+}
+
+// ExpireDataExports deletes the files of exports past their expiry.
+func ExpireDataExports(parent context.Context, repos repository.Repositories) error {
+	var ctx, cancel = context.WithTimeout(parent, config.App.DBTimeout)
+	defer cancel()
+
+	exports, err := repos.Exports.ListExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := deleteExportFile(ctx, repos.Exports, export); err != nil {
+			return err
+		}
+		if err := repos.Exports.MarkExpired(ctx, export.ID, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExportFile removes the ZIP of an export, if it has one.
+func deleteExportFile(ctx context.Context, exports repository.ExportRepository, export models.DataExport) error {
+	if export.File_ID == nil {
+		return nil
+	}
+	return exports.DeleteFile(ctx, *export.File_ID)
+}
+
+// removeDataExports deletes every export of a user along with its file, and
+// returns how many there were.
+func removeDataExports(ctx context.Context, exports repository.ExportRepository, UID primitive.ObjectID) (int64, error) {
+	found, err := exports.ListByUser(ctx, UID)
+	if err != nil {
+		return 0, err
+	}
+	for _, export := range found {
+		if err := deleteExportFile(ctx, exports, export); err != nil {
+			return 0, err
+		}
+	}
+	return exports.DeleteByUser(ctx, UID)
+}
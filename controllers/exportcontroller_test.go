@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// readExport returns the files of a stored export ZIP by name.
+func readExport(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestDataExportIncludesEverythingOfTheUser(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	charles := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+	mary := s.addUser(t, "mary@example.com", "Somerville-1780")
+	now := time.Now()
+	body := func(text string) *string { return &text }
+
+	joint := models.Conversation{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{ada.ID, charles.ID}, Created_by: ada.ID, Created_at: now}
+	other := models.Conversation{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{charles.ID, mary.ID}, Created_by: charles.ID, Created_at: now}
+	s.store.Conversations = append(s.store.Conversations, joint, other)
+	s.store.Messages = append(s.store.Messages,
+		models.Message{ID: primitive.NewObjectID(), Conversation_ID: joint.ID, Sender_ID: ada.ID, Body: body("Have you seen the engine?"), Created_at: now},
+		models.Message{ID: primitive.NewObjectID(), Conversation_ID: joint.ID, Sender_ID: charles.ID, Body: body("Splendid work"), Created_at: now},
+		models.Message{ID: primitive.NewObjectID(), Conversation_ID: other.ID, Sender_ID: charles.ID, Body: body("Not for Ada"), Created_at: now},
+	)
+	s.store.Blocks = append(s.store.Blocks, models.Block{ID: primitive.NewObjectID(), Blocker_ID: ada.ID, Blocked_ID: mary.ID, Created_at: now})
+	s.store.Mutes = append(s.store.Mutes, models.Mute{ID: primitive.NewObjectID(), Muter_ID: ada.ID, Muted_ID: charles.ID, Created_at: now})
+	moderator := primitive.NewObjectID()
+	s.store.Reports = append(s.store.Reports,
+		models.Report{ID: primitive.NewObjectID(), Reporter_ID: ada.ID, Target_type: "user", Target_ID: mary.ID, Reason: "spam", Status: models.ReportStatusDismissed, Moderator_ID: &moderator, Created_at: now},
+		models.Report{ID: primitive.NewObjectID(), Reporter_ID: charles.ID, Target_type: "user", Target_ID: ada.ID, Reason: "spam", Status: models.ReportStatusOpen, Created_at: now},
+	)
+	s.store.Sessions = append(s.store.Sessions, models.Session{ID: primitive.NewObjectID(), User_ID: ada.ID, User_agent: "test", Refresh_token_hash: "refresh-hash", Last_seen_at: now, Expires_at: now.Add(time.Hour), Created_at: now})
+	s.store.APIKeys = append(s.store.APIKeys, models.APIKey{ID: primitive.NewObjectID(), User_ID: ada.ID, Name: "scripts", Key_hash: "key-hash", Expires_at: now.Add(time.Hour), Created_at: now})
+	s.store.Exports = append(s.store.Exports, models.DataExport{ID: primitive.NewObjectID(), User_ID: ada.ID, Status: models.ExportStatusPending, Created_at: now})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ProcessDataExports(ctx, s.store.Repositories()); err != nil {
+		t.Fatal(err)
+	}
+	export := s.store.Exports[0]
+	if export.Status != models.ExportStatusReady || export.File_ID == nil {
+		t.Fatalf("got export %+v, want a ready one", export)
+	}
+	files := readExport(t, s.store.ExportFiles[*export.File_ID])
+
+	decodeFile := func(name string, v interface{}) {
+		t.Helper()
+		content, ok := files[name]
+		if !ok {
+			t.Fatalf("the export has no %s", name)
+		}
+		if err := json.Unmarshal([]byte(content), v); err != nil {
+			t.Fatalf("decoding %s: %v", name, err)
+		}
+	}
+	var conversations []models.Conversation
+	decodeFile("conversations.json", &conversations)
+	if len(conversations) != 1 || conversations[0].ID != joint.ID {
+		t.Errorf("got conversations %+v, want only the one Ada takes part in", conversations)
+	}
+	var messages []models.Message
+	decodeFile("messages.json", &messages)
+	if len(messages) != 2 {
+		t.Errorf("got %d messages, want both of Ada's conversation", len(messages))
+	}
+	var blocks []models.Block
+	decodeFile("blocks.json", &blocks)
+	var mutes []models.Mute
+	decodeFile("mutes.json", &mutes)
+	if len(blocks) != 1 || len(mutes) != 1 {
+		t.Errorf("got %d blocks and %d mutes, want one of each", len(blocks), len(mutes))
+	}
+	var reports []models.Report
+	decodeFile("reports.json", &reports)
+	if len(reports) != 1 || reports[0].Reporter_ID != ada.ID || reports[0].Moderator_ID != nil {
+		t.Errorf("got reports %+v, want Ada's report without its moderator", reports)
+	}
+	var sessions []models.Session
+	decodeFile("sessions.json", &sessions)
+	if len(sessions) != 1 {
+		t.Errorf("got %d sessions, want 1", len(sessions))
+	}
+	var apiKeys []models.APIKey
+	decodeFile("api_keys.json", &apiKeys)
+	if len(apiKeys) != 1 || apiKeys[0].Name != "scripts" {
+		t.Errorf("got API keys %+v", apiKeys)
+	}
+
+	for name, content := range files {
+		for _, secret := range []string{"Not for Ada", "refresh-hash", "key-hash", *ada.Password} {
+			if strings.Contains(content, secret) {
+				t.Errorf("%s holds %q", name, secret)
+			}
+		}
+	}
+}
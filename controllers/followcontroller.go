@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FollowHandler serves the follow endpoints from the repositories it is given.
+type FollowHandler struct {
+	Users   repository.UserRepository
+	Follows repository.FollowRepository
+}
+
+// NewFollowHandler returns a FollowHandler using repos.
+func NewFollowHandler(repos repository.Repositories) *FollowHandler {
+	return &FollowHandler{Users: repos.Users, Follows: repos.Follows}
+}
+
+// CreateFollow makes the authenticated user follow another user
+// @Summary Follow a user
+// @Description This endpoint allows a user to follow another user.
+// @Tags Follow
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param follow body models.FollowInput true "User to follow"
+// @Success 200 {object} models.CreateOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Blocked"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /follows [post]
+func (h *FollowHandler) CreateFollow() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var follow models.Follow
+		if err := c.ShouldBindJSON(&follow); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(follow); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if follow.Following_ID == UID {
+			helper.RespondError(c, http.StatusBadRequest, "You cannot follow yourself")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		blocked, err := h.Users.IsBlocked(ctx, UID, follow.Following_ID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking blocks")
+			return
+		}
+		if blocked {
+			helper.RespondError(c, http.StatusForbidden, "You cannot interact with this user")
+			return
+		}
+
+		already, err := h.Follows.Exists(ctx, UID, follow.Following_ID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking follow")
+			return
+		}
+		if already {
+			helper.RespondError(c, http.StatusBadRequest, "You already follow this user")
+			return
+		}
+
+		follow.ID = primitive.NewObjectID()
+		follow.Follower_ID = UID
+		follow.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		follow.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := h.Follows.Create(ctx, follow); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "follow item was not created")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"InsertedID": follow.ID})
+	}
+}
+
+// DeleteFollow removes a follow by ID
+// @Summary Unfollow a user
+// @Description This endpoint allows a user to delete one of their follows by ID.
+// @Tags Follow
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Follow ID"
+// @Success 200 {string} Follow deleted successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Follow not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /follows/{id} [delete]
+func (h *FollowHandler) DeleteFollow() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid follow ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Follows.Delete(ctx, objectID, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Follow not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete follow")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Follow deleted successfully"})
+	}
+}
+
+// GetFollowList is the API used to get a list of follows with pagination
+// @Summary Get a list of Follows
+// @Description This endpoint retrieves a paginated list of follows, optionally filtered by follower or followed user.
+// @Tags Follow
+// @Accept json
+// @Produce json
+// @Param follower_id query string false "Only follows made by this user"
+// @Param following_id query string false "Only follows of this user"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of follows per page" default(10)
+// @Success 200 {object} models.FollowList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /follows [get]
+func (h *FollowHandler) GetFollowList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		filter := map[string]*primitive.ObjectID{}
+		for _, key := range []string{"follower_id", "following_id"} {
+			if value := c.Query(key); value != "" {
+				id, err := primitive.ObjectIDFromHex(value)
+				if err != nil {
+					helper.RespondError(c, http.StatusBadRequest, "Invalid " + key)
+					return
+				}
+				filter[key] = &id
+			}
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		follows, err := h.Follows.List(ctx, filter["follower_id"], filter["following_id"], int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching follows")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  follows,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testMail is an email a handler sent during a test.
+type testMail struct {
+	to      string
+	subject string
+	body    string
+}
+
+// testMailer records email instead of sending it.
+type testMailer struct {
+	mu   sync.Mutex
+	sent []testMail
+}
+
+func (m *testMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, testMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+var mailTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+// tokenSentTo returns the token in the link of the last email sent to to.
+func (m *testMailer) tokenSentTo(t *testing.T, to string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].to != to {
+			continue
+		}
+		match := mailTokenPattern.FindStringSubmatch(m.sent[i].body)
+		if match == nil {
+			t.Fatalf("email %q to %s has no token link", m.sent[i].subject, to)
+		}
+		return match[1]
+	}
+	t.Fatalf("no email was sent to %s", to)
+	return ""
+}
+
+// testServer serves the handlers from an in-memory store.
+type testServer struct {
+	router *gin.Engine
+	store  *repository.Memory
+	mail   *testMailer
+}
+
+// newTestServer returns a server with the account, post, comment, like,
+// follow and block routes, using cheap password hashes and recording the
+// email it sends.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	config.App = config.Defaults()
+	config.App.SecretKey = "test-secret"
+	config.App.BcryptCost = bcrypt.MinCost
+	mail := &testMailer{}
+	helper.Mail = mail
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		WaitForBackground(ctx)
+		config.App = config.Defaults()
+		helper.Mail = helper.LogMailer{}
+	})
+
+	store := repository.NewMemory()
+	repos := store.Repositories()
+	auth := middleware.NewAuth(repos)
+	router := gin.New()
+
+	accounts := NewAccountHandler(repos)
+	router.POST("/users/signup", accounts.SignUp())
+	router.POST("/users/login", accounts.Login())
+	router.POST("/users/token/refresh", accounts.RefreshToken())
+	router.POST("/users/verify-email", accounts.VerifyEmail())
+	router.POST("/users/password/forgot", accounts.ForgotPassword())
+	router.POST("/users/password/reset", accounts.ResetPassword())
+	router.POST("/users/restore", accounts.RestoreAccount())
+	router.PUT("/users/me/password", auth.Authentication(), accounts.ChangePassword())
+	router.GET("/users/me/sessions", auth.Authentication(), accounts.GetSessionList())
+	router.GET("/auth/oidc/:provider/login", accounts.OIDCLogin())
+	router.GET("/auth/oidc/:provider/callback", accounts.OIDCCallback())
+
+	posts := NewPostHandler(repos)
+	router.POST("/posts", auth.Authentication(models.ScopeWritePosts), posts.CreatePost())
+	router.GET("/posts/:id", auth.OptionalAuthentication(models.ScopeReadPosts), posts.GetPostByID())
+	router.PUT("/posts/:id", auth.Authentication(models.ScopeWritePosts), posts.UpdatePost())
+	router.DELETE("/posts/:id", auth.Authentication(models.ScopeWritePosts), posts.DeletePost())
+
+	comments := NewCommentHandler(repos)
+	router.POST("/comments", auth.Authentication(models.ScopeWriteComments), comments.CreateComment())
+	router.GET("/comments/:id", auth.Authentication(models.ScopeReadPosts), comments.GetCommentByID())
+	router.DELETE("/comments/:id", auth.Authentication(models.ScopeWriteComments), comments.DeleteComment())
+
+	likes := NewLikeHandler(repos)
+	router.POST("/likes", auth.Authentication(models.ScopeWriteLikes), likes.CreateLike())
+	router.DELETE("/likes/:id", auth.Authentication(models.ScopeWriteLikes), likes.DeleteLike())
+
+	follows := NewFollowHandler(repos)
+	router.POST("/follows", auth.Authentication(models.ScopeWriteFollows), middleware.RequireVerifiedEmail("follow"), follows.CreateFollow())
+	router.GET("/follows", auth.Authentication(models.ScopeReadFollows), follows.GetFollowList())
+
+	blocks := NewBlockHandler(repos)
+	router.POST("/blocks", auth.Authentication(), blocks.CreateBlock())
+
+	return &testServer{router: router, store: store, mail: mail}
+}
+
+// tokenFor starts a session for the stored user uid and returns its access
+// token, so requests go through the authentication middleware as they would
+// after a login.
+func (s *testServer) tokenFor(t *testing.T, uid string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repos := s.store.Repositories()
+	user, err := repos.Users.FindByID(ctx, uid)
+	if err != nil {
+		t.Fatalf("no user %s to authenticate as: %v", uid, err)
+	}
+	now := time.Now()
+	session := models.Session{
+		ID:           primitive.NewObjectID(),
+		User_ID:      user.ID,
+		Last_seen_at: now,
+		Expires_at:   now.Add(time.Hour),
+		Created_at:   now,
+		Updated_at:   now,
+	}
+	if err := repos.Sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, user.Role, session.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// request sends body as JSON, as the user uid when uid is not empty.
+func (s *testServer) request(t *testing.T, method string, path string, body interface{}, uid string) *httptest.ResponseRecorder {
+	t.Helper()
+	token := ""
+	if uid != "" {
+		token = s.tokenFor(t, uid)
+	}
+	return s.send(t, method, path, body, token)
+}
+
+// send sends body as JSON with token, which may be empty, in the token
+// header.
+func (s *testServer) send(t *testing.T, method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// addUser stores a verified user with the given password.
+func (s *testServer) addUser(t *testing.T, email string, password string) models.User {
+	t.Helper()
+	hash := HashPassword(password)
+	first, last, phone := "Test", "User", fmt.Sprintf("+4915112345%03d", len(s.store.Users))
+	now := time.Now()
+
+	var user models.User
+	user.ID = primitive.NewObjectID()
+	user.User_id = user.ID.Hex()
+	user.First_name = &first
+	user.Last_name = &last
+	user.Email = &email
+	user.Phone = &phone
+	user.Password = &hash
+	user.Role = models.RoleUser
+	user.Email_verified = true
+	user.Created_at = now
+	user.Updated_at = now
+	s.store.Users = append(s.store.Users, user)
+	return user
+}
+
+// decode reads the JSON response into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+}
+
+// expectStatus fails the test when the response does not have the status.
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+}
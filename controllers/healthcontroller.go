@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/database"
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Names of the background workers readiness waits for.
+const (
+	workerAccountDeletion = "account_deletion"
+	workerDataExport      = "data_export"
+)
+
+// readinessPingTimeout bounds the MongoDB ping of a readiness probe, which
+// must answer well within the probe's own timeout.
+const readinessPingTimeout = 2 * time.Second
+
+// readiness holds what the process reports about itself on /readyz.
+var readiness = struct {
+	sync.Mutex
+	indexesEnsured bool
+	shuttingDown   bool
+	workers        map[string]bool
+}{workers: map[string]bool{}}
+
+// MarkIndexesEnsured records that the database indexes exist. main calls
+// it once EnsureIndexes succeeds.
+func MarkIndexesEnsured() {
+	readiness.Lock()
+	defer readiness.Unlock()
+	readiness.indexesEnsured = true
+}
+
+// MarkShuttingDown makes readiness fail so the orchestrator stops sending
+// traffic while the server drains.
+func MarkShuttingDown() {
+	readiness.Lock()
+	defer readiness.Unlock()
+	readiness.shuttingDown = true
+}
+
+func setWorkerRunning(name string, running bool) {
+	readiness.Lock()
+	defer readiness.Unlock()
+	readiness.workers[name] = running
+}
+
+// runCheck times check and reports its outcome under name.
+func runCheck(name string, check func() error) models.HealthCheck {
+	start := time.Now()
+	err := check()
+	result := models.HealthCheck{
+		Name:       name,
+		Status:     models.HealthStatusOK,
+		Latency_ms: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = models.HealthStatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness reports that the process is up
+// @Summary Liveness probe
+// @Description Answers as long as the process is running and serving HTTP. It checks no dependencies, so a failing database does not get the process restarted.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Router /healthz [get]
+func Liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.HealthReport{Status: models.HealthStatusOK})
+	}
+}
+
+// Readiness reports whether the process can serve traffic
+// @Summary Readiness probe
+// @Description Checks that MongoDB answers a ping, that the indexes were ensured at start, that the account deletion and data export workers are running and that the server is not shutting down. Every check is listed with its outcome and latency in milliseconds.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Failure 503 {object} models.HealthReport "A check is failing"
+// @Router /readyz [get]
+func Readiness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checks := []models.HealthCheck{
+			runCheck("mongodb", func() error {
+				ctx, cancel := context.WithTimeout(c.Request.Context(), readinessPingTimeout)
+				defer cancel()
+				if err := database.Client.Ping(ctx, readpref.Primary()); err != nil {
+					slog.ErrorContext(ctx, "readiness ping of MongoDB failed", "error", err)
+					return errors.New("MongoDB did not answer the ping")
+				}
+				return nil
+			}),
+		}
+
+		readiness.Lock()
+		indexesEnsured, shuttingDown := readiness.indexesEnsured, readiness.shuttingDown
+		workers := map[string]bool{}
+		for name, running := range readiness.workers {
+			workers[name] = running
+		}
+		readiness.Unlock()
+
+		checks = append(checks, runCheck("indexes", func() error {
+			if !indexesEnsured {
+				return errors.New("the indexes have not been ensured yet")
+			}
+			return nil
+		}))
+		for _, name := range []string{workerAccountDeletion, workerDataExport} {
+			running := workers[name]
+			checks = append(checks, runCheck("worker:"+name, func() error {
+				if !running {
+					return errors.New("the worker is not running")
+				}
+				return nil
+			}))
+		}
+		checks = append(checks, runCheck("shutdown", func() error {
+			if shuttingDown {
+				return errors.New("the server is shutting down")
+			}
+			return nil
+		}))
+
+		report := models.HealthReport{Status: models.HealthStatusOK, Checks: checks}
+		for _, check := range checks {
+			if check.Status != models.HealthStatusOK {
+				report.Status = models.HealthStatusFailing
+			}
+		}
+		if report.Status != models.HealthStatusOK {
+			c.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
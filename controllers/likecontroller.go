@@ -3,20 +3,30 @@ package controller
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"social-media-api/database"
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/metrics"
 	"social-media-api/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"social-media-api/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
-var likeCollection *mongo.Collection = database.OpenCollection(database.Client, "like")
+
+// LikeHandler serves the like endpoints from the repositories it is given.
+type LikeHandler struct {
+	Users repository.UserRepository
+	Posts repository.PostRepository
+	Likes repository.LikeRepository
+}
+
+// NewLikeHandler returns a LikeHandler using repos.
+func NewLikeHandler(repos repository.Repositories) *LikeHandler {
+	return &LikeHandler{Users: repos.Users, Posts: repos.Posts, Likes: repos.Likes}
+}
 
 // CreateLike is the API used to create a new like
 // @Summary Create a new Like
@@ -29,24 +39,27 @@ var likeCollection *mongo.Collection = database.OpenCollection(database.Client,
 // @Success 200 {object} models.CreateOutput
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Blocked"
+// @Failure 404 {object} models.Error "Post not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /likes [post]
-func CreateLike() gin.HandlerFunc {
+func (h *LikeHandler) CreateLike() gin.HandlerFunc {
     return func(c *gin.Context) {
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+        defer cancel()
         var like models.Like
-        if err := c.BindJSON(&like); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        if err := c.ShouldBindJSON(&like); err != nil {
+            helper.RespondInvalidInput(c, err)
             return
         }
 		UID, err := primitive.ObjectIDFromHex(uid.(string))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid UID"})
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
 			return
 		}
 		like.User_ID = UID;
@@ -56,19 +69,34 @@ func CreateLike() gin.HandlerFunc {
 
         validationErr := validate.Struct(like)
         if validationErr != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+            helper.RespondInvalidInput(c, validationErr)
             return
         }
 		
-        resultInsertionNumber, insertErr := likeCollection.InsertOne(ctx, like)
+        post, err := h.Posts.FindByID(ctx, like.Post_ID)
+        if err != nil {
+            helper.RespondError(c, http.StatusNotFound, "Post not found")
+            return
+        }
+        blocked, err := h.Users.IsBlocked(ctx, UID, post.User_id)
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Error checking blocks")
+            return
+        }
+        if blocked {
+            helper.RespondError(c, http.StatusForbidden, "You cannot interact with this user")
+            return
+        }
+
+        insertErr := h.Likes.Create(ctx, like)
         if insertErr != nil {
             msg := fmt.Sprintf("like item was not created")
-            c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+            helper.RespondError(c, http.StatusInternalServerError, msg)
             return
         }
-        defer cancel()
+        metrics.LikesCreated.Inc()
 
-        c.JSON(http.StatusOK, resultInsertionNumber)
+        c.JSON(http.StatusOK, gin.H{"InsertedID": like.ID})
 
     }
 }
@@ -82,22 +110,26 @@ func CreateLike() gin.HandlerFunc {
 // @Success 200 {object} models.Like
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Like not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /likes/{id} [get]
-func GetLikeByID() gin.HandlerFunc {
+func (h *LikeHandler) GetLikeByID() gin.HandlerFunc {
     return func(c *gin.Context) {
         likeID := c.Param("id")
         objectID, err := primitive.ObjectIDFromHex(likeID)
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid comment ID")
             return
         }
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
-        var like models.Like
-        err = likeCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&like)
+        like, err := h.Likes.FindByID(ctx, objectID)
+        if err == repository.ErrNotFound {
+            helper.RespondError(c, http.StatusNotFound, "Like not found")
+            return
+        }
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "like not found"})
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching like")
             return
         }
         c.JSON(http.StatusOK, like)
@@ -114,32 +146,39 @@ func GetLikeByID() gin.HandlerFunc {
 // @Success 200 {string} Like deleted successfully
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Like not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /likes/{id} [delete]
-func DeleteLike() gin.HandlerFunc {
+func (h *LikeHandler) DeleteLike() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		commentID := c.Param("id")
         objectID, err := primitive.ObjectIDFromHex(commentID)
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid comment ID")
             return
         }
 
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
         UID, err := primitive.ObjectIDFromHex(uid.(string))
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post UID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid post UID")
             return
         }
 
-		filter := bson.M{"_id": objectID, "user_id": UID}
-		result, err := likeCollection.DeleteOne(context.Background(), filter)
-		if err != nil || result.DeletedCount == 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment or not authorized"})
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Likes.Delete(ctx, objectID, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Like not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete like")
 			return
 		}
 
@@ -159,39 +198,26 @@ func DeleteLike() gin.HandlerFunc {
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /likes [GET]
-func GetLikeList() gin.HandlerFunc {
+func (h *LikeHandler) GetLikeList() gin.HandlerFunc {
     return func(c *gin.Context) {
         // Get pagination parameters from query string
-        page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-        if err != nil || page < 1 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
-            return
-        }
-
-        limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
-        if err != nil || limit < 1 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+        page, limit, ok := pagination(c)
+        if !ok {
             return
         }
 
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
 
-        // Define options for pagination
-        findOptions := options.Find()
-        findOptions.SetSkip(int64((page - 1) * limit))
-        findOptions.SetLimit(int64(limit))
-
-        var likes []models.Like
-
-        cursor, err := likeCollection.Find(ctx, bson.M{}, findOptions)
+        hidden, err := hiddenUserIDs(c, ctx, h.Users, false)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching likes"})
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching blocked users")
             return
         }
 
-        if err = cursor.All(ctx, &likes); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding likes"})
+        likes, err := h.Likes.ListVisible(ctx, hidden, int64((page - 1) * limit), int64(limit))
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching likes")
             return
         }
 
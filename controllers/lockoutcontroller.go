@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/metrics"
+
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Failed logins are counted per account and per client IP. Once a counter
+// reaches its limit the key is locked out, and every further failure doubles
+// the lockout up to LOGIN_LOCKOUT_MAX. Counters are forgotten after
+// LOGIN_FAILURE_WINDOW without failures.
+
+const tooManyAttemptsMessage = "too many attempts, please try again later"
+
+func accountThrottleKey(email string) string {
+	return models.LockoutScopeAccount + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return models.LockoutScopeIP + ":" + ip
+}
+
+func signupThrottleKey(ip string) string {
+	return models.LockoutScopeSignup + ":" + ip
+}
+
+// lockedFor returns how long the longest active lockout among the keys
+// still lasts, or zero when none of them is locked.
+func (h *AccountHandler) lockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	now := time.Now()
+	throttles, err := h.Throttles.FindLocked(ctx, keys, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if remaining := throttle.Locked_until.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// recordAttempt counts an attempt against key and locks the key out once
+// limit is reached, recording a LockoutEvent for admins.
+func (h *AccountHandler) recordAttempt(ctx context.Context, key string, scope string, subject string, ip string, limit int, window time.Duration) error {
+	now := time.Now()
+
+	// counters that have been quiet for a whole window start over
+	throttle, err := h.Throttles.CountFailure(ctx, key, now, now.Add(-window))
+	if err != nil {
+		return err
+	}
+	if throttle.Failures < limit {
+		return nil
+	}
+
+	lockout := config.App.LoginLockoutBase
+	for i := limit; i < throttle.Failures && lockout < config.App.LoginLockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > config.App.LoginLockoutMax {
+		lockout = config.App.LoginLockoutMax
+	}
+	until := now.Add(lockout)
+
+	var event models.LockoutEvent
+	event.ID = primitive.NewObjectID()
+	event.Scope = scope
+	event.Subject = subject
+	event.Ip_address = ip
+	event.Failures = throttle.Failures
+	event.Locked_until = until
+	event.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return h.Throttles.Lock(ctx, key, event)
+}
+
+// recordLoginFailure counts a failed login against the account and the client IP.
+func (h *AccountHandler) recordLoginFailure(ctx context.Context, email string, ip string) {
+	metrics.FailedLogins.Inc()
+	if err := h.recordAttempt(ctx, accountThrottleKey(email), models.LockoutScopeAccount, strings.ToLower(email), ip, config.App.LoginMaxFailures, config.App.LoginFailureWindow); err != nil {
+		slog.ErrorContext(ctx, "recording failed login against the account failed", "email", email, "error", err)
+	}
+	if err := h.recordAttempt(ctx, ipThrottleKey(ip), models.LockoutScopeIP, ip, ip, config.App.LoginMaxFailuresPerIP, config.App.LoginFailureWindow); err != nil {
+		slog.ErrorContext(ctx, "recording failed login against the IP failed", "client_ip", ip, "error", err)
+	}
+}
+
+// clearLoginFailures resets the account counter after a successful login.
+// The IP counter is left to expire so one valid account cannot reset it.
+func (h *AccountHandler) clearLoginFailures(ctx context.Context, email string) {
+	if _, err := h.Throttles.Clear(ctx, accountThrottleKey(email)); err != nil {
+		slog.ErrorContext(ctx, "clearing failed logins failed", "email", email, "error", err)
+	}
+}
+
+// checkCurrentPassword verifies the password a logged in user sends to confirm
+// a sensitive change. It shares the login lockout, so a stolen session cannot
+// be used to guess the password, and answers the request itself when it
+// returns false.
+func (h *AccountHandler) checkCurrentPassword(c *gin.Context, ctx context.Context, user models.User, password string, message string) bool {
+	ip := c.ClientIP()
+	if h.rejectIfLocked(c, ctx, accountThrottleKey(*user.Email), ipThrottleKey(ip)) {
+		return false
+	}
+	if valid, _ := VerifyPassword(password, *user.Password); !valid {
+		h.recordLoginFailure(ctx, *user.Email, ip)
+		helper.RespondError(c, http.StatusForbidden, message)
+		return false
+	}
+	return true
+}
+
+// rejectIfLocked answers 429 with Retry-After when any of the keys is locked out.
+func (h *AccountHandler) rejectIfLocked(c *gin.Context, ctx context.Context, keys ...string) bool {
+	wait, err := h.lockedFor(ctx, keys...)
+	if err != nil {
+		helper.RespondError(c, http.StatusInternalServerError, "error occured while checking login attempts")
+		return true
+	}
+	if wait > 0 {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+		helper.RespondError(c, http.StatusTooManyRequests, tooManyAttemptsMessage)
+		return true
+	}
+	return false
+}
+
+// GetLockoutList lists recent account and IP lockouts
+// @Summary Get lockout events
+// @Description This endpoint retrieves the paginated lockouts caused by repeated failed logins or sign-ups, newest first. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param scope query string false "Only lockouts of this scope (account, ip or signup)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of lockouts per page" default(10)
+// @Success 200 {object} models.LockoutList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/lockouts [get]
+func (h *AdminHandler) GetLockoutList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		lockouts, err := h.Throttles.ListLockouts(ctx, c.Query("scope"), int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching lockouts")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  lockouts,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MuteHandler serves the mute endpoints from the repositories it is given.
+type MuteHandler struct {
+	Mutes repository.MuteRepository
+}
+
+// NewMuteHandler returns a MuteHandler using repos.
+func NewMuteHandler(repos repository.Repositories) *MuteHandler {
+	return &MuteHandler{Mutes: repos.Mutes}
+}
+
+// CreateMute mutes another user
+// @Summary Mute a user
+// @Description Mute a user. The muted user's posts no longer appear in the muter's feed; nothing changes for the muted user.
+// @Tags Mute
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param mute body models.MuteInput true "User to mute"
+// @Success 200 {object} models.CreateOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /mutes [post]
+func (h *MuteHandler) CreateMute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var mute models.Mute
+		if err := c.ShouldBindJSON(&mute); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(mute); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if mute.Muted_ID == UID {
+			helper.RespondError(c, http.StatusBadRequest, "You cannot mute yourself")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		already, err := h.Mutes.Exists(ctx, UID, mute.Muted_ID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking mute")
+			return
+		}
+		if already {
+			helper.RespondError(c, http.StatusBadRequest, "You already muted this user")
+			return
+		}
+
+		mute.ID = primitive.NewObjectID()
+		mute.Muter_ID = UID
+		mute.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		mute.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := h.Mutes.Create(ctx, mute); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "mute item was not created")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"InsertedID": mute.ID})
+	}
+}
+
+// DeleteMute unmutes a user by mute ID
+// @Summary Unmute a user
+// @Description This endpoint allows a user to delete one of their mutes by ID.
+// @Tags Mute
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Mute ID"
+// @Success 200 {string} Mute deleted successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Mute not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /mutes/{id} [delete]
+func (h *MuteHandler) DeleteMute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid mute ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Mutes.Delete(ctx, objectID, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Mute not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete mute")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Mute deleted successfully"})
+	}
+}
+
+// GetMuteList lists the users the caller has muted
+// @Summary Get a list of Mutes
+// @Description This endpoint retrieves a paginated list of the user's mutes.
+// @Tags Mute
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of mutes per page" default(10)
+// @Success 200 {object} models.MuteList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /mutes [get]
+func (h *MuteHandler) GetMuteList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		mutes, err := h.Mutes.ListByMuter(ctx, UID, int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching mutes")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  mutes,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationHandler serves the notification endpoints from the
+// repositories it is given.
+type NotificationHandler struct {
+	Notifications repository.NotificationRepository
+}
+
+// NewNotificationHandler returns a NotificationHandler using repos.
+func NewNotificationHandler(repos repository.Repositories) *NotificationHandler {
+	return &NotificationHandler{Notifications: repos.Notifications}
+}
+
+// notify stores a notification for a user.
+func notify(ctx context.Context, notifications repository.NotificationRepository, userID primitive.ObjectID, kind string, message string, referenceID primitive.ObjectID) error {
+	var notification models.Notification
+	notification.ID = primitive.NewObjectID()
+	notification.User_ID = userID
+	notification.Type = kind
+	notification.Message = message
+	notification.Reference_ID = referenceID
+	notification.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	notification.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	return notifications.Create(ctx, notification)
+}
+
+// GetNotificationList lists the caller's notifications, newest first
+// @Summary Get a list of Notifications
+// @Description This endpoint retrieves a paginated list of the user's notifications, newest first.
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param unread query bool false "Only unread notifications"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of notifications per page" default(10)
+// @Success 200 {object} models.NotificationList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /notifications [get]
+func (h *NotificationHandler) GetNotificationList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		unreadOnly := c.Query("unread") == "true"
+		notifications, err := h.Notifications.List(ctx, UID, unreadOnly, int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching notifications")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  notifications,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// MarkNotificationRead marks one of the caller's notifications as read
+// @Summary Mark a notification as read
+// @Description This endpoint marks a notification as read.
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {string} Notification marked as read
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Notification not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /notifications/{id}/read [put]
+func (h *NotificationHandler) MarkNotificationRead() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid notification ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Notifications.MarkRead(ctx, objectID, UID, time.Now())
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Notification not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Notification could not be updated")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+	}
+}
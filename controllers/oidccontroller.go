@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+
+	"social-media-api/config"
+	"social-media-api/metrics"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// oidcStateTTL is how long the user has to sign in at the provider.
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie carries the state to the callback in the browser that
+// started the login, so a callback URL from someone else's login request
+// cannot sign the browser in to their account.
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie sets or, with an empty state, clears the state cookie.
+// It is only sent back to the provider's callback.
+func setOIDCStateCookie(c *gin.Context, providerName string, state string, maxAge int) {
+	secure := strings.HasPrefix(config.App.AppURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/auth/oidc/"+providerName+"/callback", "", secure, true)
+}
+
+// OIDCLogin starts signing in with an OpenID Connect provider
+// @Summary Sign in with an OpenID Connect provider
+// @Description Redirect to the provider's login page using the authorization code flow with PKCE. The provider redirects back to the callback endpoint.
+// @Tags Auth Registration and Login
+// @Produce json
+// @Param provider path string true "Provider name from OIDC_PROVIDERS"
+// @Success 302 {string} Redirect to the provider
+// @Failure 404 {object} models.Error "Unknown provider"
+// @Failure 502 {object} models.Error "Provider unavailable"
+// @Router /auth/oidc/{provider}/login [get]
+func (h *AccountHandler) OIDCLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := helper.OIDCProviders[c.Param("provider")]
+		if !ok {
+			helper.RespondError(c, http.StatusNotFound, "Unknown login provider")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		oauthConfig, err := provider.OAuth2Config(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "discovering OIDC provider failed", "provider", provider.Name, "error", err)
+			helper.RespondError(c, http.StatusBadGateway, "The login provider is unavailable")
+			return
+		}
+
+		var state models.OIDCState
+		state.State = helper.NewNonce()
+		state.Provider = provider.Name
+		state.Nonce = helper.NewNonce()
+		state.Verifier = oauth2.GenerateVerifier()
+		state.Expires_at = time.Now().Add(oidcStateTTL)
+		if err := h.OIDCStates.Create(ctx, state); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Login could not be started")
+			return
+		}
+
+		setOIDCStateCookie(c, provider.Name, state.State, int(oidcStateTTL.Seconds()))
+		url := oauthConfig.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier))
+		c.Redirect(http.StatusFound, url)
+	}
+}
+
+// OIDCCallback completes signing in with an OpenID Connect provider
+// @Summary OpenID Connect callback
+// @Description The provider redirects here after the user signed in. The state must match the oidc_state cookie set by the login endpoint in the same browser. A known identity logs in to its account. Otherwise an account with the same email is linked when both the provider and the account have verified that email, or a new account is created. Accounts with two-factor authentication get an MFA challenge as with a password login.
+// @Tags Auth Registration and Login
+// @Produce json
+// @Param provider path string true "Provider name from OIDC_PROVIDERS"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 200 {object} models.User "User information with tokens"
+// @Success 202 {object} models.MFAChallengeOutput "Two-factor code required"
+// @Failure 400 {object} models.Error "Invalid or expired login request"
+// @Failure 401 {object} models.Error "The provider did not confirm the login"
+// @Failure 403 {object} models.Error "Account suspended or banned"
+// @Failure 404 {object} models.Error "Unknown provider"
+// @Failure 409 {object} models.Error "An unverified account already uses this email"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *AccountHandler) OIDCCallback() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := helper.OIDCProviders[c.Param("provider")]
+		if !ok {
+			helper.RespondError(c, http.StatusNotFound, "Unknown login provider")
+			return
+		}
+		if providerErr := c.Query("error"); providerErr != "" {
+			helper.RespondError(c, http.StatusBadRequest, "The login provider returned an error: "+providerErr+" "+c.Query("error_description"))
+			return
+		}
+
+		cookieState, _ := c.Cookie(oidcStateCookie)
+		setOIDCStateCookie(c, provider.Name, "", -1)
+		if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(c.Query("state"))) != 1 {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired login request")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		// the state is deleted as it is read, so each login request works once
+		state, err := h.OIDCStates.Take(ctx, c.Query("state"), provider.Name, time.Now())
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired login request")
+			return
+		}
+
+		oauthConfig, err := provider.OAuth2Config(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "discovering OIDC provider failed", "provider", provider.Name, "error", err)
+			helper.RespondError(c, http.StatusBadGateway, "The login provider is unavailable")
+			return
+		}
+		token, err := oauthConfig.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(state.Verifier))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "The login provider did not confirm the login")
+			return
+		}
+		rawIDToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			helper.RespondError(c, http.StatusUnauthorized, "The login provider did not return an ID token")
+			return
+		}
+		idToken, err := provider.VerifyIDToken(ctx, rawIDToken)
+		if err != nil || idToken.Nonce != state.Nonce {
+			helper.RespondError(c, http.StatusUnauthorized, "The login provider did not confirm the login")
+			return
+		}
+
+		var claims helper.OIDCClaims
+		if err := idToken.Claims(&claims); err != nil || claims.Subject == "" {
+			helper.RespondError(c, http.StatusUnauthorized, "The login provider did not confirm the login")
+			return
+		}
+
+		user, status, msg := h.findOrCreateOIDCUser(ctx, provider.Name, claims)
+		if status != 0 {
+			helper.RespondError(c, status, msg)
+			return
+		}
+
+		h.completeLogin(c, user)
+	}
+}
+
+// findOrCreateOIDCUser returns the user an external identity belongs to,
+// linking or creating the account when the identity is new. It returns an
+// HTTP status and message when the login has to be refused.
+func (h *AccountHandler) findOrCreateOIDCUser(ctx context.Context, providerName string, claims helper.OIDCClaims) (models.User, int, string) {
+	user, err := h.Users.FindByIdentity(ctx, providerName, claims.Subject)
+	if err == nil {
+		return user, 0, ""
+	}
+	if err != repository.ErrNotFound {
+		return user, http.StatusInternalServerError, "error occured while looking up the account"
+	}
+
+	if claims.Email == "" {
+		return user, http.StatusBadRequest, "The login provider did not share an email address"
+	}
+	email := helper.NormalizeEmail(claims.Email)
+
+	var link models.Identity
+	link.Provider = providerName
+	link.Subject = claims.Subject
+	link.Email = email
+	link.Linked_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	user, err = h.Users.FindByEmail(ctx, email)
+	if err == nil {
+		// linking hands the account to whoever controls the provider login,
+		// so both sides must have proven they own the address
+		if !claims.EmailVerified || !user.Email_verified {
+			return user, http.StatusConflict, "An account with this email already exists. Log in with your password and verify your email address first"
+		}
+		if err := h.Users.AddIdentity(ctx, user.User_id, link); err != nil {
+			return user, http.StatusInternalServerError, "The account could not be linked"
+		}
+		user.Identities = append(user.Identities, link)
+		return user, 0, ""
+	}
+	if err != repository.ErrNotFound {
+		return user, http.StatusInternalServerError, "error occured while checking for the email"
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+	if firstName == "" {
+		firstName = strings.SplitN(email, "@", 2)[0]
+	}
+
+	// the account has no usable password until the user sets one through
+	// the forgotten password flow
+	password := HashPassword(helper.NewNonce())
+
+	user = models.User{}
+	user.ID = primitive.NewObjectID()
+	user.User_id = user.ID.Hex()
+	user.First_name = &firstName
+	user.Last_name = &lastName
+	user.Email = &email
+	user.Password = &password
+	user.Role = models.RoleUser
+	user.Email_verified = claims.EmailVerified
+	user.Identities = []models.Identity{link}
+	user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	var nonce string
+	if !user.Email_verified {
+		nonce = helper.NewNonce()
+		user.Verification_nonce = &nonce
+		user.Verification_sent_at = &user.Created_at
+	}
+
+	err = h.Users.Create(ctx, user)
+	if field := duplicateField(err); field != "" {
+		return user, http.StatusConflict, "an account with this " + field + " already exists"
+	}
+	if err != nil {
+		return user, http.StatusInternalServerError, "User item was not created"
+	}
+	metrics.Signups.WithLabelValues("oidc").Inc()
+
+	if !user.Email_verified {
+		if err := sendVerificationEmail(user, nonce); err != nil {
+			slog.ErrorContext(ctx, "sending verification email failed", "email", email, "error", err)
+		}
+	}
+	return user, 0, ""
+}
@@ -0,0 +1,332 @@
+package controller
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+)
+
+// mockProvider is an OpenID Connect provider serving discovery, keys and
+// the token endpoint. Tests authorize a login by registering a code with
+// the claims of the ID token it is exchanged for.
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	logins map[string]mockLogin
+}
+
+// mockLogin is an authorization code waiting to be exchanged.
+type mockLogin struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key, logins: map[string]mockLogin{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		login, ok := p.logins[r.Form.Get("code")]
+		delete(p.logins, r.Form.Get("code"))
+		p.mu.Unlock()
+
+		verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != login.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, login.claims)
+		idToken.Header["kid"] = "test"
+		signed, err := idToken.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     signed,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize lets the user of the login redirect sign in as subject and
+// returns the callback query. Claims override the defaults of the ID token.
+func (p *mockProvider) authorize(t *testing.T, redirect *url.URL, subject string, claims jwt.MapClaims) url.Values {
+	t.Helper()
+	query := redirect.Query()
+	if redirect.Host != p.server.Listener.Addr().String() || redirect.Path != "/authorize" {
+		t.Fatalf("login redirected to %s", redirect)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "client" {
+		t.Fatalf("login request %s lacks PKCE or the client", redirect)
+	}
+
+	idClaims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   "client",
+		"sub":   subject,
+		"nonce": query.Get("nonce"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+
+	code := helper.NewNonce()
+	p.mu.Lock()
+	p.logins[code] = mockLogin{challenge: query.Get("code_challenge"), claims: idClaims}
+	p.mu.Unlock()
+	return url.Values{"code": {code}, "state": {query.Get("state")}}
+}
+
+// oidcTestServer is a test server with the mock provider configured as "mock".
+func oidcTestServer(t *testing.T) (*testServer, *mockProvider) {
+	t.Helper()
+	s := newTestServer(t)
+	provider := newMockProvider(t)
+	helper.OIDCProviders = helper.NewOIDCProviders([]config.OIDCProvider{{
+		Name:         "mock",
+		Issuer:       provider.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8000/auth/oidc/mock/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}})
+	t.Cleanup(func() { helper.OIDCProviders = map[string]*helper.OIDCProvider{} })
+	return s, provider
+}
+
+// startOIDCLogin starts a login and returns the provider redirect and the
+// state cookie.
+func (s *testServer) startOIDCLogin(t *testing.T) (*url.URL, *http.Cookie) {
+	t.Helper()
+	w := s.request(t, http.MethodGet, "/auth/oidc/mock/login", nil, "")
+	expectStatus(t, w, http.StatusFound)
+	redirect, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			return redirect, cookie
+		}
+	}
+	t.Fatal("login did not set the state cookie")
+	return nil, nil
+}
+
+func (s *testServer) oidcCallback(t *testing.T, query url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// oidcLogin signs in at the provider as subject and completes the login.
+func (s *testServer) oidcLogin(t *testing.T, provider *mockProvider, subject string, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	t.Helper()
+	redirect, cookie := s.startOIDCLogin(t)
+	return s.oidcCallback(t, provider.authorize(t, redirect, subject, claims), cookie)
+}
+
+var verifiedAda = jwt.MapClaims{"email": "Ada@Example.com", "email_verified": true, "given_name": "Ada", "family_name": "Lovelace"}
+
+// verifiedAdaWith returns verifiedAda with one claim replaced.
+func verifiedAdaWith(name string, value interface{}) jwt.MapClaims {
+	claims := jwt.MapClaims{name: value}
+	for k, v := range verifiedAda {
+		if k != name {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+func TestOIDCLoginCreatesAndReusesAccount(t *testing.T) {
+	s, provider := oidcTestServer(t)
+
+	w := s.oidcLogin(t, provider, "subject-1", verifiedAda)
+	expectStatus(t, w, http.StatusOK)
+	var user models.User
+	decode(t, w, &user)
+	if user.Token == nil || len(s.store.Sessions) != 1 {
+		t.Fatal("the login did not start a session")
+	}
+	if len(s.store.Users) != 1 {
+		t.Fatalf("got %d users, want 1", len(s.store.Users))
+	}
+	created := s.store.Users[0]
+	if *created.Email != "ada@example.com" || !created.Email_verified || *created.First_name != "Ada" {
+		t.Errorf("created user %+v", created)
+	}
+	if len(created.Identities) != 1 || created.Identities[0].Provider != "mock" || created.Identities[0].Subject != "subject-1" {
+		t.Errorf("created identities %+v", created.Identities)
+	}
+	if len(s.store.OIDCStates) != 0 {
+		t.Error("the login state was not removed")
+	}
+
+	// the provider may have changed the email; the subject is what counts
+	w = s.oidcLogin(t, provider, "subject-1", jwt.MapClaims{"email": "lovelace@example.com", "email_verified": true})
+	expectStatus(t, w, http.StatusOK)
+	if len(s.store.Users) != 1 {
+		t.Errorf("a second login created another user")
+	}
+}
+
+func TestOIDCLoginUnverifiedEmailNeedsVerification(t *testing.T) {
+	s, provider := oidcTestServer(t)
+
+	w := s.oidcLogin(t, provider, "subject-1", jwt.MapClaims{"email": "ada@example.com", "name": "Ada Lovelace"})
+	expectStatus(t, w, http.StatusOK)
+	user := s.store.Users[0]
+	if user.Email_verified || *user.Last_name != "Lovelace" {
+		t.Errorf("created user %+v", user)
+	}
+	s.mail.tokenSentTo(t, "ada@example.com")
+}
+
+func TestOIDCLoginLinksVerifiedAccount(t *testing.T) {
+	s, provider := oidcTestServer(t)
+	existing := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+
+	w := s.oidcLogin(t, provider, "subject-1", verifiedAda)
+	expectStatus(t, w, http.StatusOK)
+	if len(s.store.Users) != 1 {
+		t.Fatalf("got %d users, want the existing one", len(s.store.Users))
+	}
+	if s.store.Users[0].ID != existing.ID || len(s.store.Users[0].Identities) != 1 {
+		t.Errorf("the identity was not linked: %+v", s.store.Users[0].Identities)
+	}
+}
+
+func TestOIDCLoginRefusesToLinkUnverifiedEmail(t *testing.T) {
+	s, provider := oidcTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+
+	w := s.oidcLogin(t, provider, "subject-1", jwt.MapClaims{"email": "ada@example.com", "email_verified": false})
+	expectStatus(t, w, http.StatusConflict)
+
+	s.store.Users[0].Email_verified = false
+	w = s.oidcLogin(t, provider, "subject-1", verifiedAda)
+	expectStatus(t, w, http.StatusConflict)
+
+	if len(s.store.Users[0].Identities) != 0 || len(s.store.Sessions) != 0 {
+		t.Error("a refused login linked the identity or started a session")
+	}
+}
+
+func TestOIDCLoginWithTwoFactorIsChallenged(t *testing.T) {
+	s, provider := oidcTestServer(t)
+	s.oidcLogin(t, provider, "subject-1", verifiedAda)
+	s.store.Users[0].Totp_enabled = true
+
+	w := s.oidcLogin(t, provider, "subject-1", verifiedAda)
+	expectStatus(t, w, http.StatusAccepted)
+	var challenge models.MFAChallengeOutput
+	decode(t, w, &challenge)
+	if challenge.Mfa_token == "" || len(s.store.Sessions) != 1 {
+		t.Error("the login was not held for the second factor")
+	}
+}
+
+func TestOIDCCallbackRejectsForgedLogins(t *testing.T) {
+	s, provider := oidcTestServer(t)
+
+	redirect, cookie := s.startOIDCLogin(t)
+	query := provider.authorize(t, redirect, "subject-1", verifiedAda)
+	w := s.oidcCallback(t, query, nil)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	other := *cookie
+	other.Value = helper.NewNonce()
+	w = s.oidcCallback(t, query, &other)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	redirect, cookie = s.startOIDCLogin(t)
+	w = s.oidcCallback(t, provider.authorize(t, redirect, "subject-1", verifiedAdaWith("nonce", "replayed")), cookie)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	redirect, cookie = s.startOIDCLogin(t)
+	w = s.oidcCallback(t, provider.authorize(t, redirect, "subject-1", verifiedAdaWith("aud", "another-client")), cookie)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	redirect, cookie = s.startOIDCLogin(t)
+	query = provider.authorize(t, redirect, "subject-1", verifiedAda)
+	query.Set("code", "unknown")
+	w = s.oidcCallback(t, query, cookie)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// the state of a failed callback cannot be used again
+	w = s.oidcCallback(t, provider.authorize(t, redirect, "subject-1", verifiedAda), cookie)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	if len(s.store.Users) != 0 || len(s.store.Sessions) != 0 {
+		t.Error("a forged login signed someone in")
+	}
+}
+
+func TestOIDCUnknownProvider(t *testing.T) {
+	s, _ := oidcTestServer(t)
+
+	w := s.request(t, http.MethodGet, "/auth/oidc/other/login", nil, "")
+	expectStatus(t, w, http.StatusNotFound)
+}
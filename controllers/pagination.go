@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+)
+
+// pagination reads the page and limit query parameters. The limit defaults
+// to DEFAULT_PAGE_LIMIT and is capped at MAX_PAGE_LIMIT. It answers with
+// 400 and returns false when either is not a positive number.
+func pagination(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		helper.RespondError(c, http.StatusBadRequest, "Invalid page number")
+		return 0, 0, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(config.App.DefaultPageLimit)))
+	if err != nil || limit < 1 {
+		helper.RespondError(c, http.StatusBadRequest, "Invalid limit number")
+		return 0, 0, false
+	}
+	if limit > config.App.MaxPageLimit {
+		limit = config.App.MaxPageLimit
+	}
+	return page, limit, true
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const forgotPasswordMessage = "If an account exists for this email, a password reset link has been sent"
+
+// ForgotPassword emails a password reset link
+// @Summary Request a password reset
+// @Description Send a short-lived, single-use password reset link to the email address. The response is the same whether or not the address is registered.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Param email body models.ForgotPasswordInput true "Account email"
+// @Success 200 {string} If an account exists for this email, a password reset link has been sent
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Router /users/password/forgot [post]
+func (h *AccountHandler) ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.ForgotPasswordInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		// the response is sent before anything that only happens for a
+		// registered email, and failures are only logged, so neither the
+		// answer nor its timing reveals whether the email is registered
+		user, err := h.Users.FindByEmail(ctx, helper.NormalizeEmail(*input.Email))
+		if err == nil {
+			runInBackground(c, func(ctx context.Context) {
+				h.sendPasswordReset(ctx, user)
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+	}
+}
+
+// sendPasswordReset emails user a reset link, unless one was sent within
+// PASSWORD_RESET_INTERVAL.
+func (h *AccountHandler) sendPasswordReset(parent context.Context, user models.User) {
+	var ctx, cancel = context.WithTimeout(parent, config.App.DBTimeout)
+	defer cancel()
+
+	if user.Password_reset_sent_at != nil && time.Since(*user.Password_reset_sent_at) < config.App.PasswordResetInterval {
+		return
+	}
+
+	nonce := helper.NewNonce()
+	now := time.Now()
+	change := repository.UserChange{Set: bson.M{"password_reset_nonce": nonce, "password_reset_sent_at": now}}
+	if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+		slog.ErrorContext(ctx, "password reset failed", "user_id", user.User_id, "error", err)
+		return
+	}
+
+	token, err := helper.GenerateActionToken(helper.PurposeResetPassword, user.User_id, *user.Email, nonce, config.App.PasswordResetTTL)
+	if err == nil {
+		link := helper.AppURL() + "/reset-password?token=" + token
+		body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. Open the link below within %s to choose a new password:\n\n%s\n\nIf this was not you, you can ignore this email.\n", *user.First_name, config.App.PasswordResetTTL, link)
+		err = helper.Mail.Send(*user.Email, "Reset your password", body)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "sending password reset email failed", "user_id", user.User_id, "error", err)
+	}
+}
+
+// ResetPassword sets a new password with a reset token
+// @Summary Reset password
+// @Description Set a new password with the token from the reset email. The password must meet the password policy. The token works once, and every existing token, session and API key of the account is revoked.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Param reset body models.ResetPasswordInput true "Reset token and new password"
+// @Success 200 {string} Password reset successfully
+// @Failure 400 {object} models.Error "Invalid or expired token, or weak password"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/password/reset [post]
+func (h *AccountHandler) ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.ResetPasswordInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		claims, msg := helper.ValidateActionToken(*input.Token, helper.PurposeResetPassword)
+		if msg != "" {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired reset link")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, claims.Uid)
+		if err != nil || user.Password_reset_nonce == nil || *user.Password_reset_nonce != claims.Id {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired reset link")
+			return
+		}
+		if rejectWeakPassword(c, "Password", *input.Password, *user.First_name, *user.Last_name, *user.Email) {
+			return
+		}
+
+		password := HashPassword(*input.Password)
+		// matching the nonce again makes the link work only once
+		match := bson.M{"password_reset_nonce": claims.Id}
+		change := repository.UserChange{
+			Set:   bson.M{"password": password, "updated_at": time.Now()},
+			Unset: []string{"password_reset_nonce", "password_reset_sent_at"},
+		}
+		err = h.Users.Update(ctx, claims.Uid, match, change)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired reset link")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Password could not be reset")
+			return
+		}
+
+		if err := h.Users.RevokeCredentials(ctx, claims.Uid); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Existing sessions could not be signed out")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	}
+}
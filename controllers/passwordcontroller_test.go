@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func waitForMail(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if !WaitForBackground(ctx) {
+		t.Fatal("background work did not finish")
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	login := s.request(t, http.MethodPost, "/users/login", map[string]string{"email": "ada@example.com", "Password": "Analytical-Engine-1843"}, "")
+	expectStatus(t, login, http.StatusOK)
+
+	w := s.request(t, http.MethodPost, "/users/password/forgot", map[string]string{"email": "ada@example.com"}, "")
+	expectStatus(t, w, http.StatusOK)
+	waitForMail(t)
+	token := s.mail.tokenSentTo(t, "ada@example.com")
+
+	reset := map[string]string{"token": token, "Password": "Difference-Engine-1822"}
+	w = s.request(t, http.MethodPost, "/users/password/reset", reset, "")
+	expectStatus(t, w, http.StatusOK)
+
+	if s.store.Sessions[0].Revoked_at == nil {
+		t.Error("the reset did not sign out the existing session")
+	}
+	w = s.request(t, http.MethodPost, "/users/login", map[string]string{"email": "ada@example.com", "Password": "Difference-Engine-1822"}, "")
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.request(t, http.MethodPost, "/users/password/reset", reset, "")
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.request(t, http.MethodPost, "/users/password/forgot", map[string]string{"email": "nobody@example.com"}, "")
+	expectStatus(t, w, http.StatusOK)
+	waitForMail(t)
+	if len(s.mail.sent) != 0 {
+		t.Errorf("sent %d emails for an unknown address", len(s.mail.sent))
+	}
+}
@@ -3,23 +3,32 @@ package controller
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
-	"social-media-api/database"
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/metrics"
 
 	"social-media-api/models"
+	"social-media-api/repository"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
-var postCollection *mongo.Collection = database.OpenCollection(database.Client, "post")
 
+// PostHandler serves the post endpoints from the repositories it is given.
+type PostHandler struct {
+	Users repository.UserRepository
+	Posts repository.PostRepository
+}
+
+// NewPostHandler returns a PostHandler using repos.
+func NewPostHandler(repos repository.Repositories) *PostHandler {
+	return &PostHandler{Users: repos.Users, Posts: repos.Posts}
+}
 
 // CreatePost handles the creation of a new post.
 // @Summary Create a new post
@@ -33,45 +42,47 @@ var postCollection *mongo.Collection = database.OpenCollection(database.Client,
 // @Failure 401 {object} models.Error "Unauthorized"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /posts [post]
-func CreatePost() gin.HandlerFunc {
+func (h *PostHandler) CreatePost() gin.HandlerFunc {
     return func(c *gin.Context) {
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 		UID, err := primitive.ObjectIDFromHex(uid.(string))
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid UID")
             return
         }
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+        defer cancel()
         var post models.Post
-        if err := c.BindJSON(&post); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        if err := c.ShouldBindJSON(&post); err != nil {
+            helper.RespondInvalidInput(c, err)
             return
         }
         validationErr := validate.Struct(post)
         if validationErr != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+            helper.RespondInvalidInput(c, validationErr)
             return
         }
 		
 		post.User_id = UID;
+		post.Hidden = false
         post.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
         post.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
         post.ID = primitive.NewObjectID()
         
 
-        resultInsertionNumber, insertErr := postCollection.InsertOne(ctx, post)
+        insertErr := h.Posts.Create(ctx, post)
         if insertErr != nil {
             msg := fmt.Sprintf("Post item was not created")
-            c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+            helper.RespondError(c, http.StatusInternalServerError, msg)
             return
         }
-        defer cancel()
+        metrics.PostsCreated.Inc()
 
-        c.JSON(http.StatusOK, resultInsertionNumber)
+        c.JSON(http.StatusOK, gin.H{"InsertedID": post.ID})
 
     }
 }
@@ -85,49 +96,44 @@ func CreatePost() gin.HandlerFunc {
 // @Param id path string true "Post ID"
 // @Success 200 {object} models.PostOutput
 // @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 404 {object} models.Error "Post not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /posts/{id} [get]
-func GetPostByID() gin.HandlerFunc {
+func (h *PostHandler) GetPostByID() gin.HandlerFunc {
     return func(c *gin.Context) {
         postID := c.Param("id")
         objID, err := primitive.ObjectIDFromHex(postID)
         if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+            helper.RespondError(c, http.StatusBadRequest, "Invalid post ID")
             return
         }
 
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
-        // Aggregation pipeline
-        pipeline := mongo.Pipeline{
-            {{Key: "$match", Value: bson.D{{"_id", objID}}}},
-            {{Key: "$lookup", Value: bson.D{{"from", "comment"}, {"localField", "_id"}, {"foreignField", "post_id"},{"as", "comments"},}}},
-            {{Key: "$lookup", Value: bson.D{{"from", "like"},{"localField", "_id"},{"foreignField", "post_id"},{"as", "likes"},}}},
-            {{Key: "$addFields", Value: bson.D{
-                {"total_comments", bson.D{{"$size", "$comments"}}},
-                {"total_likes", bson.D{{"$size", "$likes"}}},
-            }}},
-        }
 
-        cursor, err := postCollection.Aggregate(ctx, pipeline)
+        hidden, err := hiddenUserIDs(c, ctx, h.Users, false)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching post"})
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching blocked users")
             return
         }
 
-        var posts []bson.M
-        if err = cursor.All(ctx, &posts); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while decoding post data"})
+        post, err := h.Posts.FindVisible(ctx, objID, hidden)
+        if err == repository.ErrNotFound {
+            helper.RespondError(c, http.StatusNotFound, "Post not found")
+            return
+        }
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching post")
             return
         }
 
-        c.JSON(http.StatusOK, posts[0])
+        c.JSON(http.StatusOK, post)
     }
 }
 
 // UpdatePost updates an existing post.
 // @Summary Update a post
-// @Description Update an existing post by its ID for the authenticated user
+// @Description Update an existing post by its ID for the authenticated user. Moderators and admins can update any post.
 // @Tags post
 // @Accept json
 // @Produce json
@@ -136,54 +142,46 @@ func GetPostByID() gin.HandlerFunc {
 // @Success 200 {string} Post updated successfully
 // @Failure 400 {object} models.Error "Invalid request body"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Post not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /posts/{id} [put]
-func UpdatePost() gin.HandlerFunc {
+func (h *PostHandler) UpdatePost() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		postID := c.Param("id")
 		objID, err := primitive.ObjectIDFromHex(postID)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+			helper.RespondError(c, http.StatusBadRequest, "Invalid post ID")
 			return
 		}
 
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
         UID, err := primitive.ObjectIDFromHex(uid.(string))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid UID"})
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
 			return
 		}
 
 		var post models.Post
-		if err := c.BindJSON(&post); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		// Find the post and check if the user is authorized to update it
-		var existingPost models.Post
-		filter := bson.M{"_id": objID, "user_id": UID}
-		if err := postCollection.FindOne(context.Background(), filter).Decode(&existingPost); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		if err := c.ShouldBindJSON(&post); err != nil {
+			helper.RespondInvalidInput(c, err)
 			return
 		}
 
 		post.Updated_at = time.Now()
-		update := bson.M{
-			"$set": bson.M{
-				"name":       post.Name,
-				"description": post.Description,
-				"updated_at":  post.Updated_at,
-			},
-		}
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
 
-		_, err = postCollection.UpdateOne(context.Background(), filter, update)
+		err = h.Posts.Update(ctx, objID, ownerScope(c, UID), post.Name, post.Description, post.Updated_at)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Post not found")
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to update post")
 			return
 		}
 
@@ -192,7 +190,7 @@ func UpdatePost() gin.HandlerFunc {
 }
 // DeletePost deletes a post by its ID.
 // @Summary Delete a post
-// @Description Delete a post by its ID for the authenticated user
+// @Description Delete a post by its ID for the authenticated user. Moderators and admins can delete any post.
 // @Tags post
 // @Accept json
 // @Produce json
@@ -200,33 +198,40 @@ func UpdatePost() gin.HandlerFunc {
 // @Success 200 {string} Post deleted successfully
 // @Failure 400 {object} models.Error "Invalid request body"
 // @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Post not found"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /posts/{id} [delete]
-func DeletePost() gin.HandlerFunc {
+func (h *PostHandler) DeletePost() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		postID := c.Param("id")
 		objID, err := primitive.ObjectIDFromHex(postID)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+			helper.RespondError(c, http.StatusBadRequest, "Invalid post ID")
 			return
 		}
 
 		uid, exists := c.Get("uid")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
         UID, err := primitive.ObjectIDFromHex(uid.(string))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid UID"})
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
 			return
 		}
 
 
-		filter := bson.M{"_id": objID, "user_id": UID}
-		result, err := postCollection.DeleteOne(context.Background(), filter)
-		if err != nil || result.DeletedCount == 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post or not authorized"})
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Posts.Delete(ctx, objID, ownerScope(c, UID))
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Post not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to delete post")
 			return
 		}
 
@@ -245,54 +250,31 @@ func DeletePost() gin.HandlerFunc {
 // @Failure 400 {object} models.Error "Invalid pagination parameters"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /posts [get]
-func ListPosts() gin.HandlerFunc {
+func (h *PostHandler) ListPosts() gin.HandlerFunc {
     return func(c *gin.Context) {
         // Get pagination parameters from query string
-        page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-        if err != nil || page < 1 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+        page, limit, ok := pagination(c)
+        if !ok {
             return
         }
 
-        limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
-        if err != nil || limit < 1 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
-            return
-        }
-
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
         defer cancel()
 
         // Calculate skip for pagination
         skip := int64((page - 1) * limit)
 
 
-        // Aggregation pipeline
-        pipeline := mongo.Pipeline{
-            {{Key: "$lookup", Value: bson.D{{"from", "comment"}, {"localField", "_id"}, {"foreignField", "post_id"}, {"as", "comments"}}}},
-            {{Key: "$lookup", Value: bson.D{{"from", "like"}, {"localField", "_id"}, {"foreignField", "post_id"}, {"as", "likes"}}}},
-            {{Key: "$project", Value: bson.D{
-                {"name", 1},
-                {"description", 1},
-                {"created_at", 1},
-                {"updated_at", 1},
-                {"user_id", 1},
-                {"like_count", bson.D{{"$size", "$likes"}}},
-                {"comment_count", bson.D{{"$size", "$comments"}}},
-            }}},
-            {{"$skip", skip}},
-            {{"$limit", int64(limit)}},
-        }
-
-        cursor, err := postCollection.Aggregate(ctx, pipeline)
+        // Blocked and muted users are left out of the feed
+        hidden, err := hiddenUserIDs(c, ctx, h.Users, true)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching posts"})
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching blocked users")
             return
         }
 
-        var posts []bson.M
-        if err = cursor.All(ctx, &posts); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding posts"})
+        posts, err := h.Posts.ListVisible(ctx, hidden, skip, int64(limit))
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "Error fetching posts")
             return
         }
 
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *testServer) createPost(t *testing.T, uid string) string {
+	t.Helper()
+	w := s.request(t, http.MethodPost, "/posts", map[string]string{"name": "Notes", "description": "On the engine"}, uid)
+	expectStatus(t, w, http.StatusOK)
+	var created struct{ InsertedID primitive.ObjectID }
+	decode(t, w, &created)
+	return created.InsertedID.Hex()
+}
+
+func TestPostLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	other := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+
+	w := s.request(t, http.MethodPost, "/posts", map[string]string{"name": "Notes"}, owner.User_id)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	id := s.createPost(t, owner.User_id)
+
+	w = s.request(t, http.MethodGet, "/posts/"+id, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var post models.Post
+	decode(t, w, &post)
+	if post.User_id != owner.ID || *post.Name != "Notes" {
+		t.Errorf("got post %+v", post)
+	}
+
+	update := map[string]string{"name": "Sketch", "description": "Of the engine"}
+	w = s.request(t, http.MethodPut, "/posts/"+id, update, other.User_id)
+	expectStatus(t, w, http.StatusNotFound)
+	w = s.request(t, http.MethodDelete, "/posts/"+id, nil, other.User_id)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.request(t, http.MethodPut, "/posts/"+id, update, owner.User_id)
+	expectStatus(t, w, http.StatusOK)
+	if *s.store.Posts[0].Name != "Sketch" {
+		t.Errorf("post name is %q after the update", *s.store.Posts[0].Name)
+	}
+
+	w = s.request(t, http.MethodDelete, "/posts/"+id, nil, owner.User_id)
+	expectStatus(t, w, http.StatusOK)
+	w = s.request(t, http.MethodGet, "/posts/"+id, nil, "")
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestModeratorMayDeleteAnyPost(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	moderator := s.addUser(t, "mod@example.com", "Difference-Engine-1822")
+	s.store.Users[1].Role = models.RoleModerator
+	id := s.createPost(t, owner.User_id)
+
+	w := s.request(t, http.MethodDelete, "/posts/"+id, nil, moderator.User_id)
+	expectStatus(t, w, http.StatusOK)
+
+	if len(s.store.Posts) != 0 {
+		t.Error("the post was not deleted")
+	}
+}
+
+func TestCommentsAndLikes(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	other := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+	postID := s.createPost(t, owner.User_id)
+
+	comment := map[string]string{"post_id": postID, "description": "Splendid"}
+	w := s.request(t, http.MethodPost, "/comments", comment, other.User_id)
+	expectStatus(t, w, http.StatusOK)
+	var created struct{ InsertedID primitive.ObjectID }
+	decode(t, w, &created)
+	commentID := created.InsertedID.Hex()
+
+	w = s.request(t, http.MethodPost, "/likes", map[string]string{"post_id": postID}, other.User_id)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &created)
+	likeID := created.InsertedID.Hex()
+
+	w = s.request(t, http.MethodPost, "/comments", map[string]string{"post_id": primitive.NewObjectID().Hex(), "description": "Lost"}, other.User_id)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.request(t, http.MethodDelete, "/comments/"+commentID, nil, owner.User_id)
+	expectStatus(t, w, http.StatusNotFound)
+	w = s.request(t, http.MethodDelete, "/likes/"+likeID, nil, owner.User_id)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.request(t, http.MethodDelete, "/comments/"+commentID, nil, other.User_id)
+	expectStatus(t, w, http.StatusOK)
+	w = s.request(t, http.MethodDelete, "/likes/"+likeID, nil, other.User_id)
+	expectStatus(t, w, http.StatusOK)
+	if len(s.store.Comments) != 0 || len(s.store.Likes) != 0 {
+		t.Errorf("left %d comments and %d likes", len(s.store.Comments), len(s.store.Likes))
+	}
+}
+
+func TestBlockedUserCannotComment(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	other := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+	postID := s.createPost(t, owner.User_id)
+	s.store.Blocks = append(s.store.Blocks, models.Block{ID: primitive.NewObjectID(), Blocker_ID: owner.ID, Blocked_ID: other.ID})
+
+	w := s.request(t, http.MethodPost, "/comments", map[string]string{"post_id": postID, "description": "Splendid"}, other.User_id)
+	expectStatus(t, w, http.StatusForbidden)
+	w = s.request(t, http.MethodPost, "/likes", map[string]string{"post_id": postID}, other.User_id)
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestBannedUsersContentIsHidden(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	other := s.addUser(t, "charles@example.com", "Difference-Engine-1822")
+	postID := s.createPost(t, owner.User_id)
+
+	comment := map[string]string{"post_id": postID, "description": "Splendid"}
+	w := s.request(t, http.MethodPost, "/comments", comment, other.User_id)
+	expectStatus(t, w, http.StatusOK)
+	w = s.request(t, http.MethodPost, "/likes", map[string]string{"post_id": postID}, other.User_id)
+	expectStatus(t, w, http.StatusOK)
+
+	s.store.Users[1].Banned = true
+	w = s.request(t, http.MethodGet, "/posts/"+postID, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var post struct {
+		Total_comments int
+		Total_likes    int
+	}
+	decode(t, w, &post)
+	if post.Total_comments != 0 || post.Total_likes != 0 {
+		t.Errorf("got %d comments and %d likes from a banned user, want none", post.Total_comments, post.Total_likes)
+	}
+
+	s.store.Users[0].Banned = true
+	w = s.request(t, http.MethodGet, "/posts/"+postID, nil, "")
+	expectStatus(t, w, http.StatusNotFound)
+}
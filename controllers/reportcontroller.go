@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportHandler serves the report and moderation endpoints from the
+// repositories it is given.
+type ReportHandler struct {
+	Users         repository.UserRepository
+	Posts         repository.PostRepository
+	Comments      repository.CommentRepository
+	Reports       repository.ReportRepository
+	Sanctions     repository.SanctionRepository
+	Notifications repository.NotificationRepository
+}
+
+// NewReportHandler returns a ReportHandler using repos.
+func NewReportHandler(repos repository.Repositories) *ReportHandler {
+	return &ReportHandler{
+		Users:         repos.Users,
+		Posts:         repos.Posts,
+		Comments:      repos.Comments,
+		Reports:       repos.Reports,
+		Sanctions:     repos.Sanctions,
+		Notifications: repos.Notifications,
+	}
+}
+
+// reportTargetOwner returns the user responsible for a reported post, comment or user.
+func (h *ReportHandler) reportTargetOwner(ctx context.Context, targetType string, targetID primitive.ObjectID) (primitive.ObjectID, error) {
+	switch targetType {
+	case "post":
+		post, err := h.Posts.FindByID(ctx, targetID)
+		return post.User_id, err
+	case "comment":
+		comment, err := h.Comments.FindByID(ctx, targetID)
+		return comment.User_ID, err
+	default:
+		user, err := h.Users.FindByID(ctx, targetID.Hex())
+		return user.ID, err
+	}
+}
+
+// CreateReport reports a post, comment or user for moderation
+// @Summary Report content
+// @Description Report a post, comment or user to the moderators. A user can only have one open report per target.
+// @Tags Report
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param report body models.ReportInput true "Report data"
+// @Success 200 {object} models.CreateOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Target not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /reports [post]
+func (h *ReportHandler) CreateReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var input models.ReportInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		if _, err := h.reportTargetOwner(ctx, input.Target_type, input.Target_ID); err != nil {
+			helper.RespondError(c, http.StatusNotFound, "Reported " + input.Target_type + " not found")
+			return
+		}
+
+		already, err := h.Reports.HasOpen(ctx, UID, input.Target_type, input.Target_ID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error checking reports")
+			return
+		}
+		if already {
+			helper.RespondError(c, http.StatusBadRequest, "You already reported this " + input.Target_type)
+			return
+		}
+
+		var report models.Report
+		report.ID = primitive.NewObjectID()
+		report.Reporter_ID = UID
+		report.Target_type = input.Target_type
+		report.Target_ID = input.Target_ID
+		report.Reason = input.Reason
+		report.Details = input.Details
+		report.Status = models.ReportStatusOpen
+		report.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		report.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := h.Reports.Create(ctx, report); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "report item was not created")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"InsertedID": report.ID})
+	}
+}
+
+// GetReportQueue lists open reports grouped per target
+// @Summary Get the moderation queue
+// @Description This endpoint lists open reports grouped by reported post, comment or user, most reported first. Moderators only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param target_type query string false "Only reports about post, comment or user"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of targets per page" default(10)
+// @Success 200 {object} models.ReportQueue
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /moderation/reports [get]
+func (h *ReportHandler) GetReportQueue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		groups, err := h.Reports.Queue(ctx, c.Query("target_type"), int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching reports")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  groups,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// DecideReports resolves every open report about a target
+// @Summary Decide on reported content
+// @Description Dismiss the reports, hide the reported post or comment, or suspend the responsible user. The decision is recorded with the acting moderator and every reporter is notified. Moderators only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param decision body models.ModerationDecisionInput true "Moderation decision"
+// @Success 200 {object} models.ModerationAction
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 404 {object} models.Error "No open reports for this target"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /moderation/reports/decision [post]
+func (h *ReportHandler) DecideReports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var input models.ModerationDecisionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if input.Action == models.ModerationHide && input.Target_type == "user" {
+			helper.RespondError(c, http.StatusBadRequest, "Only posts and comments can be hidden")
+			return
+		}
+		if input.Suspend_days == 0 {
+			input.Suspend_days = 7
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		reports, err := h.Reports.ListOpen(ctx, input.Target_type, input.Target_ID)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching reports")
+			return
+		}
+		if len(reports) == 0 {
+			helper.RespondError(c, http.StatusNotFound, "No open reports for this target")
+			return
+		}
+
+		now := time.Now()
+		switch input.Action {
+		case models.ModerationHide:
+			hide := h.Posts.Hide
+			if input.Target_type == "comment" {
+				hide = h.Comments.Hide
+			}
+			if err := hide(ctx, input.Target_ID, now); err != nil {
+				helper.RespondError(c, http.StatusInternalServerError, "Failed to hide " + input.Target_type)
+				return
+			}
+		case models.ModerationSuspend:
+			owner, err := h.reportTargetOwner(ctx, input.Target_type, input.Target_ID)
+			if err != nil {
+				helper.RespondError(c, http.StatusNotFound, "Reported " + input.Target_type + " not found")
+				return
+			}
+			until := now.Add(time.Duration(input.Suspend_days) * 24 * time.Hour)
+			reason := "Suspended after reports about " + input.Target_type + " " + input.Target_ID.Hex()
+			if input.Note != nil {
+				reason = *input.Note
+			}
+			if _, err := applySanction(ctx, h.Users, h.Sanctions, owner, models.SanctionSuspend, reason, &until, UID); err != nil {
+				helper.RespondError(c, http.StatusInternalServerError, "Failed to suspend user")
+				return
+			}
+		}
+
+		var action models.ModerationAction
+		action.ID = primitive.NewObjectID()
+		action.Moderator_ID = UID
+		action.Target_type = input.Target_type
+		action.Target_ID = input.Target_ID
+		action.Action = input.Action
+		action.Note = input.Note
+		action.Created_at, _ = time.Parse(time.RFC3339, now.Format(time.RFC3339))
+		for _, report := range reports {
+			action.Report_IDs = append(action.Report_IDs, report.ID)
+		}
+		if err := h.Reports.RecordAction(ctx, action); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "moderation action was not recorded")
+			return
+		}
+
+		status := models.ReportStatusActioned
+		if input.Action == models.ModerationDismiss {
+			status = models.ReportStatusDismissed
+		}
+		if err := h.Reports.Decide(ctx, action.Report_IDs, status, input.Action, UID, action.Created_at); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Failed to update reports")
+			return
+		}
+
+		message := fmt.Sprintf("Thanks for your report. After review we found the reported %s does not break our rules.", input.Target_type)
+		if status == models.ReportStatusActioned {
+			message = fmt.Sprintf("Thanks for your report. We reviewed the reported %s and took action.", input.Target_type)
+		}
+		// the decision is already recorded, so a reporter who misses the
+		// notification is no reason to fail the request
+		for _, report := range reports {
+			if err := notify(ctx, h.Notifications, report.Reporter_ID, "report_decision", message, report.ID); err != nil {
+				slog.ErrorContext(ctx, "notifying a reporter of the decision failed", "report_id", report.ID.Hex(), "error", err)
+			}
+		}
+
+		c.JSON(http.StatusOK, action)
+	}
+}
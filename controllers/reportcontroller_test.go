@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingNotifications refuses to store notifications.
+type failingNotifications struct {
+	repository.NotificationRepository
+}
+
+func (failingNotifications) Create(ctx context.Context, notification models.Notification) error {
+	return errors.New("notifications are down")
+}
+
+func TestDecideReportsSurvivesFailedNotifications(t *testing.T) {
+	s := newTestServer(t)
+	reporter := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	moderator := s.addUser(t, "mod@example.com", "Difference-Engine-1822")
+	target := s.addUser(t, "spam@example.com", "Somerville-1780")
+	now := time.Now()
+	s.store.Reports = append(s.store.Reports, models.Report{
+		ID:          primitive.NewObjectID(),
+		Reporter_ID: reporter.ID,
+		Target_type: "user",
+		Target_ID:   target.ID,
+		Reason:      "spam",
+		Status:      models.ReportStatusOpen,
+		Created_at:  now,
+		Updated_at:  now,
+	})
+
+	repos := s.store.Repositories()
+	repos.Notifications = failingNotifications{repos.Notifications}
+	reports := NewReportHandler(repos)
+	s.router.POST("/moderation/reports/decision", func(c *gin.Context) {
+		c.Set("uid", moderator.User_id)
+	}, reports.DecideReports())
+
+	decision := map[string]string{"target_type": "user", "target_id": target.User_id, "action": models.ModerationDismiss}
+	w := s.request(t, http.MethodPost, "/moderation/reports/decision", decision, "")
+	expectStatus(t, w, http.StatusOK)
+	if s.store.Reports[0].Status != models.ReportStatusDismissed {
+		t.Errorf("got report status %q, want %q", s.store.Reports[0].Status, models.ReportStatusDismissed)
+	}
+
+	// the reports are decided, so a retry finds nothing left to do
+	w = s.request(t, http.MethodPost, "/moderation/reports/decision", decision, "")
+	expectStatus(t, w, http.StatusNotFound)
+}
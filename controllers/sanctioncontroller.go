@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applySanction suspends a user until the given time, or bans them when
+// until is nil, and records the sanction with the user who issued it.
+func applySanction(ctx context.Context, users repository.UserRepository, sanctions repository.SanctionRepository, userID primitive.ObjectID, kind string, reason string, until *time.Time, issuedBy primitive.ObjectID) (models.Sanction, error) {
+	var sanction models.Sanction
+	sanction.ID = primitive.NewObjectID()
+	sanction.User_ID = userID
+	sanction.Type = kind
+	sanction.Reason = reason
+	sanction.Until = until
+	sanction.Issued_by = issuedBy
+	sanction.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	sanction.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	set := bson.M{"updated_at": sanction.Created_at}
+	if kind == models.SanctionBan {
+		set["banned"] = true
+	} else {
+		set["suspended_until"] = until
+	}
+	if err := users.Update(ctx, userID.Hex(), nil, repository.UserChange{Set: set}); err != nil {
+		return sanction, err
+	}
+
+	return sanction, sanctions.Create(ctx, sanction)
+}
+
+// CreateSanction suspends or bans a user
+// @Summary Suspend or ban a user
+// @Description Suspend a user for a number of hours or ban them permanently. Sanctioned users cannot log in and their existing tokens stop working; a banned user's content is hidden. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "User ID"
+// @Param sanction body models.SanctionInput true "Sanction"
+// @Success 200 {object} models.Sanction
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/users/{id}/sanctions [post]
+func (h *AdminHandler) CreateSanction() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+		if userID == UID {
+			helper.RespondError(c, http.StatusBadRequest, "You cannot sanction yourself")
+			return
+		}
+
+		var input models.SanctionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var until *time.Time
+		if input.Type == models.SanctionSuspend {
+			if input.Duration_hours == 0 {
+				helper.RespondError(c, http.StatusBadRequest, "duration_hours is required for a suspension")
+				return
+			}
+			end := time.Now().Add(time.Duration(input.Duration_hours) * time.Hour)
+			until = &end
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		sanction, err := applySanction(ctx, h.Users, h.Sanctions, userID, input.Type, input.Reason, until, UID)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Sanction could not be applied")
+			return
+		}
+
+		c.JSON(http.StatusOK, sanction)
+	}
+}
+
+// LiftSanctions lifts every active suspension and ban of a user
+// @Summary Lift a user's sanctions
+// @Description Lift the suspension and ban of a user. The lifted sanctions keep a record of who lifted them and why. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "User ID"
+// @Param lift body models.LiftSanctionInput true "Reason for lifting"
+// @Success 200 {string} Sanctions lifted successfully
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/users/{id}/sanctions [delete]
+func (h *AdminHandler) LiftSanctions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var input models.LiftSanctionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		now := time.Now()
+		change := repository.UserChange{
+			Set:   bson.M{"banned": false, "updated_at": now},
+			Unset: []string{"suspended_until"},
+		}
+		err = h.Users.Update(ctx, userID.Hex(), nil, change)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Sanctions could not be lifted")
+			return
+		}
+
+		if err := h.Sanctions.LiftAll(ctx, userID, UID, input.Reason, now); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Sanctions could not be lifted")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Sanctions lifted successfully"})
+	}
+}
+
+// GetSanctionList lists the sanction history of a user
+// @Summary Get a user's sanctions
+// @Description This endpoint retrieves the paginated sanction history of a user, newest first. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of sanctions per page" default(10)
+// @Success 200 {object} models.SanctionList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/users/{id}/sanctions [get]
+func (h *AdminHandler) GetSanctionList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		sanctions, err := h.Sanctions.ListByUser(ctx, userID, int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching sanctions")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  sanctions,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
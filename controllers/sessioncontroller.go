@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// startSession records a new logged in device for the user and returns its
+// tokens. Only a hash of the refresh token is stored, and the session lives
+// as long as the refresh token.
+func (h *AccountHandler) startSession(ctx context.Context, user models.User, userAgent string, ip string) (token string, refreshToken string, err error) {
+	var session models.Session
+	session.ID = primitive.NewObjectID()
+	session.User_ID = user.ID
+	session.User_agent = userAgent
+	session.Ip_address = ip
+	session.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	session.Updated_at = session.Created_at
+	session.Last_seen_at = session.Created_at
+	session.Expires_at = session.Created_at.Add(config.App.RefreshTokenTTL)
+
+	token, refreshToken, err = helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, user.Role, session.ID.Hex())
+	if err != nil {
+		return
+	}
+	session.Refresh_token_hash = helper.HashRefreshToken(refreshToken)
+
+	err = h.Sessions.Create(ctx, session)
+	return
+}
+
+// refreshSession swaps a refresh token for a new token pair in the same
+// session. Each refresh token works once; presenting one that was already
+// swapped means it leaked, so the whole session is revoked.
+func (h *AccountHandler) refreshSession(ctx context.Context, claims *helper.SignedDetails, refreshToken string, user models.User, userAgent string, ip string) (string, string, error) {
+	sessionID, err := primitive.ObjectIDFromHex(claims.Sid)
+	if err != nil {
+		return "", "", errors.New("the refresh token is invalid")
+	}
+
+	session, err := h.Sessions.FindActive(ctx, sessionID, user.ID, time.Now())
+	if err != nil {
+		return "", "", errors.New("the session has been signed out")
+	}
+	if session.Refresh_token_hash != helper.HashRefreshToken(refreshToken) {
+		if err := h.Sessions.Revoke(ctx, sessionID, user.ID, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "revoking session after refresh token reuse failed", "session_id", claims.Sid, "error", err)
+		}
+		return "", "", errors.New("the refresh token was already used")
+	}
+
+	token, newRefreshToken, err := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, user.Role, claims.Sid)
+	if err != nil {
+		return "", "", err
+	}
+
+	// matching the old hash makes two concurrent refreshes with the same token fail
+	now := time.Now()
+	err = h.Sessions.Rotate(ctx, sessionID, session.Refresh_token_hash, helper.HashRefreshToken(newRefreshToken), userAgent, ip, now, now.Add(config.App.RefreshTokenTTL))
+	if err == repository.ErrNotFound {
+		return "", "", errors.New("the refresh token was already used")
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return token, newRefreshToken, nil
+}
+
+// GetSessionList lists the devices the user is logged in on
+// @Summary Get active sessions
+// @Description This endpoint retrieves the paginated active sessions of the logged in user, most recently seen first, with the device's user agent, IP address and last activity. The session making the request is marked as current.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of sessions per page" default(10)
+// @Success 200 {object} models.SessionList
+// @Failure 400 {object} models.Error "Invalid pagination parameters"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/sessions [get]
+func (h *AccountHandler) GetSessionList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		page, limit, ok := pagination(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		sessions, err := h.Sessions.ListActive(ctx, UID, time.Now(), int64((page-1)*limit), int64(limit))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Error fetching sessions")
+			return
+		}
+
+		current := c.GetString("session_id")
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID.Hex() == current
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  sessions,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// RevokeSession signs one device out
+// @Summary Revoke a session
+// @Description Sign out one of the logged in user's sessions. Its access and refresh tokens stop working immediately.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "Session ID"
+// @Success 200 {string} Session revoked successfully
+// @Failure 400 {object} models.Error "Invalid session ID"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 404 {object} models.Error "Session not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/sessions/{id} [delete]
+func (h *AccountHandler) RevokeSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		UID, err := primitive.ObjectIDFromHex(uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Invalid UID")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		err = h.Sessions.Revoke(ctx, id, UID, time.Now())
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Session could not be revoked")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+	}
+}
+
+// RefreshToken swaps a refresh token for new tokens
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token in the same session. Each refresh token works once; reusing one signs the session out.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshTokenInput true "Refresh token"
+// @Success 200 {object} models.TokenOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Invalid, expired or reused refresh token"
+// @Failure 403 {object} models.Error "Account suspended or banned"
+// @Router /users/token/refresh [post]
+func (h *AccountHandler) RefreshToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.RefreshTokenInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		claims, msg := helper.ValidateToken(*input.Refresh_token)
+		if msg != "" || claims.Type != helper.TokenRefresh {
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, "the refresh token is invalid")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, claims.Uid)
+		if err != nil || helper.TokenRevoked(claims, user) {
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, "the refresh token is no longer valid")
+			return
+		}
+		if restriction := helper.AccountRestriction(user); restriction != "" {
+			helper.RespondErrorCode(c, http.StatusForbidden, helper.CodeAccountRestricted, restriction)
+			return
+		}
+
+		token, refreshToken, err := h.refreshSession(ctx, claims, *input.Refresh_token, user, c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+	}
+}
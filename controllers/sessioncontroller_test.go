@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+)
+
+type tokenPair struct {
+	Token         string `json:"token"`
+	Refresh_token string `json:"refresh_token"`
+}
+
+func (s *testServer) login(t *testing.T, email string, password string) tokenPair {
+	t.Helper()
+	w := s.request(t, http.MethodPost, "/users/login", map[string]string{"email": email, "Password": password}, "")
+	expectStatus(t, w, http.StatusOK)
+	var tokens tokenPair
+	decode(t, w, &tokens)
+	return tokens
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	first := s.login(t, "ada@example.com", "Analytical-Engine-1843")
+
+	w := s.request(t, http.MethodPost, "/users/token/refresh", map[string]string{"refresh_token": first.Refresh_token}, "")
+	expectStatus(t, w, http.StatusOK)
+	var second tokenPair
+	decode(t, w, &second)
+	if second.Refresh_token == "" || second.Refresh_token == first.Refresh_token {
+		t.Fatal("refreshing did not rotate the refresh token")
+	}
+	if len(s.store.Sessions) != 1 {
+		t.Errorf("got %d sessions, want the one rotated", len(s.store.Sessions))
+	}
+
+	// a refresh token that was already used has been stolen or replayed
+	w = s.request(t, http.MethodPost, "/users/token/refresh", map[string]string{"refresh_token": first.Refresh_token}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+	if s.store.Sessions[0].Revoked_at == nil {
+		t.Error("reusing a refresh token did not revoke the session")
+	}
+	w = s.request(t, http.MethodPost, "/users/token/refresh", map[string]string{"refresh_token": second.Refresh_token}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestRefreshTokenRejectsAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	tokens := s.login(t, "ada@example.com", "Analytical-Engine-1843")
+
+	w := s.request(t, http.MethodPost, "/users/token/refresh", map[string]string{"refresh_token": tokens.Token}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestChangePasswordSignsOutOtherSessions(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	tokens := s.login(t, "ada@example.com", "Analytical-Engine-1843")
+
+	change := map[string]string{"current_password": "wrong", "new_password": "Difference-Engine-1822"}
+	w := s.send(t, http.MethodPut, "/users/me/password", change, tokens.Token)
+	expectStatus(t, w, http.StatusForbidden)
+
+	change["current_password"] = "Analytical-Engine-1843"
+	w = s.send(t, http.MethodPut, "/users/me/password", change, tokens.Token)
+	expectStatus(t, w, http.StatusOK)
+
+	if len(s.store.Sessions) != 2 {
+		t.Fatalf("got %d sessions, want the old and the new one", len(s.store.Sessions))
+	}
+	if s.store.Sessions[0].Revoked_at == nil {
+		t.Error("the old session was not revoked")
+	}
+	if s.store.Sessions[1].Revoked_at != nil {
+		t.Error("the new session was revoked")
+	}
+	w = s.send(t, http.MethodGet, "/users/me/sessions", nil, tokens.Token)
+	expectStatus(t, w, http.StatusUnauthorized)
+	s.login(t, "ada@example.com", "Difference-Engine-1822")
+}
+
+func TestChangePasswordFailuresLockTheAccount(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	tokens := s.login(t, "ada@example.com", "Analytical-Engine-1843")
+
+	change := map[string]string{"current_password": "wrong", "new_password": "Difference-Engine-1822"}
+	for i := 0; i < 5; i++ {
+		w := s.send(t, http.MethodPut, "/users/me/password", change, tokens.Token)
+		expectStatus(t, w, http.StatusForbidden)
+	}
+	if len(s.store.Lockouts) == 0 {
+		t.Error("no lockout was recorded")
+	}
+
+	change["current_password"] = "Analytical-Engine-1843"
+	w := s.send(t, http.MethodPut, "/users/me/password", change, tokens.Token)
+	expectStatus(t, w, http.StatusTooManyRequests)
+	w = s.request(t, http.MethodPost, "/users/login", map[string]string{"email": "ada@example.com", "Password": "Analytical-Engine-1843"}, "")
+	expectStatus(t, w, http.StatusTooManyRequests)
+}
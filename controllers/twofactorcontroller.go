@@ -0,0 +1,438 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	"social-media-api/metrics"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+
+// verifySecondFactor checks a TOTP code or a recovery code for the user.
+// A TOTP code is accepted once per time step and a recovery code is removed
+// when used, so neither can be replayed.
+func (h *AccountHandler) verifySecondFactor(ctx context.Context, user models.User, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		if user.Totp_secret == nil {
+			return false, nil
+		}
+		step, ok := helper.ValidateTOTP(*user.Totp_secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return h.Users.UseTOTPStep(ctx, user.User_id, step)
+	}
+
+	if recoveryCode != "" {
+		return h.Users.UseRecoveryCode(ctx, user.User_id, helper.HashRecoveryCode(recoveryCode))
+	}
+
+	return false, nil
+}
+
+// LoginTwoFactor completes a login for an account with two-factor authentication
+// @Summary Complete a two-factor login
+// @Description Exchange the MFA challenge token returned by login and a code from the authenticator app, or an unused recovery code, for access and refresh tokens.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLoginInput true "Challenge token and code"
+// @Success 200 {object} models.User "User information with tokens"
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Invalid or expired challenge, or wrong code"
+// @Failure 403 {object} models.Error "Account suspended or banned"
+// @Failure 429 {object} models.Error "Too many failed attempts"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/login/2fa [post]
+func (h *AccountHandler) LoginTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.TwoFactorLoginInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if input.Code == "" && input.Recovery_code == "" {
+			helper.RespondError(c, http.StatusBadRequest, "code or recovery_code is required")
+			return
+		}
+
+		claims, msg := helper.ValidateActionToken(*input.Mfa_token, helper.PurposeMFALogin)
+		if msg != "" {
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, "Invalid or expired login challenge")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		ip := c.ClientIP()
+		if h.rejectIfLocked(c, ctx, accountThrottleKey(claims.Email), ipThrottleKey(ip)) {
+			return
+		}
+
+		user, err := h.Users.FindByID(ctx, claims.Uid)
+		if err != nil || !user.Totp_enabled {
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, "Invalid or expired login challenge")
+			return
+		}
+		if restriction := helper.AccountRestriction(user); restriction != "" {
+			helper.RespondErrorCode(c, http.StatusForbidden, helper.CodeAccountRestricted, restriction)
+			return
+		}
+
+		valid, err := h.verifySecondFactor(ctx, user, input.Code, input.Recovery_code)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Code could not be checked")
+			return
+		}
+		if !valid {
+			h.recordLoginFailure(ctx, claims.Email, ip)
+			helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidCredentials, "Invalid authentication code")
+			return
+		}
+
+		h.clearLoginFailures(ctx, claims.Email)
+		token, refreshToken, err := h.startSession(ctx, user, c.Request.UserAgent(), ip)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Session could not be started")
+			return
+		}
+		metrics.Logins.Inc()
+		user.Token = &token
+		user.Refresh_token = &refreshToken
+
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// SetupTwoFactor starts two-factor enrollment
+// @Summary Start two-factor setup
+// @Description Generate a new TOTP secret for the logged in user. Show the provisioning URI as a QR code in an authenticator app, then confirm with a code to enable two-factor authentication.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Success 200 {object} models.TwoFactorSetupOutput
+// @Failure 400 {object} models.Error "Two-factor authentication already enabled"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/2fa/setup [post]
+func (h *AccountHandler) SetupTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if user.Totp_enabled {
+			helper.RespondError(c, http.StatusBadRequest, "Two-factor authentication is already enabled")
+			return
+		}
+
+		secret := helper.NewTOTPSecret()
+		change := repository.UserChange{Set: bson.M{"totp_secret": secret, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Two-factor setup could not be started")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.TwoFactorSetupOutput{
+			Secret:           secret,
+			Provisioning_uri: helper.TOTPProvisioningURI(*user.Email, secret),
+		})
+	}
+}
+
+// EnableTwoFactor confirms two-factor enrollment
+// @Summary Enable two-factor authentication
+// @Description Confirm the secret from the setup step with a code from the authenticator app. The response holds one-time recovery codes; they are only shown once.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param code body models.TwoFactorCodeInput true "Authenticator code"
+// @Success 200 {object} models.RecoveryCodesOutput
+// @Failure 400 {object} models.Error "Invalid request body or code"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/2fa/enable [post]
+func (h *AccountHandler) EnableTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.TwoFactorCodeInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if user.Totp_enabled {
+			helper.RespondError(c, http.StatusBadRequest, "Two-factor authentication is already enabled")
+			return
+		}
+		if user.Totp_secret == nil {
+			helper.RespondError(c, http.StatusBadRequest, "Start two-factor setup first")
+			return
+		}
+
+		step, ok := helper.ValidateTOTP(*user.Totp_secret, *input.Code, time.Now())
+		if !ok {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid authentication code")
+			return
+		}
+
+		codes, hashes := helper.NewRecoveryCodes()
+		change := repository.UserChange{Set: bson.M{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+			"recovery_codes": hashes,
+			"updated_at":     time.Now(),
+		}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Two-factor authentication could not be enabled")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.RecoveryCodesOutput{Recovery_codes: codes})
+	}
+}
+
+// DisableTwoFactor turns two-factor authentication off
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication for the logged in user. The password and a current code or recovery code are required, and failed attempts count towards the login lockout. Not allowed when an admin requires two-factor authentication for the account.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param disable body models.TwoFactorDisableInput true "Password and code"
+// @Success 200 {string} Two-factor authentication disabled
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Wrong password or code, or two-factor authentication is required"
+// @Failure 429 {object} models.Error "Too many failed attempts"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/2fa [delete]
+func (h *AccountHandler) DisableTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.TwoFactorDisableInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+		if input.Code == "" && input.Recovery_code == "" {
+			helper.RespondError(c, http.StatusBadRequest, "code or recovery_code is required")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !user.Totp_enabled {
+			helper.RespondError(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+			return
+		}
+		if user.Totp_required {
+			helper.RespondError(c, http.StatusForbidden, "Two-factor authentication is required for this account")
+			return
+		}
+		if !h.checkCurrentPassword(c, ctx, user, *input.Password, "Password is incorrect") {
+			return
+		}
+
+		valid, err := h.verifySecondFactor(ctx, user, input.Code, input.Recovery_code)
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Code could not be checked")
+			return
+		}
+		if !valid {
+			h.recordLoginFailure(ctx, *user.Email, c.ClientIP())
+			helper.RespondError(c, http.StatusForbidden, "Invalid authentication code")
+			return
+		}
+		h.clearLoginFailures(ctx, *user.Email)
+
+		change := repository.UserChange{
+			Set:   bson.M{"totp_enabled": false, "updated_at": time.Now()},
+			Unset: []string{"totp_secret", "recovery_codes"},
+		}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Two-factor authentication could not be disabled")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the logged in user
+// @Summary Regenerate recovery codes
+// @Description Replace every recovery code with a new set. Earlier codes stop working. A current authenticator code is required.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param code body models.TwoFactorCodeInput true "Authenticator code"
+// @Success 200 {object} models.RecoveryCodesOutput
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Invalid authentication code"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/me/2fa/recovery-codes [post]
+func (h *AccountHandler) RegenerateRecoveryCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var input models.TwoFactorCodeInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !user.Totp_enabled {
+			helper.RespondError(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+			return
+		}
+
+		valid, err := h.verifySecondFactor(ctx, user, *input.Code, "")
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Code could not be checked")
+			return
+		}
+		if !valid {
+			helper.RespondError(c, http.StatusForbidden, "Invalid authentication code")
+			return
+		}
+
+		codes, hashes := helper.NewRecoveryCodes()
+		change := repository.UserChange{Set: bson.M{"recovery_codes": hashes, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Recovery codes could not be replaced")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.RecoveryCodesOutput{Recovery_codes: codes})
+	}
+}
+
+// SetTwoFactorRequired requires or stops requiring two-factor authentication for a staff account
+// @Summary Require two-factor authentication
+// @Description Require a moderator or admin to use two-factor authentication. Until they enable it, their moderation and admin routes are refused, and they cannot turn it off while it is required. Admins only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Param id path string true "User ID"
+// @Param required body models.TwoFactorRequiredInput true "Whether two-factor authentication is required"
+// @Success 200 {string} Two-factor requirement updated
+// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 403 {object} models.Error "Forbidden"
+// @Failure 404 {object} models.Error "User not found"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /admin/users/{id}/2fa-required [put]
+func (h *AdminHandler) SetTwoFactorRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		var input models.TwoFactorRequiredInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, userID)
+		if err != nil {
+			helper.RespondError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		if input.Required && user.Role != models.RoleModerator && user.Role != models.RoleAdmin {
+			helper.RespondError(c, http.StatusBadRequest, "Two-factor authentication can only be required for moderators and admins")
+			return
+		}
+
+		change := repository.UserChange{Set: bson.M{"totp_required": input.Required, "updated_at": time.Now()}}
+		if err := h.Users.Update(ctx, userID, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Two-factor requirement could not be updated")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor requirement updated"})
+	}
+}
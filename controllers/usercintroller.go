@@ -2,38 +2,102 @@ package controller
 
 import (
     "context"
+    "errors"
     "fmt"
-    "log"
+    "log/slog"
 
     "net/http"
+    "sync"
     "time"
 
     "github.com/gin-gonic/gin"
-    "github.com/go-playground/validator/v10"
 
-    "social-media-api/database"
+    "social-media-api/config"
+    "social-media-api/metrics"
 
     helper "social-media-api/helpers"
     "social-media-api/models"
+    "social-media-api/repository"
 
-    "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
-    "go.mongodb.org/mongo-driver/mongo"
     "golang.org/x/crypto/bcrypt"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
-var validate = validator.New()
+var validate = helper.NewValidator()
+
+// AccountHandler serves sign-up, login, sessions and the endpoints that
+// change an account from the repositories it is given.
+type AccountHandler struct {
+    Users      repository.UserRepository
+    Sessions   repository.SessionRepository
+    Throttles  repository.ThrottleRepository
+    OIDCStates repository.OIDCStateRepository
+    Deletions  repository.DeletionRepository
+}
+
+// NewAccountHandler returns an AccountHandler using repos.
+func NewAccountHandler(repos repository.Repositories) *AccountHandler {
+    return &AccountHandler{
+        Users:      repos.Users,
+        Sessions:   repos.Sessions,
+        Throttles:  repos.Throttles,
+        OIDCStates: repos.OIDCStates,
+        Deletions:  repos.Deletions,
+    }
+}
+
+var dummyHashOnce sync.Once
+var dummyHash string
+
+// dummyPasswordHash is compared against when the login email is unknown.
+func dummyPasswordHash() string {
+    dummyHashOnce.Do(func() {
+        dummyHash = HashPassword(helper.NewNonce())
+    })
+    return dummyHash
+}
 
 func HashPassword(password string) string {
-    bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+    bytes, err := bcrypt.GenerateFromPassword([]byte(password), config.App.BcryptCost)
     if err != nil {
-        log.Panic(err)
+        panic(err)
     }
 
     return string(bytes)
 }
 
+// rejectWeakPassword answers 400 with the broken password rules listed under
+// the request field when the password does not meet the policy.
+func rejectWeakPassword(c *gin.Context, field string, password string, personal ...string) bool {
+    problems := helper.Passwords().Check(password, personal...)
+    if len(problems) == 0 {
+        return false
+    }
+    fields := make([]models.FieldError, 0, len(problems))
+    for _, problem := range problems {
+        fields = append(fields, models.FieldError{Field: field, Code: "password_policy", Message: problem})
+    }
+    helper.RespondFieldErrors(c, "the password does not meet the requirements", fields...)
+    return true
+}
+
+// duplicateField names the user field a *repository.DuplicateError is
+// about, or returns an empty string for any other error.
+func duplicateField(err error) string {
+    var duplicate *repository.DuplicateError
+    if errors.As(err, &duplicate) {
+        return duplicate.Field
+    }
+    return ""
+}
+
+// respondConflict answers 409 naming the field that is already taken.
+func respondConflict(c *gin.Context, field string) {
+    problem := helper.NewProblem(c, http.StatusConflict, helper.CodeConflict, fmt.Sprintf("an account with this %s already exists", field))
+    problem.Errors = []models.FieldError{{Field: field, Code: "unique", Message: "is already in use"}}
+    helper.WriteProblem(c, problem)
+}
+
 func VerifyPassword(userPassword string, providedPassword string) (bool, string) {
     err := bcrypt.CompareHashAndPassword([]byte(providedPassword), []byte(userPassword))
     check := true
@@ -49,116 +113,218 @@ func VerifyPassword(userPassword string, providedPassword string) (bool, string)
 
 // RegisterUser
 // @Summary Register a User
-// @Description Register a new User.
+// @Description Register a new User. The email is stored in lowercase and the phone number in E.164 format; both must be unique. The password must meet the password policy and must not be a known breached password. The account starts with an unverified email address and a verification link is emailed to it.
 // @Tags Auth Registration and Login
 // @Accept json
 // @Produce json
 // @Security APIKeyAuth
 // @Param user body models.UserRegisterInput true "User register"
 // @Success 200 {object} models.User
-// @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 400 {object} models.Error "Invalid request body, phone number or weak password"
+// @Failure 409 {object} models.Error "Email or phone number already in use"
+// @Failure 429 {object} models.Error "Too many sign-ups from this address"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /users/signup [post]
-func SignUp() gin.HandlerFunc {
+func (h *AccountHandler) SignUp() gin.HandlerFunc {
     return func(c *gin.Context) {
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+        defer cancel()
         var user models.User
-        if err := c.BindJSON(&user); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        if err := c.ShouldBindJSON(&user); err != nil {
+            helper.RespondInvalidInput(c, err)
             return
         }
         validationErr := validate.Struct(user)
         if validationErr != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+            helper.RespondInvalidInput(c, validationErr)
             return
         }
-
-        count, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
-        defer cancel()
+        email := helper.NormalizeEmail(*user.Email)
+        user.Email = &email
+        phone, err := helper.NormalizePhone(*user.Phone)
         if err != nil {
-            log.Panic(err)
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
+            helper.RespondFieldErrors(c, err.Error(), models.FieldError{Field: "phone", Code: "invalid", Message: err.Error()})
+            return
+        }
+        user.Phone = &phone
+        if rejectWeakPassword(c, "Password", *user.Password, *user.First_name, *user.Last_name, *user.Email) {
             return
         }
 
-        password := HashPassword(*user.Password)
-        user.Password = &password
+        // every sign-up costs a bcrypt hash, so they are limited per client IP
+        ip := c.ClientIP()
+        if h.rejectIfLocked(c, ctx, signupThrottleKey(ip)) {
+            return
+        }
+        if err := h.recordAttempt(ctx, signupThrottleKey(ip), models.LockoutScopeSignup, ip, ip, config.App.SignupMaxPerIP, config.App.SignupWindow); err != nil {
+            slog.ErrorContext(ctx, "recording sign-up failed", "client_ip", ip, "error", err)
+        }
 
-        count, err = userCollection.CountDocuments(ctx, bson.M{"phone": user.Phone})
-        defer cancel()
-        if err != nil {
-            log.Panic(err)
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the phone number"})
+        // the unique indexes are what really prevents duplicates; checking
+        // first answers the common case before paying for the password hash
+        _, err = h.Users.FindByEmail(ctx, *user.Email)
+        if err == nil {
+            respondConflict(c, "email")
+            return
+        }
+        if err != repository.ErrNotFound {
+            helper.RespondError(c, http.StatusInternalServerError, "error occured while checking for the email")
             return
         }
 
-        if count > 0 {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "this email or phone number already exists"})
+        _, err = h.Users.FindByPhone(ctx, *user.Phone)
+        if err == nil {
+            respondConflict(c, "phone")
+            return
+        }
+        if err != repository.ErrNotFound {
+            helper.RespondError(c, http.StatusInternalServerError, "error occured while checking for the phone number")
             return
         }
 
+        password := HashPassword(*user.Password)
+        user.Password = &password
+
         user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
         user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
         user.ID = primitive.NewObjectID()
         user.User_id = user.ID.Hex()
-        token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
-        user.Token = &token
-        user.Refresh_token = &refreshToken
+        user.Role = models.RoleUser
+        user.Suspended_until = nil
+        user.Banned = false
+        user.Pending_email = nil
+        user.Totp_secret = nil
+        user.Totp_enabled = false
+        user.Totp_required = false
+        user.Recovery_codes = nil
+        user.Identities = nil
+        user.Deletion_scheduled_at = nil
+        user.Deletion_started_at = nil
+        nonce := helper.NewNonce()
+        user.Email_verified = false
+        user.Verification_nonce = &nonce
+        user.Verification_sent_at = &user.Created_at
+        // sign-up does not start a session; the client logs in afterwards
+        user.Token = nil
+        user.Refresh_token = nil
 
-        resultInsertionNumber, insertErr := userCollection.InsertOne(ctx, user)
+        insertErr := h.Users.Create(ctx, user)
+        if field := duplicateField(insertErr); field != "" {
+            respondConflict(c, field)
+            return
+        }
         if insertErr != nil {
             msg := fmt.Sprintf("User item was not created")
-            c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+            helper.RespondError(c, http.StatusInternalServerError, msg)
             return
         }
-        defer cancel()
+        metrics.Signups.WithLabelValues("password").Inc()
+
+        // the account exists either way; a failed email can be resent later
+        if err := sendVerificationEmail(user, nonce); err != nil {
+            slog.ErrorContext(ctx, "sending verification email failed", "email", *user.Email, "error", err)
+        }
 
-        c.JSON(http.StatusOK, resultInsertionNumber)
+        c.JSON(http.StatusOK, gin.H{"InsertedID": user.ID})
 
     }
 }
 // Login
 // @Summary Login a User
-// @Description Authenticate a user and return access and refresh tokens.
+// @Description Authenticate a user and return access and refresh tokens. When the account has two-factor authentication enabled, a short-lived MFA challenge token is returned instead and the login is completed at /users/login/2fa.
 // @Tags Auth Registration and Login
 // @Accept json
 // @Produce json
 // @Param user body models.UserLoginInput true "User login credentials"
 // @Success 200 {object} models.User "User information with tokens"
+// @Success 202 {object} models.MFAChallengeOutput "Two-factor code required"
 // @Failure 400 {object} models.Error "Invalid request body"
+// @Failure 401 {object} models.Error "Login or password is incorrect"
+// @Failure 403 {object} models.Error "Account suspended or banned"
+// @Failure 429 {object} models.Error "Too many failed attempts"
 // @Failure 500 {object} models.Error "Internal server error"
 // @Router /users/login [post]
-func Login() gin.HandlerFunc {
+func (h *AccountHandler) Login() gin.HandlerFunc {
     return func(c *gin.Context) {
-        var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+        var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+        defer cancel()
         var user models.User
-        var foundUser models.User
 
-        if err := c.BindJSON(&user); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        if err := c.ShouldBindJSON(&user); err != nil {
+            helper.RespondInvalidInput(c, err)
             return
         }
+        if user.Email == nil || user.Password == nil {
+            helper.RespondError(c, http.StatusBadRequest, "email and Password are required")
+            return
+        }
+        email := helper.NormalizeEmail(*user.Email)
+        user.Email = &email
 
-        err := userCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&foundUser)
-        defer cancel()
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "login or passowrd is incorrect"})
+        ip := c.ClientIP()
+        if h.rejectIfLocked(c, ctx, accountThrottleKey(*user.Email), ipThrottleKey(ip)) {
             return
         }
 
-        passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
-        defer cancel()
+        // an unknown email costs the same bcrypt work and gets the same error
+        // as a wrong password, so responses do not reveal which emails exist
+        foundUser, err := h.Users.FindByEmail(ctx, *user.Email)
+        passwordIsValid := false
+        if err == nil {
+            passwordIsValid, _ = VerifyPassword(*user.Password, *foundUser.Password)
+        } else {
+            VerifyPassword(*user.Password, dummyPasswordHash())
+        }
         if passwordIsValid != true {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+            h.recordLoginFailure(ctx, *user.Email, ip)
+            helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidCredentials, "login or password is incorrect")
             return
         }
 
-        token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
+        if !foundUser.Totp_enabled {
+            h.clearLoginFailures(ctx, *user.Email)
+        }
+        h.completeLogin(c, foundUser)
 
-        helper.UpdateAllTokens(token, refreshToken, foundUser.User_id)
+    }
+}
 
-        c.JSON(http.StatusOK, foundUser)
+// completeLogin finishes a login once the user has proven who they are. It
+// refuses suspended and banned accounts, answers with an MFA challenge when
+// two-factor authentication is enabled, and otherwise starts a new session.
+func (h *AccountHandler) completeLogin(c *gin.Context, user models.User) {
+    if restriction := helper.AccountRestriction(user); restriction != "" {
+        helper.RespondErrorCode(c, http.StatusForbidden, helper.CodeAccountRestricted, restriction)
+        return
+    }
 
+    if user.Totp_enabled {
+        mfaToken, err := helper.GenerateActionToken(helper.PurposeMFALogin, user.User_id, *user.Email, helper.NewNonce(), config.App.MFAChallengeTTL)
+        if err != nil {
+            helper.RespondError(c, http.StatusInternalServerError, "login challenge could not be created")
+            return
+        }
+        c.JSON(http.StatusAccepted, gin.H{"mfa_required": true, "mfa_token": mfaToken})
+        return
     }
+    h.respondWithSession(c, user)
+}
+
+// respondWithSession starts a new session for a user who has passed every
+// check and answers with the user and its tokens.
+func (h *AccountHandler) respondWithSession(c *gin.Context, user models.User) {
+    var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+    defer cancel()
+
+    token, refreshToken, err := h.startSession(ctx, user, c.Request.UserAgent(), c.ClientIP())
+    if err != nil {
+        helper.RespondError(c, http.StatusInternalServerError, "session could not be started")
+        return
+    }
+    metrics.Logins.Inc()
+    user.Token = &token
+    user.Refresh_token = &refreshToken
+
+    c.JSON(http.StatusOK, user)
 }
 
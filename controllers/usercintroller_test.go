@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"social-media-api/models"
+)
+
+func signUpBody(email string, phone string) map[string]string {
+	return map[string]string{
+		"first_name": "Ada",
+		"last_name":  "Lovelace",
+		"email":      email,
+		"phone":      phone,
+		"Password":   "Analytical-Engine-1843",
+	}
+}
+
+func TestSignUpNormalizesAndSendsVerification(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.request(t, http.MethodPost, "/users/signup", signUpBody("Ada@Example.COM", "+44 20 7946 0958"), "")
+	expectStatus(t, w, http.StatusOK)
+
+	if len(s.store.Users) != 1 {
+		t.Fatalf("got %d users, want 1", len(s.store.Users))
+	}
+	user := s.store.Users[0]
+	if *user.Email != "ada@example.com" || *user.Phone != "+442079460958" {
+		t.Errorf("stored email %q and phone %q, want them normalized", *user.Email, *user.Phone)
+	}
+	if user.Email_verified || user.Role != models.RoleUser {
+		t.Errorf("new user is verified %v with role %q", user.Email_verified, user.Role)
+	}
+	if *user.Password == "Analytical-Engine-1843" {
+		t.Error("password was stored in plain text")
+	}
+	if len(s.store.Sessions) != 0 {
+		t.Error("sign-up started a session")
+	}
+
+	token := s.mail.tokenSentTo(t, "ada@example.com")
+	w = s.request(t, http.MethodPost, "/users/verify-email", map[string]string{"token": token}, "")
+	expectStatus(t, w, http.StatusOK)
+	if !s.store.Users[0].Email_verified {
+		t.Error("email is not verified after following the link")
+	}
+
+	w = s.request(t, http.MethodPost, "/users/verify-email", map[string]string{"token": token}, "")
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestSignUpConflict(t *testing.T) {
+	s := newTestServer(t)
+	s.request(t, http.MethodPost, "/users/signup", signUpBody("ada@example.com", "+442079460958"), "")
+
+	w := s.request(t, http.MethodPost, "/users/signup", signUpBody("ADA@example.com", "+442079460000"), "")
+	expectStatus(t, w, http.StatusConflict)
+
+	w = s.request(t, http.MethodPost, "/users/signup", signUpBody("other@example.com", "+44 20 7946 0958"), "")
+	expectStatus(t, w, http.StatusConflict)
+
+	if len(s.store.Users) != 1 {
+		t.Errorf("got %d users, want 1", len(s.store.Users))
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	user := s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+
+	w := s.request(t, http.MethodPost, "/users/login", map[string]string{"email": "Ada@example.com", "Password": "Analytical-Engine-1843"}, "")
+	expectStatus(t, w, http.StatusOK)
+
+	var body models.User
+	decode(t, w, &body)
+	if body.Token == nil || body.Refresh_token == nil {
+		t.Fatal("login did not return tokens")
+	}
+	if len(s.store.Sessions) != 1 || s.store.Sessions[0].User_ID != user.ID {
+		t.Fatalf("got sessions %+v, want one for the user", s.store.Sessions)
+	}
+}
+
+func TestLoginFailuresLockTheAccount(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "ada@example.com", "Analytical-Engine-1843")
+	wrong := map[string]string{"email": "ada@example.com", "Password": "Difference-Engine-1822"}
+
+	for i := 0; i < 5; i++ {
+		w := s.request(t, http.MethodPost, "/users/login", wrong, "")
+		expectStatus(t, w, http.StatusUnauthorized)
+	}
+	if len(s.store.Lockouts) == 0 {
+		t.Error("no lockout was recorded")
+	}
+
+	right := map[string]string{"email": "ada@example.com", "Password": "Analytical-Engine-1843"}
+	w := s.request(t, http.MethodPost, "/users/login", right, "")
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+	if len(s.store.Sessions) != 0 {
+		t.Error("a locked account got a session")
+	}
+}
+
+func TestLoginUnknownEmail(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.request(t, http.MethodPost, "/users/login", map[string]string{"email": "nobody@example.com", "Password": "Analytical-Engine-1843"}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+
+const verificationTokenTTL = 24 * time.Hour
+
+
+// sendVerificationEmail mails a signed verification link for the given nonce.
+func sendVerificationEmail(user models.User, nonce string) error {
+	token, err := helper.GenerateActionToken(helper.PurposeVerifyEmail, user.User_id, *user.Email, nonce, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := helper.AppURL() + "/verify-email?token=" + token
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below within 24 hours:\n\n%s\n\nIf you did not sign up, you can ignore this email.\n", *user.First_name, link)
+	return helper.Mail.Send(*user.Email, "Confirm your email address", body)
+}
+
+// VerifyEmail confirms an email address with the token from the verification email
+// @Summary Verify email address
+// @Description Confirm the user's email address with the single-use token sent by email.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Param token body models.VerifyEmailInput true "Verification token"
+// @Success 200 {string} Email verified successfully
+// @Failure 400 {object} models.Error "Invalid or expired token"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/verify-email [post]
+func (h *AccountHandler) VerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.VerifyEmailInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			helper.RespondInvalidInput(c, err)
+			return
+		}
+		if validationErr := validate.Struct(input); validationErr != nil {
+			helper.RespondInvalidInput(c, validationErr)
+			return
+		}
+
+		claims, msg := helper.ValidateActionToken(*input.Token, helper.PurposeVerifyEmail)
+		if msg != "" {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired verification link")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		match := bson.M{"email": claims.Email, "verification_nonce": claims.Id}
+		change := repository.UserChange{
+			Set:   bson.M{"email_verified": true, "updated_at": time.Now()},
+			Unset: []string{"verification_nonce"},
+		}
+		err := h.Users.Update(ctx, claims.Uid, match, change)
+		if err == repository.ErrNotFound {
+			helper.RespondError(c, http.StatusBadRequest, "Invalid or expired verification link")
+			return
+		}
+		if err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Email could not be verified")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+	}
+}
+
+// ResendVerificationEmail sends a new verification email
+// @Summary Resend verification email
+// @Description Send a new verification link to the logged in user. Earlier links stop working. Requests are throttled.
+// @Tags Auth Registration and Login
+// @Accept json
+// @Produce json
+// @Security APIKeyAuth
+// @Success 200 {string} Verification email sent
+// @Failure 400 {object} models.Error "Email already verified"
+// @Failure 401 {object} models.Error "Unauthorized"
+// @Failure 429 {object} models.Error "Too many requests"
+// @Failure 500 {object} models.Error "Internal server error"
+// @Router /users/verify-email/resend [post]
+func (h *AccountHandler) ResendVerificationEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, exists := c.Get("uid")
+		if !exists {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+		defer cancel()
+
+		user, err := h.Users.FindByID(ctx, uid.(string))
+		if err != nil {
+			helper.RespondError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if user.Email_verified {
+			helper.RespondError(c, http.StatusBadRequest, "Email is already verified")
+			return
+		}
+		if user.Verification_sent_at != nil && time.Since(*user.Verification_sent_at) < config.App.VerificationResendInterval {
+			wait := config.App.VerificationResendInterval - time.Since(*user.Verification_sent_at)
+			c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+			helper.RespondError(c, http.StatusTooManyRequests, "Please wait before requesting another verification email")
+			return
+		}
+
+		nonce := helper.NewNonce()
+		now := time.Now()
+		change := repository.UserChange{Set: bson.M{"verification_nonce": nonce, "verification_sent_at": now, "updated_at": now}}
+		if err := h.Users.Update(ctx, user.User_id, nil, change); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Verification email could not be sent")
+			return
+		}
+
+		if err := sendVerificationEmail(user, nonce); err != nil {
+			helper.RespondError(c, http.StatusInternalServerError, "Verification email could not be sent")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+	}
+}
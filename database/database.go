@@ -2,50 +2,52 @@ package database
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"os"
-	"time"
+	"log/slog"
+
+	"social-media-api/config"
+	"social-media-api/logging"
+	"social-media-api/metrics"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 //DBinstance func
 func DBinstance() *mongo.Client {
-    err := godotenv.Load(".env")
-	
-    if err != nil {
-        log.Fatal("Error loading .env file")
-    }
+    MongoDb := config.App.MongoURI
 
-    MongoDb := os.Getenv("MONGODB_URL")
-
-    client, err := mongo.NewClient(options.Client().ApplyURI(MongoDb))
+    clientOptions := options.Client().ApplyURI(MongoDb).
+        SetMonitor(commandMonitor()).
+        SetPoolMonitor(metrics.PoolMonitor())
+    client, err := mongo.NewClient(clientOptions)
     if err != nil {
-        log.Fatal(err)
+        logging.Fatal("creating the MongoDB client failed", "error", err)
     }
 
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), config.App.MongoConnectTimeout)
 
     defer cancel()
     err = client.Connect(ctx)
     if err != nil {
-        log.Fatal(err)
+        logging.Fatal("connecting to MongoDB failed", "error", err)
     }
-    fmt.Println("Connected to MongoDB!")
+    slog.Info("connected to MongoDB")
 
     return client
 }
 
-//Client Database instance
-var Client *mongo.Client = DBinstance()
+//Client Database instance, connected by main with DBinstance
+var Client *mongo.Client
+
+//OpenDatabase returns the database the API keeps its collections in
+func OpenDatabase(client *mongo.Client) *mongo.Database {
+    return client.Database(config.App.MongoDatabase)
+}
 
 //OpenCollection is a  function makes a connection with a collection in the database
 func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
 
-    var collection *mongo.Collection = client.Database("cluster0").Collection(collectionName)
+    var collection *mongo.Collection = OpenDatabase(client).Collection(collectionName)
 
     return collection
 }
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+
+	"social-media-api/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names of the unique indexes on the user collection. Duplicate key errors
+// mention the index name, which is how handlers tell which field clashed.
+const (
+	UserEmailIndex = "email_unique"
+	UserPhoneIndex = "phone_unique"
+)
+
+// EnsureIndexes creates the indexes the API relies on. Creating an index
+// that already exists is a no-op, so it is safe to call on every start.
+func EnsureIndexes(client *mongo.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.App.MongoIndexTimeout)
+	defer cancel()
+
+	users := OpenCollection(client, "user")
+	_, err := users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName(UserEmailIndex).SetUnique(true)},
+		// accounts created through an OpenID Connect provider have no phone
+		{Keys: bson.D{{Key: "phone", Value: 1}}, Options: options.Index().SetName(UserPhoneIndex).SetUnique(true).
+			SetPartialFilterExpression(bson.M{"phone": bson.M{"$type": "string"}})},
+		{Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}, Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}})},
+	})
+	if err != nil {
+		return err
+	}
+
+	apiKeys := OpenCollection(client, "api_key")
+	_, err = apiKeys.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	sessions := OpenCollection(client, "session")
+	_, err = sessions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the deletion job looks for accounts whose grace period has ended
+	_, err = users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "deletion_scheduled_at", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	deletions := OpenCollection(client, "account_deletion")
+	_, err = deletions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	exports := OpenCollection(client, "data_export")
+	_, err = exports.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// login requests that were never completed expire on their own
+	oidcStates := OpenCollection(client, "oidc_state")
+	_, err = oidcStates.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"social-media-api/metrics"
+	"social-media-api/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runningCommand is what is known about a command between its started and
+// finished events, which only carry the command name.
+type runningCommand struct {
+	collection string
+	span       trace.Span
+}
+
+// commandCollection returns the collection a command works on. The first
+// element of a command names it and usually holds the collection; getMore
+// names it in a field of its own.
+func commandCollection(name string, command bson.Raw) string {
+	first, err := command.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	value := first.Value()
+	if name == "getMore" {
+		value = command.Lookup("collection")
+	}
+	collection, _ := value.StringValueOK()
+	return collection
+}
+
+// commandMonitor times every MongoDB command for the metrics and traces it
+// as a client span under the span of the context it runs with. Command
+// documents are not recorded because they hold user data.
+func commandMonitor() *event.CommandMonitor {
+	var running sync.Map // request ID to runningCommand
+
+	finished := func(requestID int64, command string, outcome string, seconds float64, failure string) {
+		value, ok := running.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		cmd := value.(runningCommand)
+		label := cmd.collection
+		if label == "" {
+			label = "none"
+		}
+		metrics.MongoDuration.WithLabelValues(label, command, outcome).Observe(seconds)
+		if failure != "" {
+			cmd.span.SetStatus(codes.Error, failure)
+		}
+		cmd.span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			collection := commandCollection(e.CommandName, e.Command)
+			name := e.CommandName
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBNamespace(e.DatabaseName),
+				semconv.DBOperationName(e.CommandName),
+			}
+			if collection != "" {
+				name += " " + collection
+				attrs = append(attrs, semconv.DBCollectionName(collection))
+			}
+			_, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			running.Store(e.RequestID, runningCommand{collection: collection, span: span})
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finished(e.RequestID, e.CommandName, "success", e.Duration.Seconds(), "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finished(e.RequestID, e.CommandName, "failure", e.Duration.Seconds(), e.Failure)
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import (
+    "fmt"
+    "time"
+
+    "social-media-api/models"
+)
+
+// AccountRestriction explains why a user may not use the API right now, or
+// returns an empty string when the account is in good standing.
+func AccountRestriction(user models.User) string {
+    if user.Banned {
+        return "this account has been banned"
+    }
+    if user.Suspended_until != nil && user.Suspended_until.After(time.Now()) {
+        return fmt.Sprintf("this account is suspended until %s", user.Suspended_until.Format(time.RFC3339))
+    }
+    if user.Deletion_scheduled_at != nil {
+        return fmt.Sprintf("this account is scheduled for deletion on %s, restore it at /users/restore", user.Deletion_scheduled_at.Format(time.RFC3339))
+    }
+    return ""
+}
+
+// AccountRole returns the role of the user, which is a regular user when none
+// was ever set.
+func AccountRole(user models.User) string {
+    if user.Role == "" {
+        return models.RoleUser
+    }
+    return user.Role
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "time"
+
+    "social-media-api/config"
+
+    jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+    PurposeVerifyEmail   = "verify_email"
+    PurposeResetPassword = "reset_password"
+    PurposeChangeEmail   = "change_email"
+    PurposeMFALogin      = "mfa_login"
+    PurposeDataExport    = "data_export"
+)
+
+// ActionClaims are carried by the signed single-use links sent by email.
+// Id holds a random nonce that is also stored on the user, so a link stops
+// working once it has been used or a newer one was sent.
+type ActionClaims struct {
+    Purpose string
+    Uid     string
+    Email   string
+    jwt.StandardClaims
+}
+
+// NewNonce returns a random hex string for single-use tokens.
+func NewNonce() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        panic(err)
+    }
+    return hex.EncodeToString(b)
+}
+
+// GenerateActionToken signs a token for a single purpose that expires after ttl.
+func GenerateActionToken(purpose string, uid string, email string, nonce string, ttl time.Duration) (string, error) {
+    claims := &ActionClaims{
+        Purpose: purpose,
+        Uid:     uid,
+        Email:   email,
+        StandardClaims: jwt.StandardClaims{
+            Id:        nonce,
+            ExpiresAt: time.Now().Local().Add(ttl).Unix(),
+        },
+    }
+    return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.App.SecretKey))
+}
+
+// ValidateActionToken checks the signature, expiry and purpose of an action token.
+func ValidateActionToken(signedToken string, purpose string) (claims *ActionClaims, msg string) {
+    token, err := jwt.ParseWithClaims(
+        signedToken,
+        &ActionClaims{},
+        func(token *jwt.Token) (interface{}, error) {
+            return []byte(config.App.SecretKey), nil
+        },
+    )
+    if err != nil {
+        msg = err.Error()
+        return
+    }
+
+    claims, ok := token.Claims.(*ActionClaims)
+    if !ok || !token.Valid || claims.Purpose != purpose {
+        msg = "the token is invalid"
+        return
+    }
+
+    return claims, msg
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "strings"
+
+    "social-media-api/models"
+)
+
+// APIKeyPrefix starts every API key so keys are easy to tell apart from JWTs
+// and easy to spot when leaked.
+const APIKeyPrefix = "sma_"
+
+// NewAPIKey returns a new secret API key, the short prefix shown in key
+// listings and the hash that is stored in place of the key.
+func NewAPIKey() (key string, prefix string, hash string) {
+    b := make([]byte, 24)
+    if _, err := rand.Read(b); err != nil {
+        panic(err)
+    }
+    secret := hex.EncodeToString(b)
+    key = APIKeyPrefix + secret
+    return key, key[:len(APIKeyPrefix)+8], HashAPIKey(key)
+}
+
+// HashAPIKey hashes an API key for storage and lookup. Keys are random, so a
+// fast hash is enough.
+func HashAPIKey(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether a credential looks like an API key rather than a JWT.
+func IsAPIKey(credential string) bool {
+    return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// HasScope reports whether the key was granted scope.
+func HasScope(apiKey models.APIKey, scope string) bool {
+    for _, granted := range apiKey.Scopes {
+        if granted == scope {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+    "errors"
+    "strings"
+
+    "social-media-api/config"
+)
+
+// NormalizeEmail lowercases and trims an email address so lookups and the
+// unique index treat addresses that differ only in case as the same.
+func NormalizeEmail(email string) string {
+    return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone converts a phone number to E.164 (+ and 8 to 15 digits).
+// Spaces, dashes, dots and parentheses are ignored and a leading 00 is read
+// as +. Numbers without a country code get DEFAULT_PHONE_COUNTRY_CODE, with
+// the national trunk prefix 0 removed; without that setting they are
+// rejected.
+func NormalizePhone(phone string) (string, error) {
+    var b strings.Builder
+    for i, r := range strings.TrimSpace(phone) {
+        switch {
+        case r >= '0' && r <= '9':
+            b.WriteRune(r)
+        case r == '+' && i == 0:
+            b.WriteRune(r)
+        case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+        default:
+            return "", errors.New("phone number may only contain digits, spaces, dashes, dots, parentheses and a leading +")
+        }
+    }
+
+    number := b.String()
+    switch {
+    case strings.HasPrefix(number, "+"):
+        number = number[1:]
+    case strings.HasPrefix(number, "00"):
+        number = number[2:]
+    default:
+        countryCode := config.App.DefaultPhoneCountryCode
+        if countryCode == "" {
+            return "", errors.New("phone number must include a country code, e.g. +14155550100")
+        }
+        number = countryCode + strings.TrimPrefix(number, "0")
+    }
+
+    if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+        return "", errors.New("phone number is not a valid international number")
+    }
+    return "+" + number, nil
+}
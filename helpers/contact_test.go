@@ -0,0 +1,76 @@
+package helpers
+
+import (
+	"testing"
+
+	"social-media-api/config"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	t.Cleanup(func() { config.App = config.Defaults() })
+
+	tests := []struct {
+		name        string
+		countryCode string
+		phone       string
+		want        string
+	}{
+		{"E.164", "", "+14155550100", "+14155550100"},
+		{"formatted", "", "+1 (415) 555-0100", "+14155550100"},
+		{"dots", "", "+44.20.7946.0958", "+442079460958"},
+		{"surrounding space", "", "  +49 151 12345678 ", "+4915112345678"},
+		{"leading 00", "", "0049 151 12345678", "+4915112345678"},
+		{"default country code", "49", "151 12345678", "+4915112345678"},
+		{"trunk prefix dropped", "49", "0151 12345678", "+4915112345678"},
+		{"explicit code wins over the default", "49", "+44 20 7946 0958", "+442079460958"},
+		{"longest number", "", "+123456789012345", "+123456789012345"},
+		{"shortest number", "", "+12345678", "+12345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.App.DefaultPhoneCountryCode = tt.countryCode
+			got, err := NormalizePhone(tt.phone)
+			if err != nil {
+				t.Fatalf("NormalizePhone(%q): %v", tt.phone, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneRejectsInvalidNumbers(t *testing.T) {
+	t.Cleanup(func() { config.App = config.Defaults() })
+
+	tests := []struct {
+		name        string
+		countryCode string
+		phone       string
+	}{
+		{"no country code and no default", "", "0151 12345678"},
+		{"letters", "", "+1 415 CALL NOW"},
+		{"plus in the middle", "", "1+4155550100"},
+		{"slash", "", "+49 151/12345678"},
+		{"too short", "", "+1234567"},
+		{"too long", "", "+1234567890123456"},
+		{"country code starting with 0", "", "+0151 12345678"},
+		{"empty", "", ""},
+		{"only a plus", "", "+"},
+		{"too short with the default", "49", "0151"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.App.DefaultPhoneCountryCode = tt.countryCode
+			if got, err := NormalizePhone(tt.phone); err == nil {
+				t.Errorf("NormalizePhone(%q) = %q, want an error", tt.phone, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := NormalizeEmail("  Ada.Lovelace@Example.COM "); got != "ada.lovelace@example.com" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package helpers
+
+import (
+    "log/slog"
+    "net/smtp"
+    "strings"
+
+    "social-media-api/config"
+)
+
+// Mailer sends plain text emails.
+type Mailer interface {
+    Send(to string, subject string, body string) error
+}
+
+// SMTPMailer sends email through an SMTP server. Leave Username empty for
+// servers without authentication such as a local MailHog.
+type SMTPMailer struct {
+    Host     string
+    Port     string
+    Username string
+    Password string
+    From     string
+}
+
+func (m SMTPMailer) Send(to string, subject string, body string) error {
+    var auth smtp.Auth
+    if m.Username != "" {
+        auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+    }
+
+    msg := strings.Join([]string{
+        "From: " + m.From,
+        "To: " + to,
+        "Subject: " + subject,
+        "MIME-Version: 1.0",
+        "Content-Type: text/plain; charset=UTF-8",
+        "",
+        body,
+    }, "\r\n")
+
+    return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(msg))
+}
+
+// LogMailer writes emails to the log instead of sending them; used when no
+// SMTP server is configured. The logger redacts the tokens in the links, so
+// the links in the log do not work.
+type LogMailer struct{}
+
+func (LogMailer) Send(to string, subject string, body string) error {
+    slog.Info("mail", "to", to, "subject", subject, "body", body)
+    return nil
+}
+
+// NewMailer returns an SMTPMailer configured by SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM, or a LogMailer without SMTP_HOST.
+func NewMailer(settings config.Config) Mailer {
+    if settings.SMTPHost == "" {
+        return LogMailer{}
+    }
+    return SMTPMailer{
+        Host:     settings.SMTPHost,
+        Port:     settings.SMTPPort,
+        Username: settings.SMTPUsername,
+        Password: settings.SMTPPassword,
+        From:     settings.MailFrom,
+    }
+}
+
+// Mail is the mailer used to send account emails. main sets it from the
+// configuration; replace it to plug in another provider.
+var Mail Mailer = LogMailer{}
+
+// AppURL is the public address links in emails point to.
+func AppURL() string {
+    return config.App.AppURL
+}
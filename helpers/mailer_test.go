@@ -0,0 +1,156 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"social-media-api/config"
+)
+
+// receivedMail is what the test SMTP server was told during one session.
+type receivedMail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer accepts one SMTP session on a local port and sends what it
+// received on the returned channel. rejectRecipient makes it refuse RCPT.
+func smtpServer(t *testing.T, rejectRecipient bool) (host string, port string, received <-chan receivedMail) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	out := make(chan receivedMail, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		var mail receivedMail
+		defer func() { out <- mail }()
+
+		text.PrintfLine("220 localhost ESMTP test")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				text.PrintfLine("250-localhost")
+				text.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				mail.auth = arg
+				text.PrintfLine("235 2.7.0 Authentication successful")
+			case "MAIL":
+				mail.from = arg
+				text.PrintfLine("250 OK")
+			case "RCPT":
+				if rejectRecipient {
+					text.PrintfLine("550 5.1.1 No such user")
+					continue
+				}
+				mail.to = append(mail.to, arg)
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				mail.data = string(data)
+				text.PrintfLine("250 OK")
+			case "RSET", "NOOP":
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(listener.Addr().String())
+	return host, port, out
+}
+
+func TestSMTPMailerSends(t *testing.T) {
+	host, port, received := smtpServer(t, false)
+	mailer := SMTPMailer{Host: host, Port: port, From: "noreply@example.com"}
+
+	if err := mailer.Send("ada@example.com", "Welcome", "Hi Ada,\n.\nLine after a lone dot"); err != nil {
+		t.Fatal(err)
+	}
+	mail := <-received
+	if mail.auth != "" {
+		t.Errorf("authenticated as %q without a username", mail.auth)
+	}
+	if mail.from != "FROM:<noreply@example.com>" {
+		t.Errorf("got MAIL %q", mail.from)
+	}
+	if len(mail.to) != 1 || mail.to[0] != "TO:<ada@example.com>" {
+		t.Errorf("got RCPT %q", mail.to)
+	}
+	for _, header := range []string{"From: noreply@example.com", "To: ada@example.com", "Subject: Welcome", "Content-Type: text/plain; charset=UTF-8"} {
+		if !strings.Contains(mail.data, header+"\n") {
+			t.Errorf("message has no %q header:\n%s", header, mail.data)
+		}
+	}
+	if !strings.HasSuffix(mail.data, "\n\nHi Ada,\n.\nLine after a lone dot\n") {
+		t.Errorf("body was not sent intact:\n%s", mail.data)
+	}
+}
+
+func TestSMTPMailerAuthenticates(t *testing.T) {
+	// PLAIN authentication without TLS is only allowed to localhost
+	_, port, received := smtpServer(t, false)
+	mailer := SMTPMailer{Host: "localhost", Port: port, Username: "api", Password: "secret", From: "noreply@example.com"}
+
+	if err := mailer.Send("ada@example.com", "Welcome", "Hi Ada"); err != nil {
+		t.Fatal(err)
+	}
+	mail := <-received
+	want := "PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00api\x00secret"))
+	if mail.auth != want {
+		t.Errorf("got AUTH %q, want %q", mail.auth, want)
+	}
+}
+
+func TestSMTPMailerReportsRejectedRecipients(t *testing.T) {
+	host, port, received := smtpServer(t, true)
+	mailer := SMTPMailer{Host: host, Port: port, From: "noreply@example.com"}
+
+	err := mailer.Send("nobody@example.com", "Welcome", "Hi")
+	if err == nil || !strings.Contains(err.Error(), "No such user") {
+		t.Errorf("got error %v, want the server's rejection", err)
+	}
+	<-received
+}
+
+func TestNewMailer(t *testing.T) {
+	settings := config.Defaults()
+	if _, ok := NewMailer(settings).(LogMailer); !ok {
+		t.Error("got an SMTP mailer without SMTP_HOST")
+	}
+
+	settings.SMTPHost = "mail.example.com"
+	settings.SMTPUsername = "api"
+	settings.SMTPPassword = "secret"
+	settings.MailFrom = "noreply@example.com"
+	want := SMTPMailer{Host: "mail.example.com", Port: settings.SMTPPort, Username: "api", Password: "secret", From: "noreply@example.com"}
+	if got := NewMailer(settings); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package helpers
+
+import (
+    "context"
+    "errors"
+    "sync"
+
+    "social-media-api/config"
+
+    "github.com/coreos/go-oidc/v3/oidc"
+    "golang.org/x/oauth2"
+)
+
+// OIDCProvider is a configured OpenID Connect provider. Providers are listed
+// in OIDC_PROVIDERS as comma separated names, and each name NAME is set up by
+// OIDC_NAME_ISSUER, OIDC_NAME_CLIENT_ID, OIDC_NAME_CLIENT_SECRET and
+// optionally OIDC_NAME_REDIRECT_URL and OIDC_NAME_SCOPES; see config.OIDCProvider.
+type OIDCProvider struct {
+    Name         string
+    Issuer       string
+    ClientID     string
+    ClientSecret string
+    RedirectURL  string
+    Scopes       []string
+
+    mu       sync.Mutex
+    provider *oidc.Provider
+}
+
+// OIDCProviders are the configured OpenID Connect providers by name. main
+// fills it from the configuration.
+var OIDCProviders = map[string]*OIDCProvider{}
+
+// NewOIDCProviders returns the providers of settings by name.
+func NewOIDCProviders(settings []config.OIDCProvider) map[string]*OIDCProvider {
+    providers := map[string]*OIDCProvider{}
+    for _, p := range settings {
+        providers[p.Name] = &OIDCProvider{
+            Name:         p.Name,
+            Issuer:       p.Issuer,
+            ClientID:     p.ClientID,
+            ClientSecret: p.ClientSecret,
+            RedirectURL:  p.RedirectURL,
+            Scopes:       p.Scopes,
+        }
+    }
+    return providers
+}
+
+// discover fetches the provider's discovery document the first time it is
+// needed, so an unreachable provider does not stop the API from starting.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if p.provider == nil {
+        if p.Issuer == "" || p.ClientID == "" {
+            return nil, errors.New("the provider " + p.Name + " is not fully configured")
+        }
+        provider, err := oidc.NewProvider(ctx, p.Issuer)
+        if err != nil {
+            return nil, err
+        }
+        p.provider = provider
+    }
+    return p.provider, nil
+}
+
+// OAuth2Config returns the authorization code flow configuration.
+func (p *OIDCProvider) OAuth2Config(ctx context.Context) (*oauth2.Config, error) {
+    provider, err := p.discover(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return &oauth2.Config{
+        ClientID:     p.ClientID,
+        ClientSecret: p.ClientSecret,
+        RedirectURL:  p.RedirectURL,
+        Endpoint:     provider.Endpoint(),
+        Scopes:       p.Scopes,
+    }, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience and expiry of an ID token.
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken string) (*oidc.IDToken, error) {
+    provider, err := p.discover(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return provider.Verifier(&oidc.Config{ClientID: p.ClientID}).Verify(ctx, rawIDToken)
+}
+
+// OIDCClaims are the ID token claims used to find or create the local user.
+type OIDCClaims struct {
+    Subject       string `json:"sub"`
+    Email         string `json:"email"`
+    EmailVerified bool   `json:"email_verified"`
+    GivenName     string `json:"given_name"`
+    FamilyName    string `json:"family_name"`
+    Name          string `json:"name"`
+}
@@ -0,0 +1,222 @@
+package helpers
+
+import (
+    "bufio"
+    "crypto/sha1"
+    _ "embed"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "log/slog"
+    "math"
+    "os"
+    "strings"
+    "sync"
+    "unicode"
+    "unicode/utf8"
+
+    "social-media-api/config"
+)
+
+// PasswordPolicy describes what a new password has to look like. The one in
+// force is returned by Passwords.
+type PasswordPolicy struct {
+    MinLength      int
+    MaxLength      int
+    RequireUpper   bool
+    RequireLower   bool
+    RequireDigit   bool
+    RequireSymbol  bool
+    RejectPersonal bool
+    RejectBreached bool
+}
+
+// Passwords returns the password policy of the PASSWORD_* settings.
+func Passwords() PasswordPolicy {
+    return PasswordPolicy{
+        MinLength:      config.App.PasswordMinLength,
+        MaxLength:      config.App.PasswordMaxLength,
+        RequireUpper:   config.App.PasswordRequireUpper,
+        RequireLower:   config.App.PasswordRequireLower,
+        RequireDigit:   config.App.PasswordRequireDigit,
+        RequireSymbol:  config.App.PasswordRequireSymbol,
+        RejectPersonal: config.App.PasswordRejectPersonal,
+        RejectBreached: config.App.PasswordRejectBreached,
+    }
+}
+
+// Check returns every rule the password breaks, in a form that can be shown
+// to the user, or nil when it is acceptable. personal holds the user's name
+// and email, which may not appear in the password.
+func (policy PasswordPolicy) Check(password string, personal ...string) []string {
+    var problems []string
+
+    if length := utf8.RuneCountInString(password); length < policy.MinLength {
+        problems = append(problems, fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+    } else if len(password) > policy.MaxLength {
+        problems = append(problems, fmt.Sprintf("must be at most %d bytes long", policy.MaxLength))
+    }
+
+    var upper, lower, digit, symbol bool
+    for _, r := range password {
+        switch {
+        case unicode.IsUpper(r):
+            upper = true
+        case unicode.IsLower(r):
+            lower = true
+        case unicode.IsDigit(r):
+            digit = true
+        default:
+            symbol = true
+        }
+    }
+    if policy.RequireUpper && !upper {
+        problems = append(problems, "must contain an uppercase letter")
+    }
+    if policy.RequireLower && !lower {
+        problems = append(problems, "must contain a lowercase letter")
+    }
+    if policy.RequireDigit && !digit {
+        problems = append(problems, "must contain a digit")
+    }
+    if policy.RequireSymbol && !symbol {
+        problems = append(problems, "must contain a symbol")
+    }
+
+    if policy.RejectPersonal {
+        lowered := strings.ToLower(password)
+        for _, value := range personal {
+            value = strings.ToLower(strings.TrimSpace(value))
+            if at := strings.Index(value, "@"); at >= 0 {
+                value = value[:at]
+            }
+            if len(value) >= 3 && strings.Contains(lowered, value) {
+                problems = append(problems, "must not contain your name or email address")
+                break
+            }
+        }
+    }
+
+    if policy.RejectBreached && PasswordBreached(password) {
+        problems = append(problems, "appears in a list of breached passwords, please choose another one")
+    }
+
+    return problems
+}
+
+//go:embed breached_passwords.txt
+var bundledBreachedPasswords string
+
+var breachedOnce sync.Once
+var breached *bloomFilter
+
+// PasswordBreached reports whether the password is in the bundled list of
+// common breached passwords or in BREACHED_PASSWORDS_FILE. The file may hold
+// plain passwords or SHA-1 hashes in the "HASH:count" format of the Have I
+// Been Pwned downloads. Entries are kept in a bloom filter keyed by SHA-1, so
+// a rare false positive only asks the user to pick another password.
+func PasswordBreached(password string) bool {
+    breachedOnce.Do(loadBreachedPasswords)
+    return breached.contains(sha1Hex(password)) || breached.contains(sha1Hex(strings.ToLower(password)))
+}
+
+func loadBreachedPasswords() {
+    var hashes []string
+    addAll := func(r io.Reader) {
+        scanner := bufio.NewScanner(r)
+        for scanner.Scan() {
+            line := strings.TrimSpace(scanner.Text())
+            if line == "" {
+                continue
+            }
+            if hash, _, _ := strings.Cut(line, ":"); len(hash) == 40 && isHex(hash) {
+                hashes = append(hashes, strings.ToUpper(hash))
+            } else {
+                hashes = append(hashes, sha1Hex(line))
+            }
+        }
+        if err := scanner.Err(); err != nil {
+            slog.Error("reading breached passwords failed", "error", err)
+        }
+    }
+
+    addAll(strings.NewReader(bundledBreachedPasswords))
+    if path := config.App.BreachedPasswordsFile; path != "" {
+        file, err := os.Open(path)
+        if err != nil {
+            slog.Error("opening the breached password list failed", "path", path, "error", err)
+        } else {
+            addAll(file)
+            file.Close()
+        }
+    }
+
+    breached = newBloomFilter(len(hashes), 0.0001)
+    for _, hash := range hashes {
+        breached.add(hash)
+    }
+}
+
+func sha1Hex(value string) string {
+    sum := sha1.Sum([]byte(value))
+    return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isHex(value string) bool {
+    _, err := hex.DecodeString(value)
+    return err == nil
+}
+
+// bloomFilter is a fixed size bloom filter over SHA-1 hex strings. The hash
+// is already uniform, so its bytes are used directly for double hashing.
+type bloomFilter struct {
+    bits   []uint64
+    size   uint64
+    hashes int
+}
+
+func newBloomFilter(items int, falsePositiveRate float64) *bloomFilter {
+    if items < 1 {
+        items = 1
+    }
+    size := uint64(math.Ceil(-float64(items) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+    hashes := int(math.Round(float64(size) / float64(items) * math.Ln2))
+    if hashes < 1 {
+        hashes = 1
+    }
+    return &bloomFilter{bits: make([]uint64, (size+63)/64), size: size, hashes: hashes}
+}
+
+func (f *bloomFilter) positions(hash string) []uint64 {
+    raw, err := hex.DecodeString(hash)
+    if err != nil || len(raw) < 16 {
+        return nil
+    }
+    h1 := binary.BigEndian.Uint64(raw[0:8])
+    h2 := binary.BigEndian.Uint64(raw[8:16])
+    positions := make([]uint64, f.hashes)
+    for i := range positions {
+        positions[i] = (h1 + uint64(i)*h2) % f.size
+    }
+    return positions
+}
+
+func (f *bloomFilter) add(hash string) {
+    for _, p := range f.positions(hash) {
+        f.bits[p/64] |= 1 << (p % 64)
+    }
+}
+
+func (f *bloomFilter) contains(hash string) bool {
+    positions := f.positions(hash)
+    if positions == nil {
+        return false
+    }
+    for _, p := range positions {
+        if f.bits[p/64]&(1<<(p%64)) == 0 {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,158 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"social-media-api/config"
+)
+
+// reloadBreachedPasswords makes the next PasswordBreached call read the
+// bundled list and BREACHED_PASSWORDS_FILE again, and restores the defaults
+// when the test ends.
+func reloadBreachedPasswords(t *testing.T, file string) {
+	t.Helper()
+	config.App.BreachedPasswordsFile = file
+	breachedOnce = sync.Once{}
+	t.Cleanup(func() {
+		config.App = config.Defaults()
+		breachedOnce = sync.Once{}
+	})
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	reloadBreachedPasswords(t, "")
+	policy := PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      72,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		RejectPersonal: true,
+		RejectBreached: true,
+	}
+	personal := []string{"Ada", "Lovelace", "Ada.Lovelace@example.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		problems []string
+	}{
+		{"acceptable", "Engine-1843", nil},
+		{"too short", "En-18", []string{"must be at least 8 characters long"}},
+		{"too short in characters, not bytes", "Ää-1ß", []string{"must be at least 8 characters long"}},
+		{"too long", "E-1" + strings.Repeat("n", 70), []string{"must be at most 72 bytes long"}},
+		{"no uppercase letter", "engine-1843", []string{"must contain an uppercase letter"}},
+		{"no lowercase letter", "ENGINE-1843", []string{"must contain a lowercase letter"}},
+		{"no digit", "Engine-Room", []string{"must contain a digit"}},
+		{"no symbol", "Engine1843", []string{"must contain a symbol"}},
+		{"every class missing", "        ", []string{"must contain an uppercase letter", "must contain a lowercase letter", "must contain a digit"}},
+		{"first name", "Dear-ADA-1843", []string{"must not contain your name or email address"}},
+		{"last name", "Countess-Lovelace-1", []string{"must not contain your name or email address"}},
+		{"local part of the email", "Ada.lovelace-1843", []string{"must not contain your name or email address"}},
+		{"bundled breached password", "Password1!", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := policy.Check(tt.password, personal...)
+			if !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("got %q, want %q", problems, tt.problems)
+			}
+		})
+	}
+
+	// a symbol-free breached password breaks both rules
+	problems := policy.Check("Password1")
+	want := []string{"must contain a symbol", "appears in a list of breached passwords, please choose another one"}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("Password1: got %q, want %q", problems, want)
+	}
+}
+
+func TestPasswordPolicyCheckSkipsDisabledRules(t *testing.T) {
+	reloadBreachedPasswords(t, "")
+	policy := PasswordPolicy{MinLength: 1, MaxLength: 72}
+	for _, password := range []string{"password", "ada", "123456"} {
+		if problems := policy.Check(password, "Ada"); problems != nil {
+			t.Errorf("%q: got %q, want no problems", password, problems)
+		}
+	}
+}
+
+func TestPasswordPolicyCheckIgnoresShortPersonalData(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 1, MaxLength: 72, RejectPersonal: true}
+	// a name of one or two letters would rule out too many passwords
+	if problems := policy.Check("Jo-Engine-1843", "Jo", "  ", ""); problems != nil {
+		t.Errorf("got %q, want no problems", problems)
+	}
+}
+
+func TestPasswordBreached(t *testing.T) {
+	// "Engine-Room-1843" as a plain line and "Difference-Engine-1822" as the
+	// SHA-1 hash of a Have I Been Pwned download
+	list := "Engine-Room-1843\n\n" + strings.ToLower(sha1Hex("Difference-Engine-1822")) + ":12\n"
+	file := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(file, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloadBreachedPasswords(t, file)
+
+	tests := []struct {
+		password string
+		breached bool
+	}{
+		{"123456", true},
+		{"password1", true},
+		{"PASSWORD1", true},
+		{"Engine-Room-1843", true},
+		{"Difference-Engine-1822", true},
+		{"Analytical-Engine-1843", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := PasswordBreached(tt.password); got != tt.breached {
+			t.Errorf("%q: got breached %v, want %v", tt.password, got, tt.breached)
+		}
+	}
+}
+
+func TestPasswordBreachedWithoutFile(t *testing.T) {
+	reloadBreachedPasswords(t, filepath.Join(t.TempDir(), "missing.txt"))
+	// a missing file is logged and the bundled list still applies
+	if !PasswordBreached("qwerty123") {
+		t.Error("bundled password was not found")
+	}
+	if PasswordBreached("Engine-Room-1843") {
+		t.Error("password from no list was reported as breached")
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	filter := newBloomFilter(100, 0.0001)
+	for i := 0; i < 100; i++ {
+		filter.add(sha1Hex(strings.Repeat("x", i)))
+	}
+	for i := 0; i < 100; i++ {
+		if !filter.contains(sha1Hex(strings.Repeat("x", i))) {
+			t.Fatalf("added entry %d is missing", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.contains(sha1Hex(strings.Repeat("y", i+1))) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 10 {
+		t.Errorf("got %d false positives in 10000, want about 1", falsePositives)
+	}
+	if filter.contains("not a hash") {
+		t.Error("a malformed hash was reported as present")
+	}
+}
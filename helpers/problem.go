@@ -0,0 +1,153 @@
+package helpers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "reflect"
+    "strings"
+
+    "social-media-api/models"
+
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of error responses.
+const ProblemContentType = "application/problem+json"
+
+// Machine-readable error codes. Most follow from the status code; the
+// others name a condition clients are expected to handle on its own.
+const (
+    CodeBadRequest         = "bad_request"
+    CodeValidationFailed   = "validation_failed"
+    CodeUnauthorized       = "unauthorized"
+    CodeInvalidCredentials = "invalid_credentials"
+    CodeInvalidToken       = "invalid_token"
+    CodeForbidden          = "forbidden"
+    CodeAccountRestricted  = "account_restricted"
+    CodeNotFound           = "not_found"
+    CodeMethodNotAllowed   = "method_not_allowed"
+    CodeConflict           = "conflict"
+    CodeGone               = "gone"
+    CodeTooManyRequests    = "too_many_requests"
+    CodeInternal           = "internal_error"
+    CodeBadGateway         = "bad_gateway"
+    CodeUnavailable        = "service_unavailable"
+)
+
+var statusErrorCodes = map[int]string{
+    http.StatusBadRequest:          CodeBadRequest,
+    http.StatusUnauthorized:        CodeUnauthorized,
+    http.StatusForbidden:           CodeForbidden,
+    http.StatusNotFound:            CodeNotFound,
+    http.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+    http.StatusConflict:            CodeConflict,
+    http.StatusGone:                CodeGone,
+    http.StatusTooManyRequests:     CodeTooManyRequests,
+    http.StatusInternalServerError: CodeInternal,
+    http.StatusBadGateway:          CodeBadGateway,
+    http.StatusServiceUnavailable:  CodeUnavailable,
+}
+
+// ErrorCode returns the code of errors answered with status.
+func ErrorCode(status int) string {
+    if code, ok := statusErrorCodes[status]; ok {
+        return code
+    }
+    if status >= 500 {
+        return CodeInternal
+    }
+    return CodeBadRequest
+}
+
+// NewProblem builds the body of an error response to the request of c.
+func NewProblem(c *gin.Context, status int, code string, detail string) models.Error {
+    return models.Error{
+        Type:       "about:blank",
+        Title:      http.StatusText(status),
+        Status:     status,
+        Code:       code,
+        Detail:     detail,
+        Instance:   c.Request.URL.Path,
+        Request_id: c.GetString("request_id"),
+    }
+}
+
+// WriteProblem answers with problem and stops the handler chain.
+func WriteProblem(c *gin.Context, problem models.Error) {
+    c.Header("Content-Type", ProblemContentType)
+    c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// RespondError answers with an error whose code follows from status.
+func RespondError(c *gin.Context, status int, detail string) {
+    WriteProblem(c, NewProblem(c, status, ErrorCode(status), detail))
+}
+
+// RespondErrorCode answers with an error under a specific code.
+func RespondErrorCode(c *gin.Context, status int, code string, detail string) {
+    WriteProblem(c, NewProblem(c, status, code, detail))
+}
+
+// RespondFieldErrors answers 400 listing the fields that were rejected.
+func RespondFieldErrors(c *gin.Context, detail string, fields ...models.FieldError) {
+    problem := NewProblem(c, http.StatusBadRequest, CodeValidationFailed, detail)
+    problem.Errors = fields
+    WriteProblem(c, problem)
+}
+
+// RespondInvalidInput answers 400 for a request body that could not be
+// read or failed validation. Validation failures list every field.
+func RespondInvalidInput(c *gin.Context, err error) {
+    var invalid validator.ValidationErrors
+    if !errors.As(err, &invalid) {
+        RespondError(c, http.StatusBadRequest, err.Error())
+        return
+    }
+    fields := make([]models.FieldError, 0, len(invalid))
+    for _, fe := range invalid {
+        fields = append(fields, models.FieldError{Field: fe.Field(), Code: fe.Tag(), Message: fieldMessage(fe)})
+    }
+    RespondFieldErrors(c, "the request has invalid fields", fields...)
+}
+
+// NewValidator returns a validator that reports fields by their json names,
+// so the errors of RespondInvalidInput match the request body.
+func NewValidator() *validator.Validate {
+    v := validator.New()
+    v.RegisterTagNameFunc(func(field reflect.StructField) string {
+        name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+        if name == "-" {
+            return ""
+        }
+        if name == "" {
+            return field.Name
+        }
+        return name
+    })
+    return v
+}
+
+// fieldMessage explains a failed validation rule in words.
+func fieldMessage(fe validator.FieldError) string {
+    switch fe.Tag() {
+    case "required":
+        return "is required"
+    case "email":
+        return "must be a valid email address"
+    case "min":
+        return fmt.Sprintf("must be at least %s long", fe.Param())
+    case "max":
+        return fmt.Sprintf("must be at most %s long", fe.Param())
+    case "len":
+        return fmt.Sprintf("must be exactly %s long", fe.Param())
+    case "oneof":
+        return fmt.Sprintf("must be one of: %s", fe.Param())
+    case "gte":
+        return fmt.Sprintf("must be at least %s", fe.Param())
+    case "lte":
+        return fmt.Sprintf("must be at most %s", fe.Param())
+    }
+    return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"social-media-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respond runs handler for a GET of path and decodes the problem it wrote.
+func respond(t *testing.T, path string, handler gin.HandlerFunc) (*httptest.ResponseRecorder, models.Error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET(path, func(c *gin.Context) {
+		c.Set("request_id", "req-1")
+		handler(c)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var problem models.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	return w, problem
+}
+
+func TestRespondErrorWritesProblem(t *testing.T) {
+	w, problem := respond(t, "/posts/abc", func(c *gin.Context) {
+		RespondError(c, http.StatusNotFound, "Post not found")
+	})
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want 404", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Errorf("got content type %q, want %q", got, ProblemContentType)
+	}
+	want := models.Error{
+		Type:       "about:blank",
+		Title:      "Not Found",
+		Status:     http.StatusNotFound,
+		Code:       CodeNotFound,
+		Detail:     "Post not found",
+		Instance:   "/posts/abc",
+		Request_id: "req-1",
+	}
+	if !reflect.DeepEqual(problem, want) {
+		t.Errorf("got %+v, want %+v", problem, want)
+	}
+}
+
+func TestRespondInvalidInputNamesJSONFields(t *testing.T) {
+	type signup struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password,omitempty" validate:"min=8"`
+	}
+	err := NewValidator().Struct(signup{Email: "ada", Password: "short"})
+	if err == nil {
+		t.Fatal("the request passed validation")
+	}
+
+	w, problem := respond(t, "/users/signup", func(c *gin.Context) {
+		RespondInvalidInput(c, err)
+	})
+
+	if w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Errorf("got status %d and code %q, want 400 and %q", w.Code, problem.Code, CodeValidationFailed)
+	}
+	want := []models.FieldError{
+		{Field: "email", Code: "email", Message: "must be a valid email address"},
+		{Field: "password", Code: "min", Message: "must be at least 8 long"},
+	}
+	if !reflect.DeepEqual(problem.Errors, want) {
+		t.Errorf("got field errors %+v, want %+v", problem.Errors, want)
+	}
+}
+
+func TestRespondInvalidInputReportsUnreadableBodies(t *testing.T) {
+	w, problem := respond(t, "/users/signup", func(c *gin.Context) {
+		RespondInvalidInput(c, &json.SyntaxError{})
+	})
+
+	if w.Code != http.StatusBadRequest || problem.Code != CodeBadRequest || problem.Errors != nil {
+		t.Errorf("got status %d and %+v", w.Code, problem)
+	}
+}
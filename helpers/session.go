@@ -0,0 +1,13 @@
+package helpers
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+)
+
+// HashRefreshToken returns the hash a session stores in place of its
+// refresh token.
+func HashRefreshToken(refreshToken string) string {
+    sum := sha256.Sum256([]byte(refreshToken))
+    return hex.EncodeToString(sum[:])
+}
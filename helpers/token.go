@@ -1,19 +1,20 @@
 package helpers
 
 import (
-    "context"
     "fmt"
-    "log"
-    "os"
     "time"
 
-    "social-media-api/database"
+    "social-media-api/config"
+    "social-media-api/models"
 
     jwt "github.com/dgrijalva/jwt-go"
-    "go.mongodb.org/mongo-driver/bson"
-    "go.mongodb.org/mongo-driver/bson/primitive"
-    "go.mongodb.org/mongo-driver/mongo"
-    "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The types of session token. Action tokens share the signing key but carry
+// a purpose instead, so ValidateToken refuses them.
+const (
+    TokenAccess  = "access"
+    TokenRefresh = "refresh"
 )
 
 // SignedDetails
@@ -22,37 +23,52 @@ type SignedDetails struct {
     First_name string
     Last_name  string
     Uid        string
+    Role       string
+    Sid        string
+    Type       string
     jwt.StandardClaims
 }
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
-
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
-
 // The GenerateAllTokens function generates a signed token and a signed refresh token with specified
-// claims for a user.
-func GenerateAllTokens(email string, firstName string, lastName string, uid string) (signedToken string, signedRefreshToken string, err error) {
+// claims for a user. An empty role is issued as a regular user. Both tokens carry the session they
+// belong to.
+func GenerateAllTokens(email string, firstName string, lastName string, uid string, role string, sessionId string) (signedToken string, signedRefreshToken string, err error) {
+    if role == "" {
+        role = models.RoleUser
+    }
     claims := &SignedDetails{
         Email:      email,
         First_name: firstName,
         Last_name:  lastName,
         Uid:        uid,
+        Role:       role,
+        Sid:        sessionId,
+        Type:       TokenAccess,
         StandardClaims: jwt.StandardClaims{
-            ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
+            IssuedAt:  time.Now().Unix(),
+            ExpiresAt: time.Now().Local().Add(config.App.AccessTokenTTL).Unix(),
         },
     }
 
+    // the random ID keeps a rotated refresh token from matching the one
+    // it replaces when both are issued within the same second
     refreshClaims := &SignedDetails{
+        Uid:     uid,
+        Sid:     sessionId,
+        Type:    TokenRefresh,
         StandardClaims: jwt.StandardClaims{
-            ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
+            Id:        NewNonce(),
+            IssuedAt:  time.Now().Unix(),
+            ExpiresAt: time.Now().Local().Add(config.App.RefreshTokenTTL).Unix(),
         },
     }
 
-    token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
-    refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
-
+    token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.App.SecretKey))
+    if err != nil {
+        return
+    }
+    refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(config.App.SecretKey))
     if err != nil {
-        log.Panic(err)
         return
     }
 
@@ -61,13 +77,14 @@ func GenerateAllTokens(email string, firstName string, lastName string, uid stri
 
 //ValidateToken validates the jwt token
 // The function `ValidateToken` parses a signed token using a secret key and checks its validity based
-// on expiration time.
+// on expiration time. Only access and refresh tokens of a session are valid; callers check which of
+// the two they were given.
 func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
     token, err := jwt.ParseWithClaims(
         signedToken,
         &SignedDetails{},
         func(token *jwt.Token) (interface{}, error) {
-            return []byte(SECRET_KEY), nil
+            return []byte(config.App.SecretKey), nil
         },
     )
 
@@ -77,55 +94,25 @@ func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
     }
 
     claims, ok := token.Claims.(*SignedDetails)
-    if !ok {
+    if !ok || !token.Valid {
         msg = fmt.Sprintf("the token is invalid")
-        msg = err.Error()
-        return
+        return nil, msg
+    }
+    if claims.Type != TokenAccess && claims.Type != TokenRefresh || claims.Sid == "" {
+        msg = fmt.Sprintf("the token is invalid")
+        return nil, msg
     }
 
     if claims.ExpiresAt < time.Now().Local().Unix() {
         msg = fmt.Sprintf("token is expired")
-        msg = err.Error()
-        return
+        return nil, msg
     }
 
     return claims, msg
 }
 
 
-// The function `UpdateAllTokens` updates a user's token and refresh token in a MongoDB collection with
-// the provided signed tokens and user ID.
-func UpdateAllTokens(signedToken string, signedRefreshToken string, userId string) {
-    var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-    var updateObj primitive.D
-
-    updateObj = append(updateObj, bson.E{Key: "token", Value: signedToken})
-    updateObj = append(updateObj, bson.E{Key: "refresh_token", Value: signedRefreshToken})
-
-    Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-    updateObj = append(updateObj, bson.E{Key: "updated_at", Value: Updated_at})
-
-    upsert := true
-    filter := bson.M{"user_id": userId}
-    opt := options.UpdateOptions{
-        Upsert: &upsert,
-    }
-
-    _, err := userCollection.UpdateOne(
-        ctx,
-        filter,
-        bson.D{
-            {"$set", updateObj},
-        },
-        &opt,
-    )
-    defer cancel()
-
-    if err != nil {
-        log.Panic(err)
-        return
-    }
-
-    return
-}
\ No newline at end of file
+// TokenRevoked reports whether a token was issued before the user's tokens were revoked.
+func TokenRevoked(claims *SignedDetails, user models.User) bool {
+    return user.Tokens_valid_after != nil && claims.IssuedAt < user.Tokens_valid_after.Unix()
+}
@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"social-media-api/config"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func useTestSecret(t *testing.T) {
+	t.Helper()
+	config.App.SecretKey = "test-secret"
+	t.Cleanup(func() { config.App = config.Defaults() })
+}
+
+func TestValidateTokenAcceptsSessionTokens(t *testing.T) {
+	useTestSecret(t)
+	token, refreshToken, err := GenerateAllTokens("ada@example.com", "Ada", "Lovelace", "uid", "", "sid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, msg := ValidateToken(token)
+	if msg != "" || claims.Type != TokenAccess || claims.Sid != "sid" {
+		t.Errorf("access token: got %+v, %q", claims, msg)
+	}
+	claims, msg = ValidateToken(refreshToken)
+	if msg != "" || claims.Type != TokenRefresh {
+		t.Errorf("refresh token: got %+v, %q", claims, msg)
+	}
+}
+
+func TestValidateTokenRejectsOtherTokens(t *testing.T) {
+	useTestSecret(t)
+	sign := func(claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.App.SecretKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	expiry := time.Now().Add(time.Hour).Unix()
+
+	for _, purpose := range []string{PurposeMFALogin, PurposeVerifyEmail, PurposeResetPassword, PurposeChangeEmail, PurposeDataExport} {
+		token, err := GenerateActionToken(purpose, "uid", "ada@example.com", NewNonce(), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, msg := ValidateToken(token); msg == "" {
+			t.Errorf("%s token was accepted as a session token", purpose)
+		}
+	}
+
+	tokens := map[string]string{
+		"untyped":     sign(&SignedDetails{Uid: "uid", Sid: "sid", StandardClaims: jwt.StandardClaims{ExpiresAt: expiry}}),
+		"sessionless": sign(&SignedDetails{Uid: "uid", Type: TokenAccess, StandardClaims: jwt.StandardClaims{ExpiresAt: expiry}}),
+		"expired":     sign(&SignedDetails{Uid: "uid", Sid: "sid", Type: TokenAccess, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()}}),
+		"garbage":     "not.a.token",
+	}
+	for name, token := range tokens {
+		if claims, msg := ValidateToken(token); msg == "" || claims != nil {
+			t.Errorf("%s token: got %+v, %q", name, claims, msg)
+		}
+	}
+}
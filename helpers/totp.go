@@ -0,0 +1,120 @@
+package helpers
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha1"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base32"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+
+    "social-media-api/config"
+)
+
+// TOTP parameters from RFC 6238 as understood by common authenticator apps.
+const (
+    totpPeriod = 30
+    totpDigits = 6
+    totpSkew   = 1
+
+    recoveryCodeCount = 10
+)
+
+// NewTOTPSecret returns a random 160-bit secret, base32 encoded without padding.
+func NewTOTPSecret() string {
+    b := make([]byte, 20)
+    if _, err := rand.Read(b); err != nil {
+        panic(err)
+    }
+    return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that authenticator apps read
+// from a QR code.
+func TOTPProvisioningURI(account string, secret string) string {
+    issuer := config.App.TOTPIssuer
+
+    query := url.Values{}
+    query.Set("secret", secret)
+    query.Set("issuer", issuer)
+    query.Set("algorithm", "SHA1")
+    query.Set("digits", fmt.Sprintf("%d", totpDigits))
+    query.Set("period", fmt.Sprintf("%d", totpPeriod))
+
+    label := url.PathEscape(issuer + ":" + account)
+    return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TOTPStep returns the time step a moment falls into.
+func TOTPStep(t time.Time) int64 {
+    return t.Unix() / totpPeriod
+}
+
+func totpCode(secret string, step int64) (string, error) {
+    key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+    if err != nil {
+        return "", err
+    }
+
+    var msg [8]byte
+    binary.BigEndian.PutUint64(msg[:], uint64(step))
+    mac := hmac.New(sha1.New, key)
+    mac.Write(msg[:])
+    sum := mac.Sum(nil)
+
+    offset := sum[len(sum)-1] & 0x0f
+    value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+    return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// ValidateTOTP checks a code against the secret, allowing one step of clock
+// drift either way. It returns the matched step so callers can refuse to
+// accept the same code twice.
+func ValidateTOTP(secret string, code string, now time.Time) (int64, bool) {
+    code = strings.TrimSpace(code)
+    if len(code) != totpDigits {
+        return 0, false
+    }
+
+    current := TOTPStep(now)
+    for step := current - totpSkew; step <= current+totpSkew; step++ {
+        expected, err := totpCode(secret, step)
+        if err != nil {
+            return 0, false
+        }
+        if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+            return step, true
+        }
+    }
+    return 0, false
+}
+
+// NewRecoveryCodes returns a fresh set of one-time recovery codes together
+// with the hashes that are stored in place of the codes.
+func NewRecoveryCodes() (codes []string, hashes []string) {
+    for i := 0; i < recoveryCodeCount; i++ {
+        b := make([]byte, 5)
+        if _, err := rand.Read(b); err != nil {
+            panic(err)
+        }
+        code := hex.EncodeToString(b)
+        code = code[:5] + "-" + code[5:]
+        codes = append(codes, code)
+        hashes = append(hashes, HashRecoveryCode(code))
+    }
+    return codes, hashes
+}
+
+// HashRecoveryCode hashes a recovery code for storage and lookup. The codes
+// are random, so a fast hash is enough.
+func HashRecoveryCode(code string) string {
+    code = strings.ToLower(strings.TrimSpace(code))
+    sum := sha256.Sum256([]byte(code))
+    return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPAcceptsRFC6238Vectors(t *testing.T) {
+	// the RFC lists eight digit codes; six digit codes are their last six
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+		if !ok {
+			t.Errorf("%d: code %s was refused", tt.unix, tt.code)
+			continue
+		}
+		if step != TOTPStep(now) {
+			t.Errorf("%d: got step %d, want %d", tt.unix, step, TOTPStep(now))
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOneStepOfDrift(t *testing.T) {
+	// 1111111111 is step 37037037, whose code is 050471
+	issued := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		offset time.Duration
+		ok     bool
+	}{
+		{"same step", 0, true},
+		{"one step later", 30 * time.Second, true},
+		{"one step earlier", -30 * time.Second, true},
+		{"two steps later", 60 * time.Second, false},
+		{"two steps earlier", -60 * time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, "050471", issued.Add(tt.offset))
+			if ok != tt.ok {
+				t.Fatalf("got ok %v, want %v", ok, tt.ok)
+			}
+			if ok && step != TOTPStep(issued) {
+				t.Errorf("got step %d, want the step the code belongs to, %d", step, TOTPStep(issued))
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReportsTheStepOfAReplayedCode(t *testing.T) {
+	// callers refuse a step they have already seen, so a code replayed
+	// within the drift window must report the step it was first used in
+	issued := time.Unix(1111111111, 0)
+	first, ok := ValidateTOTP(rfc6238Secret, "050471", issued)
+	if !ok {
+		t.Fatal("code was refused")
+	}
+	replayed, ok := ValidateTOTP(rfc6238Secret, "050471", issued.Add(30*time.Second))
+	if !ok {
+		t.Fatal("code was refused one step later")
+	}
+	if replayed != first {
+		t.Errorf("replayed code matched step %d, want %d", replayed, first)
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfc6238Secret, "123456"},
+		{"too short", rfc6238Secret, "50471"},
+		{"eight digits", rfc6238Secret, "14050471"},
+		{"empty", rfc6238Secret, ""},
+		{"invalid secret", "not base32!", "050471"},
+	}
+	for _, tt := range tests {
+		if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+			t.Errorf("%s: code %q was accepted", tt.name, tt.code)
+		}
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, " 050471 ", now); !ok {
+		t.Error("surrounding spaces were not ignored")
+	}
+}
@@ -0,0 +1,155 @@
+// Package logging sets up the structured JSON logger of the API. Every line
+// logged with a request's context carries its trace and request IDs and,
+// once the caller is authenticated, their uid. Attributes that hold secrets are
+// redacted and email addresses are masked before anything is written.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// level is the minimum level written. main sets it from the configuration
+// once that is loaded.
+var level slog.LevelVar
+
+// The logger is installed as slog's default when the package is
+// initialised, so the standard log package and everything that runs while
+// other packages initialise log JSON too.
+func init() {
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       &level,
+		ReplaceAttr: redact,
+	})
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// SetLevel sets the minimum level written to one of debug, info, warn or
+// error. config validates the name, so an unknown one is ignored.
+func SetLevel(name string) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err == nil {
+		level.Set(l)
+	}
+}
+
+// secretKeys are attribute keys whose values are never written.
+var secretKeys = map[string]bool{
+	"password":         true,
+	"new_password":     true,
+	"current_password": true,
+	"token":            true,
+	"refresh_token":    true,
+	"mfa_token":        true,
+	"api_key":          true,
+	"secret":           true,
+	"totp_secret":      true,
+	"code":             true,
+	"recovery_code":    true,
+	"authorization":    true,
+	"cookie":           true,
+}
+
+// urlSecret matches the query parameters that carry tokens in the links the
+// API emails, such as /reset-password?token=...
+var urlSecret = regexp.MustCompile(`([?&](?:token|code)=)[^&#\s]+`)
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(a.Key, "[REDACTED]")
+	case key == "email" || key == "to":
+		return slog.String(a.Key, MaskEmail(a.Value.String()))
+	case a.Value.Kind() == slog.KindString:
+		return slog.String(a.Key, RedactURLSecrets(a.Value.String()))
+	}
+	return a
+}
+
+// RedactURLSecrets hides the token and code query parameters of the URLs in
+// text, which would otherwise let anyone reading the logs use the link.
+func RedactURLSecrets(text string) string {
+	if !strings.Contains(text, "token=") && !strings.Contains(text, "code=") {
+		return text
+	}
+	return urlSecret.ReplaceAllString(text, "${1}[REDACTED]")
+}
+
+// MaskEmail keeps the first letter and the domain of an email address, so
+// log lines can still be told apart without naming the user.
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "[REDACTED]"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+type requestKey struct{}
+
+// requestInfo is shared by everything that runs for one request. The uid
+// is only known after authentication, so it is filled in later.
+type requestInfo struct {
+	id  string
+	uid atomic.Value
+}
+
+// WithRequestID returns a context whose log lines carry requestID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &requestInfo{id: requestID})
+}
+
+// RequestID returns the request ID of ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// SetUID records the authenticated caller of the request ctx belongs to.
+func SetUID(ctx context.Context, uid string) {
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		info.uid.Store(uid)
+	}
+}
+
+// contextHandler adds the trace, request ID and uid of the context to each
+// record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	if info, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		r.AddAttrs(slog.String("request_id", info.id))
+		if uid, _ := info.uid.Load().(string); uid != "" {
+			r.AddAttrs(slog.String("uid", uid))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -1,10 +1,27 @@
 package main
 
 import (
+    "context"
+    "errors"
+    "log/slog"
+    "net/http"
     "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
 
-    // middleware "practice/social-media-api/middleware"
+    "social-media-api/config"
+    controller "social-media-api/controllers"
+    "social-media-api/database"
+    helper "social-media-api/helpers"
+    "social-media-api/logging"
+    "social-media-api/migrations"
+    "social-media-api/repository"
+
+    "social-media-api/middleware"
     routes "social-media-api/routes"
+    "social-media-api/tracing"
 
     "github.com/gin-gonic/gin"
     "github.com/swaggo/gin-swagger"
@@ -13,24 +30,113 @@ import (
 )
 
 func main() {
-    port := os.Getenv("PORT")
+    config.MustLoad()
+    logging.SetLevel(config.App.LogLevel)
+    database.Client = database.DBinstance()
+    helper.Mail = helper.NewMailer(config.App)
+    helper.OIDCProviders = helper.NewOIDCProviders(config.App.OIDCProviders)
+    port := config.App.Port
 
-    if port == "" {
-        port = "8000"
+    if err := migrations.Run(context.Background(), database.OpenDatabase(database.Client)); err != nil {
+        logging.Fatal("migrating the database failed", "error", err)
+    }
+    if err := database.EnsureIndexes(database.Client); err != nil {
+        logging.Fatal("creating database indexes failed, remove duplicate users and restart", "error", err)
     }
-    
+    controller.MarkIndexesEnsured()
+
+    shutdownTracing, err := tracing.Setup(context.Background())
+    if err != nil {
+        logging.Fatal("setting up tracing failed", "error", err)
+    }
+
+    // SIGINT or SIGTERM stops the background jobs and drains the server
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    repos := repository.NewMongoRepositories(database.OpenDatabase(database.Client))
+
+    var jobs sync.WaitGroup
+    jobs.Add(2)
+    go func() {
+        defer jobs.Done()
+        controller.RunAccountDeletion(ctx, repos)
+    }()
+    go func() {
+        defer jobs.Done()
+        controller.RunDataExports(ctx, repos)
+    }()
+
     //router
     router := gin.New()
-    router.Use(gin.Logger())
-    routes.AuthRoutes(router)
-    routes.PostRoutes(router)
-    routes.CommentRoutes(router)
-    routes.LikeRoutes(router)
+    // the login and sign-up throttles key on the client IP, which must not
+    // come from a header any client can set
+    if err := router.SetTrustedProxies(config.App.TrustedProxies); err != nil {
+        logging.Fatal("setting the trusted proxies failed", "error", err)
+    }
+    router.Use(middleware.RequestID())
+    router.Use(middleware.Tracing())
+    router.Use(middleware.RequestLogger())
+    router.Use(middleware.Metrics())
+    router.Use(middleware.Recovery())
+    router.HandleMethodNotAllowed = true
+    router.NoRoute(func(c *gin.Context) {
+        helper.RespondError(c, http.StatusNotFound, "no route matches "+c.Request.URL.Path)
+    })
+    router.NoMethod(func(c *gin.Context) {
+        helper.RespondError(c, http.StatusMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path)
+    })
+    routes.HealthRoutes(router)
+    routes.MetricsRoutes(router)
+    routes.AuthRoutes(router, repos)
+    routes.PostRoutes(router, repos)
+    routes.CommentRoutes(router, repos)
+    routes.LikeRoutes(router, repos)
+    routes.FollowRoutes(router, repos)
+    routes.ConversationRoutes(router, repos)
+    routes.BlockRoutes(router, repos)
+    routes.ReportRoutes(router, repos)
+    routes.AdminRoutes(router, repos)
+    routes.APIKeyRoutes(router, repos)
 
     //swagger
     docs.SwaggerInfo.BasePath = "/"
     router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-    
 
-    router.Run(":" + port)
+    server := &http.Server{
+        Addr:              ":" + port,
+        Handler:           router,
+        ReadHeaderTimeout: 10 * time.Second,
+    }
+    go func() {
+        if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+            logging.Fatal("serving HTTP failed", "error", err)
+        }
+    }()
+
+    <-ctx.Done()
+    stop()
+    controller.MarkShuttingDown()
+
+    // /readyz now fails; keep serving while load balancers notice and stop
+    // sending new requests, so none of them reach a closed listener
+    slog.Info("shutting down, draining traffic", "delay", config.App.ShutdownDrainDelay.String())
+    time.Sleep(config.App.ShutdownDrainDelay)
+    slog.Info("shutting down, waiting for requests to finish", "timeout", config.App.ShutdownTimeout.String())
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), config.App.ShutdownTimeout)
+    defer cancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        slog.Warn("requests still running at shutdown were cut off", "error", err)
+    }
+    if !controller.WaitForBackground(shutdownCtx) {
+        slog.Warn("work started by requests was cut off at shutdown")
+    }
+    jobs.Wait()
+    if err := database.Client.Disconnect(shutdownCtx); err != nil {
+        slog.Error("disconnecting from MongoDB failed", "error", err)
+    }
+    if err := shutdownTracing(shutdownCtx); err != nil {
+        slog.Error("flushing traces failed", "error", err)
+    }
 }
\ No newline at end of file
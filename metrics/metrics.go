@@ -0,0 +1,106 @@
+// Package metrics defines the Prometheus metrics of the API: HTTP traffic,
+// MongoDB commands and connection pool, and domain events. They are
+// registered with the default registry, which also exports the Go runtime
+// and process metrics, and served on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "social_media"
+
+var (
+	// HTTPRequests counts finished requests by method, route template and status.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes how long requests took by method, route template and status.
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to answer HTTP requests by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// MongoDuration observes MongoDB commands by collection, command and outcome.
+	MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongodb",
+		Name:      "command_duration_seconds",
+		Help:      "Time taken by MongoDB commands by collection, command name and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"collection", "command", "outcome"})
+
+	// MongoPoolConnections is the number of open connections to MongoDB.
+	MongoPoolConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "mongodb",
+		Name:      "pool_connections",
+		Help:      "Open connections in the MongoDB connection pool.",
+	})
+
+	// MongoPoolInUse is the number of connections checked out of the pool.
+	MongoPoolInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "mongodb",
+		Name:      "pool_connections_in_use",
+		Help:      "MongoDB connections currently checked out of the pool.",
+	})
+
+	// MongoPoolCheckoutFailures counts failed attempts to get a connection.
+	MongoPoolCheckoutFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mongodb",
+		Name:      "pool_checkout_failures_total",
+		Help:      "Failed attempts to check a connection out of the MongoDB pool by reason.",
+	}, []string{"reason"})
+
+	// Signups counts accounts created, by password or through a login provider.
+	Signups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signups_total",
+		Help:      "Accounts created by sign-up method.",
+	}, []string{"method"})
+
+	// Logins counts logins that started a session.
+	Logins = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Logins that started a session.",
+	})
+
+	// FailedLogins counts logins refused for a wrong password or code.
+	FailedLogins = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_logins_total",
+		Help:      "Logins refused because of a wrong password or authentication code.",
+	})
+
+	// PostsCreated counts posts created.
+	PostsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Posts created.",
+	})
+
+	// CommentsCreated counts comments created.
+	CommentsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comments_created_total",
+		Help:      "Comments created.",
+	})
+
+	// LikesCreated counts likes created.
+	LikesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "likes_created_total",
+		Help:      "Likes created.",
+	})
+)
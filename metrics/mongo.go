@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolMonitor keeps the connection pool gauges up to date.
+func PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				MongoPoolConnections.Inc()
+			case event.ConnectionClosed:
+				MongoPoolConnections.Dec()
+			case event.GetSucceeded:
+				MongoPoolInUse.Inc()
+			case event.ConnectionReturned:
+				MongoPoolInUse.Dec()
+			case event.GetFailed:
+				MongoPoolCheckoutFailures.WithLabelValues(e.Reason).Inc()
+			}
+		},
+	}
+}
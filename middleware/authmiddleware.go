@@ -1,28 +1,185 @@
 package middleware
 
 import (
+    "context"
+    "errors"
     "fmt"
+    "log/slog"
     "net/http"
+    "strings"
+    "time"
 
+    "social-media-api/config"
     helper "social-media-api/helpers"
+    "social-media-api/logging"
+    "social-media-api/models"
+    "social-media-api/repository"
 
     "github.com/gin-gonic/gin"
+    "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Authz validates token and authorizes users
-func Authentication() gin.HandlerFunc {
+// sessionTouchInterval limits how often last_seen_at is written for a busy session.
+const sessionTouchInterval = time.Minute
+
+// apiKeyTouchInterval limits how often last_used_at is written for a busy key.
+const apiKeyTouchInterval = time.Minute
+
+// Auth authenticates requests against the accounts, sessions and API keys
+// of the repositories it is given.
+type Auth struct {
+    Users    repository.UserRepository
+    Sessions repository.SessionRepository
+    APIKeys  repository.APIKeyRepository
+}
+
+// NewAuth returns an Auth using repos.
+func NewAuth(repos repository.Repositories) *Auth {
+    return &Auth{Users: repos.Users, Sessions: repos.Sessions, APIKeys: repos.APIKeys}
+}
+
+// apiKeyFromRequest returns the API key sent in the X-API-Key header, or in
+// the token header in place of a JWT.
+func apiKeyFromRequest(c *gin.Context) string {
+    if key := c.Request.Header.Get("X-API-Key"); key != "" {
+        return key
+    }
+    if token := c.Request.Header.Get("token"); helper.IsAPIKey(token) {
+        return token
+    }
+    return ""
+}
+
+// validateAPIKey looks up an API key and checks that it is neither revoked
+// nor expired. Successful lookups record when the key was last used.
+func (a *Auth) validateAPIKey(ctx context.Context, key string) (models.APIKey, error) {
+    apiKey, err := a.APIKeys.FindByHash(ctx, helper.HashAPIKey(key))
+    if err != nil {
+        return apiKey, errors.New("the API key is invalid")
+    }
+    if apiKey.Revoked_at != nil {
+        return apiKey, errors.New("the API key has been revoked")
+    }
+    now := time.Now()
+    if now.After(apiKey.Expires_at) {
+        return apiKey, errors.New("the API key has expired")
+    }
+
+    if apiKey.Last_used_at == nil || now.Sub(*apiKey.Last_used_at) > apiKeyTouchInterval {
+        if err := a.APIKeys.Touch(ctx, apiKey.ID, now); err != nil {
+            slog.ErrorContext(ctx, "recording API key use failed", "api_key_prefix", apiKey.Prefix, "error", err)
+        }
+    }
+    return apiKey, nil
+}
+
+// checkSession reports whether the session a token belongs to is still
+// active, recording that it was just seen.
+func (a *Auth) checkSession(ctx context.Context, sessionId string, userId primitive.ObjectID, ip string) bool {
+    id, err := primitive.ObjectIDFromHex(sessionId)
+    if err != nil {
+        return false
+    }
+
+    now := time.Now()
+    session, err := a.Sessions.FindActive(ctx, id, userId, now)
+    if err != nil {
+        return false
+    }
+
+    if now.Sub(session.Last_seen_at) > sessionTouchInterval {
+        if err := a.Sessions.Touch(ctx, id, ip, now); err != nil {
+            slog.ErrorContext(ctx, "recording session activity failed", "session_id", sessionId, "error", err)
+        }
+    }
+    return true
+}
+
+// authenticateAPIKey identifies the owner of an API key. The key must carry
+// every scope the route asks for, and it acts with the permissions of a
+// regular user whatever the owner's role. It returns an error message and
+// status when the key may not be used.
+func (a *Auth) authenticateAPIKey(c *gin.Context, key string, scopes []string) (int, string) {
+    if len(scopes) == 0 {
+        return http.StatusForbidden, "API keys cannot be used for this endpoint"
+    }
+
+    ctx, cancel := context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+    defer cancel()
+
+    apiKey, err := a.validateAPIKey(ctx, key)
+    if err != nil {
+        return http.StatusUnauthorized, err.Error()
+    }
+    for _, scope := range scopes {
+        if !helper.HasScope(apiKey, scope) {
+            return http.StatusForbidden, fmt.Sprintf("the API key is missing the %s scope", scope)
+        }
+    }
+
+    account, lookupErr := a.Users.FindByID(ctx, apiKey.User_ID.Hex())
+    if lookupErr != nil {
+        return http.StatusUnauthorized, "the API key is invalid"
+    }
+    if restriction := helper.AccountRestriction(account); restriction != "" {
+        return http.StatusForbidden, restriction
+    }
+
+    c.Set("email", *account.Email)
+    c.Set("first_name", *account.First_name)
+    c.Set("last_name", *account.Last_name)
+    c.Set("uid", account.User_id)
+    logging.SetUID(c.Request.Context(), account.User_id)
+    c.Set("role", models.RoleUser)
+    c.Set("email_verified", account.Email_verified)
+    c.Set("api_key_id", apiKey.ID.Hex())
+    c.Set("scopes", strings.Join(apiKey.Scopes, " "))
+    return 0, ""
+}
+
+// Authz validates token and authorizes users. API keys are only accepted on
+// routes that name the scopes they need; a logged in session has every scope.
+func (a *Auth) Authentication(scopes ...string) gin.HandlerFunc {
     return func(c *gin.Context) {
+        if key := apiKeyFromRequest(c); key != "" {
+            if status, msg := a.authenticateAPIKey(c, key, scopes); status != 0 {
+                helper.RespondError(c, status, msg)
+                return
+            }
+            c.Next()
+            return
+        }
+
         clientToken := c.Request.Header.Get("token")
         if clientToken == "" {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("No Authorization header provided")})
-            c.Abort()
+            helper.RespondError(c, http.StatusUnauthorized, "No Authorization header provided")
             return
         }
 
         claims, err := helper.ValidateToken(clientToken)
         if err != "" {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-            c.Abort()
+            helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, err)
+            return
+        }
+        if claims.Type != helper.TokenAccess {
+            helper.RespondError(c, http.StatusUnauthorized, "a refresh token cannot be used to authenticate")
+            return
+        }
+
+        ctx, cancel := context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+        defer cancel()
+
+        account, lookupErr := a.Users.FindByID(ctx, claims.Uid)
+        if lookupErr != nil || helper.TokenRevoked(claims, account) {
+            helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, "the token is no longer valid")
+            return
+        }
+        if !a.checkSession(ctx, claims.Sid, account.ID, c.ClientIP()) {
+            helper.RespondErrorCode(c, http.StatusUnauthorized, helper.CodeInvalidToken, "the session has been signed out")
+            return
+        }
+        if restriction := helper.AccountRestriction(account); restriction != "" {
+            helper.RespondErrorCode(c, http.StatusForbidden, helper.CodeAccountRestricted, restriction)
             return
         }
 
@@ -30,8 +187,61 @@ func Authentication() gin.HandlerFunc {
         c.Set("first_name", claims.First_name)
         c.Set("last_name", claims.Last_name)
         c.Set("uid", claims.Uid)
+        logging.SetUID(c.Request.Context(), claims.Uid)
+        // the stored role, so a demotion takes effect at once
+        c.Set("role", helper.AccountRole(account))
+        c.Set("session_id", claims.Sid)
+        c.Set("email_verified", account.Email_verified)
+        c.Set("totp_enabled", account.Totp_enabled)
+        c.Set("totp_required", account.Totp_required)
 
         c.Next()
 
     }
-}
\ No newline at end of file
+}
+
+// OptionalAuthentication identifies the caller when a valid token is sent but
+// lets anonymous requests through, for public routes that tailor their output
+// to the logged in user. Suspended and banned users are treated as anonymous,
+// and so are API keys without the given scopes.
+func (a *Auth) OptionalAuthentication(scopes ...string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if key := apiKeyFromRequest(c); key != "" {
+            // a key that is refused sets nothing, leaving the caller anonymous
+            a.authenticateAPIKey(c, key, scopes)
+            c.Next()
+            return
+        }
+
+        clientToken := c.Request.Header.Get("token")
+        if clientToken != "" {
+            claims, err := helper.ValidateToken(clientToken)
+            if err == "" && claims.Type == helper.TokenAccess {
+                ctx, cancel := context.WithTimeout(c.Request.Context(), config.App.DBTimeout)
+                defer cancel()
+
+                account, lookupErr := a.Users.FindByID(ctx, claims.Uid)
+                if lookupErr != nil || helper.TokenRevoked(claims, account) || helper.AccountRestriction(account) != "" {
+                    c.Next()
+                    return
+                }
+                if !a.checkSession(ctx, claims.Sid, account.ID, c.ClientIP()) {
+                    c.Next()
+                    return
+                }
+                c.Set("email", claims.Email)
+                c.Set("first_name", claims.First_name)
+                c.Set("last_name", claims.Last_name)
+                c.Set("uid", claims.Uid)
+                logging.SetUID(c.Request.Context(), claims.Uid)
+                c.Set("role", helper.AccountRole(account))
+                c.Set("session_id", claims.Sid)
+                c.Set("email_verified", account.Email_verified)
+                c.Set("totp_enabled", account.Totp_enabled)
+                c.Set("totp_required", account.Totp_required)
+            }
+        }
+
+        c.Next()
+    }
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"social-media-api/config"
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthenticationRejectsNonAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.App.SecretKey = "test-secret"
+	t.Cleanup(func() { config.App = config.Defaults() })
+
+	auth := NewAuth(repository.NewMemory().Repositories())
+	router := gin.New()
+	router.GET("/protected", auth.Authentication(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	mfaToken, err := helper.GenerateActionToken(helper.PurposeMFALogin, "64b7f0c2a1b2c3d4e5f60718", "ada@example.com", helper.NewNonce(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, refreshToken, err := helper.GenerateAllTokens("ada@example.com", "Ada", "Lovelace", "64b7f0c2a1b2c3d4e5f60718", "", "64b7f0c2a1b2c3d4e5f60719")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"mfa": mfaToken, "refresh": refreshToken, "missing": ""} {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		if token != "" {
+			req.Header.Set("token", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: got status %d, want 401: %s", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAuthenticationChecksAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemory()
+	now := time.Now()
+
+	email, first, last := "ada@example.com", "Ada", "Lovelace"
+	owner := models.User{ID: primitive.NewObjectID(), Email: &email, First_name: &first, Last_name: &last, Role: models.RoleAdmin}
+	owner.User_id = owner.ID.Hex()
+	banned := owner
+	banned.ID = primitive.NewObjectID()
+	banned.User_id = banned.ID.Hex()
+	banned.Banned = true
+	store.Users = append(store.Users, owner, banned)
+
+	newKey := func(user models.User, scopes []string, expires time.Time, revoked *time.Time) string {
+		key, prefix, hash := helper.NewAPIKey()
+		store.APIKeys = append(store.APIKeys, models.APIKey{
+			ID:         primitive.NewObjectID(),
+			User_ID:    user.ID,
+			Prefix:     prefix,
+			Key_hash:   hash,
+			Scopes:     scopes,
+			Expires_at: expires,
+			Revoked_at: revoked,
+			Created_at: now,
+			Updated_at: now,
+		})
+		return key
+	}
+	writer := newKey(owner, []string{models.ScopeReadPosts, models.ScopeWritePosts}, now.Add(time.Hour), nil)
+	reader := newKey(owner, []string{models.ScopeReadPosts}, now.Add(time.Hour), nil)
+	poster := newKey(owner, []string{models.ScopeWritePosts}, now.Add(time.Hour), nil)
+	revoked := newKey(owner, []string{models.ScopeWritePosts}, now.Add(time.Hour), &now)
+	expired := newKey(owner, []string{models.ScopeWritePosts}, now.Add(-time.Minute), nil)
+	bannedKey := newKey(banned, []string{models.ScopeWritePosts}, now.Add(time.Hour), nil)
+	unknown, _, _ := helper.NewAPIKey()
+
+	auth := NewAuth(store.Repositories())
+	router := gin.New()
+	respond := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"uid": c.GetString("uid"), "role": c.GetString("role")})
+	}
+	router.POST("/posts", auth.Authentication(models.ScopeWritePosts), respond)
+	router.GET("/posts", auth.OptionalAuthentication(models.ScopeReadPosts), respond)
+	router.GET("/users/me/api-keys", auth.Authentication(), respond)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		key    string
+		status int
+		uid    string
+	}{
+		{"scope granted", http.MethodPost, "/posts", "X-API-Key", writer, http.StatusOK, owner.User_id},
+		{"key in the token header", http.MethodPost, "/posts", "token", writer, http.StatusOK, owner.User_id},
+		{"missing scope", http.MethodPost, "/posts", "X-API-Key", reader, http.StatusForbidden, ""},
+		{"route without scopes", http.MethodGet, "/users/me/api-keys", "X-API-Key", writer, http.StatusForbidden, ""},
+		{"revoked key", http.MethodPost, "/posts", "X-API-Key", revoked, http.StatusUnauthorized, ""},
+		{"expired key", http.MethodPost, "/posts", "X-API-Key", expired, http.StatusUnauthorized, ""},
+		{"unknown key", http.MethodPost, "/posts", "X-API-Key", unknown, http.StatusUnauthorized, ""},
+		{"banned owner", http.MethodPost, "/posts", "X-API-Key", bannedKey, http.StatusForbidden, ""},
+		{"optional with the scope", http.MethodGet, "/posts", "X-API-Key", reader, http.StatusOK, owner.User_id},
+		{"optional without the scope is anonymous", http.MethodGet, "/posts", "X-API-Key", poster, http.StatusOK, ""},
+		{"optional with a revoked key is anonymous", http.MethodGet, "/posts", "X-API-Key", revoked, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(tt.header, tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var caller struct{ UID, Role string }
+			if err := json.Unmarshal(w.Body.Bytes(), &caller); err != nil {
+				t.Fatal(err)
+			}
+			if caller.UID != tt.uid {
+				t.Errorf("got uid %q, want %q", caller.UID, tt.uid)
+			}
+			// a key acts as a regular user, whatever its owner's role
+			if tt.uid != "" && caller.Role != models.RoleUser {
+				t.Errorf("got role %q, want %q", caller.Role, models.RoleUser)
+			}
+		})
+	}
+
+	if store.APIKeys[0].Last_used_at == nil {
+		t.Error("the use of the key was not recorded")
+	}
+}
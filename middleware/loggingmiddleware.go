@@ -0,0 +1,63 @@
+package middleware
+
+import (
+    "log/slog"
+    "regexp"
+    "time"
+
+    helper "social-media-api/helpers"
+    "social-media-api/logging"
+
+    "github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs accepted from clients to ones that are
+// safe to copy into logs and headers.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID gives every request an ID, keeping the one the client or a
+// proxy sent in X-Request-ID when it is usable. The ID is echoed in the
+// response and attached to the request context for logging.
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.Request.Header.Get(RequestIDHeader)
+        if !validRequestID.MatchString(id) {
+            id = helper.NewNonce()
+        }
+        c.Set("request_id", id)
+        c.Header(RequestIDHeader, id)
+        c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+        c.Next()
+    }
+}
+
+// RequestLogger writes one line per request. The query string is left out
+// because some links carry tokens in it.
+func RequestLogger() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+
+        status := c.Writer.Status()
+        level := slog.LevelInfo
+        switch {
+        case status >= 500:
+            level = slog.LevelError
+        case status >= 400:
+            level = slog.LevelWarn
+        }
+        slog.LogAttrs(c.Request.Context(), level, "request",
+            slog.String("method", c.Request.Method),
+            slog.String("path", c.Request.URL.Path),
+            slog.String("route", c.FullPath()),
+            slog.Int("status", status),
+            slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+            slog.Int("size", c.Writer.Size()),
+            slog.String("client_ip", c.ClientIP()),
+            slog.String("user_agent", c.Request.UserAgent()),
+        )
+    }
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+    "strconv"
+    "time"
+
+    "social-media-api/metrics"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Metrics counts and times every request. Requests are labelled with the
+// route template rather than the path, so IDs do not create new series;
+// requests that match no route share the "unmatched" label.
+func Metrics() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+
+        route := c.FullPath()
+        if route == "" {
+            route = "unmatched"
+        }
+        status := strconv.Itoa(c.Writer.Status())
+        metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+        metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+    }
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "runtime/debug"
+
+    helper "social-media-api/helpers"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic in a handler into a 500 error response and logs it
+// with the stack. http.ErrAbortHandler is passed on so the server can drop
+// the connection as the handler asked.
+func Recovery() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        defer func() {
+            recovered := recover()
+            if recovered == nil {
+                return
+            }
+            if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+                panic(recovered)
+            }
+            slog.ErrorContext(c.Request.Context(), "handler panicked",
+                "panic", fmt.Sprint(recovered),
+                "stack", string(debug.Stack()),
+            )
+            if c.Writer.Written() {
+                c.Abort()
+                return
+            }
+            helper.RespondError(c, http.StatusInternalServerError, "an unexpected error occurred")
+        }()
+        c.Next()
+    }
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	helper "social-media-api/helpers"
+	"social-media-api/models"
+)
+
+func TestRecoveryAnswersPanicsWithProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Recovery())
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != helper.ProblemContentType {
+		t.Errorf("got content type %q, want %q", got, helper.ProblemContentType)
+	}
+	var problem models.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	if problem.Status != http.StatusInternalServerError || problem.Code != helper.CodeInternal || problem.Instance != "/panic" {
+		t.Errorf("got %+v", problem)
+	}
+}
+
+func TestRecoveryPassesOnAbortHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Recovery())
+	router.GET("/abort", func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("got panic %v, want http.ErrAbortHandler", recovered)
+		}
+	}()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	t.Error("the request finished without a panic")
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+    "net/http"
+
+    helper "social-media-api/helpers"
+
+    "github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets callers whose token carries one of the given roles
+// through. Accounts an admin requires to use two-factor authentication are
+// refused until they enable it. It must run after Authentication.
+func RequireRole(roles ...string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        role := c.GetString("role")
+        for _, allowed := range roles {
+            if role == allowed {
+                if c.GetBool("totp_required") && !c.GetBool("totp_enabled") {
+                    helper.RespondError(c, http.StatusForbidden, "Two-factor authentication must be enabled to access this resource")
+                    return
+                }
+                c.Next()
+                return
+            }
+        }
+
+        helper.RespondError(c, http.StatusForbidden, "You do not have permission to access this resource")
+    }
+}
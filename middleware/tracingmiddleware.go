@@ -0,0 +1,53 @@
+package middleware
+
+import (
+    "net/http"
+
+    "social-media-api/tracing"
+
+    "github.com/gin-gonic/gin"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/propagation"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace
+// of an incoming traceparent header. Handlers that derive their contexts
+// from the request put their database spans under it.
+func Tracing() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+        route := c.FullPath()
+        name := c.Request.Method + " " + route
+        if route == "" {
+            name = c.Request.Method
+        }
+        ctx, span := tracing.Tracer().Start(ctx, name,
+            trace.WithSpanKind(trace.SpanKindServer),
+            trace.WithAttributes(
+                semconv.HTTPRequestMethodKey.String(c.Request.Method),
+                semconv.URLPath(c.Request.URL.Path),
+                semconv.HTTPRoute(route),
+                semconv.ClientAddress(c.ClientIP()),
+                semconv.UserAgentOriginal(c.Request.UserAgent()),
+            ),
+        )
+        defer span.End()
+
+        c.Request = c.Request.WithContext(ctx)
+        c.Next()
+
+        status := c.Writer.Status()
+        span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+        if uid := c.GetString("uid"); uid != "" {
+            span.SetAttributes(attribute.String("enduser.id", uid))
+        }
+        if status >= 500 {
+            span.SetStatus(codes.Error, http.StatusText(status))
+        }
+    }
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"social-media-api/config"
+	"social-media-api/database"
+)
+
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+)
+
+// fakeMongo speaks just enough of the MongoDB wire protocol for a client to
+// connect and run find: handshakes are answered as a standalone server and
+// every find returns no documents.
+func fakeMongo(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeMongo(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveFakeMongo(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var header [16]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return
+		}
+		length := binary.LittleEndian.Uint32(header[0:])
+		requestID := binary.LittleEndian.Uint32(header[4:])
+		opCode := binary.LittleEndian.Uint32(header[12:])
+		body := make([]byte, length-16)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		var command bson.Raw
+		switch opCode {
+		case opQuery:
+			// flags, then the collection name as a C string, skip and limit
+			name := 4
+			for body[name] != 0 {
+				name++
+			}
+			command = bson.Raw(body[name+1+8:])
+		case opMsg:
+			// flags, then a kind 0 section holding the command
+			command = bson.Raw(body[5:])
+		default:
+			return
+		}
+
+		reply, err := bson.Marshal(fakeMongoReply(command))
+		if err != nil {
+			return
+		}
+		var out []byte
+		if opCode == opQuery {
+			out = make([]byte, 16+20)
+			binary.LittleEndian.PutUint32(out[16+16:], 1)
+			binary.LittleEndian.PutUint32(out[12:], opReply)
+		} else {
+			out = make([]byte, 16+5)
+			binary.LittleEndian.PutUint32(out[12:], opMsg)
+		}
+		out = append(out, reply...)
+		binary.LittleEndian.PutUint32(out[0:], uint32(len(out)))
+		binary.LittleEndian.PutUint32(out[8:], requestID)
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func fakeMongoReply(command bson.Raw) bson.D {
+	first, err := command.IndexErr(0)
+	if err != nil {
+		return bson.D{{Key: "ok", Value: 0}}
+	}
+	switch first.Key() {
+	case "hello", "isMaster", "ismaster":
+		return bson.D{
+			{Key: "ismaster", Value: true},
+			{Key: "isWritablePrimary", Value: true},
+			{Key: "helloOk", Value: true},
+			{Key: "maxBsonObjectSize", Value: 16 * 1024 * 1024},
+			{Key: "maxMessageSizeBytes", Value: 48000000},
+			{Key: "maxWriteBatchSize", Value: 100000},
+			{Key: "localTime", Value: time.Now()},
+			{Key: "minWireVersion", Value: 0},
+			{Key: "maxWireVersion", Value: 21},
+			{Key: "ok", Value: 1.0},
+		}
+	case "find":
+		collection, _ := first.Value().StringValueOK()
+		db, _ := command.Lookup("$db").StringValueOK()
+		return bson.D{
+			{Key: "cursor", Value: bson.D{
+				{Key: "firstBatch", Value: bson.A{}},
+				{Key: "id", Value: int64(0)},
+				{Key: "ns", Value: db + "." + collection},
+			}},
+			{Key: "ok", Value: 1.0},
+		}
+	}
+	return bson.D{{Key: "ok", Value: 1.0}}
+}
+
+// recordSpans installs a tracer provider that records every span, and the
+// W3C propagator, until the test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func findSpan(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	t.Fatalf("no span %q among %q", name, names)
+	return nil
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, want attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTracingPutsMongoCommandsUnderTheRequestSpan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := recordSpans(t)
+	config.App.MongoURI = "mongodb://" + fakeMongo(t) + "/?directConnection=true&serverSelectionTimeoutMS=5000"
+	t.Cleanup(func() { config.App = config.Defaults() })
+
+	client := database.DBinstance()
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	router := gin.New()
+	router.Use(Tracing())
+	router.GET("/posts/:id", func(c *gin.Context) {
+		err := database.OpenCollection(client, "post").FindOne(c.Request.Context(), bson.M{"_id": c.Param("id")}).Err()
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("find returned %v", err)
+		}
+		c.Status(http.StatusNotFound)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/posts/abc", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	server := findSpan(t, spans, "GET /posts/:id")
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("request span kind is %v", server.SpanKind())
+	}
+	if server.SpanContext().TraceID().String() != traceID || server.Parent().SpanID().String() != parentID {
+		t.Errorf("request span did not continue the incoming trace: %v under %v", server.SpanContext(), server.Parent())
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("http.route", "/posts/:id"),
+		attribute.Int("http.response.status_code", http.StatusNotFound),
+	} {
+		if !hasAttribute(server, want) {
+			t.Errorf("request span lacks %v: %v", want, server.Attributes())
+		}
+	}
+
+	command := findSpan(t, spans, "find post")
+	if command.SpanKind() != trace.SpanKindClient {
+		t.Errorf("command span kind is %v", command.SpanKind())
+	}
+	if command.Parent().SpanID() != server.SpanContext().SpanID() || command.SpanContext().TraceID() != server.SpanContext().TraceID() {
+		t.Errorf("command span is not a child of the request span: %v under %v", command.SpanContext(), command.Parent())
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.collection.name", "post"),
+		attribute.String("db.operation.name", "find"),
+		attribute.String("db.namespace", config.App.MongoDatabase),
+	} {
+		if !hasAttribute(command, want) {
+			t.Errorf("command span lacks %v: %v", want, command.Attributes())
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+    "net/http"
+
+    "social-media-api/config"
+    helper "social-media-api/helpers"
+
+    "github.com/gin-gonic/gin"
+)
+
+// restricted reports whether accounts with an unverified email may not
+// perform action. UNVERIFIED_RESTRICTIONS lists the restricted actions out of
+// post, comment, like, follow, message and report; set it to none to lift
+// every restriction.
+func restricted(action string) bool {
+    for _, candidate := range config.App.UnverifiedRestrictions {
+        if candidate == action {
+            return true
+        }
+    }
+    return false
+}
+
+// RequireVerifiedEmail stops accounts with an unverified email from performing
+// action when it is restricted. It must run after Authentication.
+func RequireVerifiedEmail(action string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if restricted(action) && !c.GetBool("email_verified") {
+            helper.RespondError(c, http.StatusForbidden, "Please verify your email address before you " + action)
+            return
+        }
+
+        c.Next()
+    }
+}
@@ -0,0 +1,55 @@
+// Package migrations brings data written by older versions of the API up to
+// date. Each migration runs once per database, before the indexes are
+// created, and is recorded in the migration collection when it succeeds.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration changes existing documents. Run must be safe to repeat, because
+// a crash can stop it after some changes but before it is recorded.
+type Migration struct {
+	ID  string
+	Run func(ctx context.Context, db *mongo.Database) error
+}
+
+// All lists the migrations in the order they run. Append new ones at the end.
+var All = []Migration{
+	{ID: "2026-10-normalize-user-contacts", Run: normalizeUserContacts},
+	{ID: "2026-10-unset-stored-tokens", Run: unsetStoredTokens},
+}
+
+type record struct {
+	ID         string    `bson:"_id"`
+	Applied_at time.Time `bson:"applied_at"`
+}
+
+// Run applies the migrations of All that db has not seen yet.
+func Run(ctx context.Context, db *mongo.Database) error {
+	applied := db.Collection("migration")
+	for _, migration := range All {
+		err := applied.FindOne(ctx, bson.M{"_id": migration.ID}).Err()
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		slog.InfoContext(ctx, "running migration", "migration", migration.ID)
+		if err := migration.Run(ctx, db); err != nil {
+			return fmt.Errorf("migration %s: %w", migration.ID, err)
+		}
+		if _, err := applied.InsertOne(ctx, record{ID: migration.ID, Applied_at: time.Now()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
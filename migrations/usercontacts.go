@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	helper "social-media-api/helpers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userContact holds the fields of a user the contact migration looks at.
+type userContact struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Email      *string            `bson:"email"`
+	Phone      *string            `bson:"phone"`
+	Created_at time.Time          `bson:"created_at"`
+}
+
+// contactChange is the update one user needs. Conflicts are applied first,
+// because they free the values that other users are normalized to.
+type contactChange struct {
+	ID       primitive.ObjectID
+	Set      bson.M
+	Unset    bson.M
+	Conflict bool
+}
+
+// normalizeUserContacts stores every email in lowercase and every phone
+// number in E.164, as sign-up does since the unique indexes were added.
+// Older accounts may hold the same address or number, in which case the
+// oldest account keeps it. The newer ones get a placeholder email under the
+// reserved .invalid domain and no phone, and keep the original values in
+// conflicting_email and conflicting_phone for an admin to sort out.
+func normalizeUserContacts(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("user")
+	projection := options.Find().SetProjection(bson.M{"email": 1, "phone": 1, "created_at": 1})
+	cursor, err := users.Find(ctx, bson.M{}, projection)
+	if err != nil {
+		return err
+	}
+	var contacts []userContact
+	if err := cursor.All(ctx, &contacts); err != nil {
+		return err
+	}
+
+	for _, change := range planContactChanges(contacts) {
+		update := bson.M{}
+		if len(change.Set) > 0 {
+			update["$set"] = change.Set
+		}
+		if len(change.Unset) > 0 {
+			update["$unset"] = change.Unset
+		}
+		if _, err := users.UpdateByID(ctx, change.ID, update); err != nil {
+			return err
+		}
+		if change.Conflict {
+			slog.WarnContext(ctx, "user shares an email or phone number with an older account", "user_id", change.ID.Hex())
+		}
+	}
+	return nil
+}
+
+// planContactChanges works out the updates normalizeUserContacts applies.
+func planContactChanges(contacts []userContact) []contactChange {
+	sort.SliceStable(contacts, func(i, j int) bool {
+		if !contacts[i].Created_at.Equal(contacts[j].Created_at) {
+			return contacts[i].Created_at.Before(contacts[j].Created_at)
+		}
+		return contacts[i].ID.Hex() < contacts[j].ID.Hex()
+	})
+
+	emails := map[string]bool{}
+	phones := map[string]bool{}
+	var conflicts, normalized []contactChange
+	for _, user := range contacts {
+		change := contactChange{ID: user.ID, Set: bson.M{}, Unset: bson.M{}}
+
+		if user.Email != nil {
+			email := helper.NormalizeEmail(*user.Email)
+			switch {
+			case emails[email]:
+				change.Set["email"] = user.ID.Hex() + "@duplicate.invalid"
+				change.Set["conflicting_email"] = *user.Email
+				change.Conflict = true
+			case email != *user.Email:
+				change.Set["email"] = email
+			}
+			emails[email] = true
+		}
+
+		if user.Phone != nil && *user.Phone != "" {
+			// numbers that cannot be converted are left as they are
+			phone, err := helper.NormalizePhone(*user.Phone)
+			if err != nil {
+				phone = *user.Phone
+			}
+			switch {
+			case phones[phone]:
+				change.Unset["phone"] = ""
+				change.Set["conflicting_phone"] = *user.Phone
+				change.Conflict = true
+			case phone != *user.Phone:
+				change.Set["phone"] = phone
+			}
+			phones[phone] = true
+		}
+
+		switch {
+		case change.Conflict:
+			conflicts = append(conflicts, change)
+		case len(change.Set) > 0:
+			normalized = append(normalized, change)
+		}
+	}
+	return append(conflicts, normalized...)
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// unsetStoredTokens removes the tokens sign-up and login used to store on
+// the user. Sessions keep a hash of the refresh token instead, and nothing
+// reads the stored tokens back.
+func unsetStoredTokens(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"token": bson.M{"$exists": true}},
+		bson.M{"refresh_token": bson.M{"$exists": true}},
+	}}
+	update := bson.M{"$unset": bson.M{"token": "", "refresh_token": ""}}
+	_, err := db.Collection("user").UpdateMany(ctx, filter, update)
+	return err
+}
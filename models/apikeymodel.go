@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes an API key can be granted. A route that accepts API keys names the
+// scope it needs; routes that do not only accept logged in sessions.
+const (
+	ScopeReadPosts         = "read:posts"
+	ScopeWritePosts        = "write:posts"
+	ScopeWriteComments     = "write:comments"
+	ScopeWriteLikes        = "write:likes"
+	ScopeReadFollows       = "read:follows"
+	ScopeWriteFollows      = "write:follows"
+	ScopeReadMessages      = "read:messages"
+	ScopeWriteMessages     = "write:messages"
+	ScopeReadNotifications = "read:notifications"
+)
+
+type APIKey struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	User_ID      primitive.ObjectID `json:"user_id"`
+	Name         string             `json:"name"`
+	Prefix       string             `json:"prefix"`
+	Key_hash     string             `json:"-"`
+	Scopes       []string           `json:"scopes"`
+	Expires_at   time.Time          `json:"expires_at"`
+	Last_used_at *time.Time         `json:"last_used_at"`
+	Revoked_at   *time.Time         `json:"revoked_at"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+}
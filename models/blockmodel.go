@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Block struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Blocker_ID primitive.ObjectID `json:"blocker_id"`
+	Blocked_ID primitive.ObjectID `json:"blocked_id" validate:"required"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+}
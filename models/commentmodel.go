@@ -13,4 +13,5 @@ type Comment struct {
 	Description *string            `json:"description" validate:"required"`
 	Created_at  time.Time          `json:"created_at"`
 	Updated_at  time.Time          `json:"updated_at"`
+	Hidden      bool               `json:"hidden"`
 }
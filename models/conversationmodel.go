@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Conversation struct {
+	ID              primitive.ObjectID   `bson:"_id"`
+	Name            *string              `json:"name"`
+	Participants    []primitive.ObjectID `json:"participants" validate:"required,min=2,max=10"`
+	Is_group        bool                 `json:"is_group"`
+	Created_by      primitive.ObjectID   `json:"created_by"`
+	Last_message_at time.Time            `json:"last_message_at"`
+	Created_at      time.Time            `json:"created_at"`
+	Updated_at      time.Time            `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DeletionStatusScheduled = "scheduled"
+	DeletionStatusRestored  = "restored"
+	DeletionStatusCompleted = "completed"
+)
+
+// AccountDeletion is the audit record of an account deletion request. It
+// outlives the account and holds no personal data besides the user ID.
+type AccountDeletion struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	User_ID       primitive.ObjectID `json:"user_id"`
+	Status        string             `json:"status"`
+	Requested_at  time.Time          `json:"requested_at"`
+	Scheduled_for time.Time          `json:"scheduled_for"`
+	Restored_at   *time.Time         `json:"restored_at"`
+	Completed_at  *time.Time         `json:"completed_at"`
+	Removed       map[string]int64   `json:"removed"`
+	Anonymized    map[string]int64   `json:"anonymized"`
+	Created_at    time.Time          `json:"created_at"`
+	Updated_at    time.Time          `json:"updated_at"`
+}
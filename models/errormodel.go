@@ -0,0 +1,23 @@
+package models
+
+// Error is the body of every error response, a problem details object as
+// described in RFC 7807 and sent as application/problem+json. Code is a
+// stable, machine-readable name for the error; Detail is meant for people
+// and may change.
+type Error struct {
+	Type       string       `json:"type" example:"about:blank"`
+	Title      string       `json:"title" example:"Not Found"`
+	Status     int          `json:"status" example:"404"`
+	Code       string       `json:"code" example:"not_found"`
+	Detail     string       `json:"detail,omitempty" example:"Post not found"`
+	Instance   string       `json:"instance,omitempty" example:"/posts/65f1c0ffee0000000000abcd"`
+	Request_id string       `json:"request_id,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError explains why one field of the request was rejected.
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Code    string `json:"code" example:"email"`
+	Message string `json:"message" example:"must be a valid email address"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ExportStatusPending = "pending"
+	ExportStatusRunning = "running"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+	ExportStatusExpired = "expired"
+)
+
+// DataExport is a request for a copy of a user's data. The ZIP file is kept
+// in GridFS until Expires_at.
+type DataExport struct {
+	ID           primitive.ObjectID  `bson:"_id"`
+	User_ID      primitive.ObjectID  `json:"user_id"`
+	Status       string              `json:"status"`
+	Error        string              `json:"error,omitempty"`
+	File_ID      *primitive.ObjectID `json:"-"`
+	Size         int64               `json:"size"`
+	Download_url string              `bson:"-" json:"download_url,omitempty"`
+	Started_at   *time.Time          `json:"started_at"`
+	Completed_at *time.Time          `json:"completed_at"`
+	Expires_at   *time.Time          `json:"expires_at"`
+	Created_at   time.Time           `json:"created_at"`
+	Updated_at   time.Time           `json:"updated_at"`
+}
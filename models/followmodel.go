@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Follow struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Follower_ID  primitive.ObjectID `json:"follower_id"`
+	Following_ID primitive.ObjectID `json:"following_id" validate:"required"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusFailing = "failing"
+)
+
+// HealthCheck is the outcome of checking one dependency of the API.
+type HealthCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Latency_ms float64 `json:"latency_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// HealthReport is the answer of the health endpoints. Status is failing
+// when any check is.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity links a user to an account at an external OpenID Connect provider.
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	Linked_at time.Time `json:"linked_at"`
+}
+
+// OIDCState remembers an authorization request until the provider redirects
+// back, so the callback can check the state and nonce and send the PKCE verifier.
+type OIDCState struct {
+	State      string    `bson:"_id"`
+	Provider   string    `json:"provider"`
+	Nonce      string    `json:"nonce"`
+	Verifier   string    `json:"verifier"`
+	Expires_at time.Time `json:"expires_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+	LockoutScopeSignup  = "signup"
+)
+
+// LoginThrottle counts recent attempts for one account or client IP.
+type LoginThrottle struct {
+	Key          string     `bson:"_id" json:"key"`
+	Failures     int        `json:"failures"`
+	Last_failure time.Time  `json:"last_failure"`
+	Locked_until *time.Time `json:"locked_until"`
+}
+
+// LockoutEvent records every time an account or IP is locked out.
+type LockoutEvent struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Scope        string             `json:"scope"`
+	Subject      string             `json:"subject"`
+	Ip_address   string             `json:"ip_address"`
+	Failures     int                `json:"failures"`
+	Locked_until time.Time          `json:"locked_until"`
+	Created_at   time.Time          `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Message struct {
+	ID              primitive.ObjectID   `bson:"_id"`
+	Conversation_ID primitive.ObjectID   `json:"conversation_id" validate:"required"`
+	Sender_ID       primitive.ObjectID   `json:"sender_id" validate:"required"`
+	Body            *string              `json:"body" validate:"required,max=5000"`
+	Read_by         []primitive.ObjectID `json:"read_by"`
+	Created_at      time.Time            `json:"created_at"`
+	Updated_at      time.Time            `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ModerationDismiss = "dismiss"
+	ModerationHide    = "hide"
+	ModerationSuspend = "suspend"
+)
+
+type ModerationAction struct {
+	ID           primitive.ObjectID   `bson:"_id"`
+	Moderator_ID primitive.ObjectID   `json:"moderator_id"`
+	Target_type  string               `json:"target_type"`
+	Target_ID    primitive.ObjectID   `json:"target_id"`
+	Action       string               `json:"action"`
+	Note         *string              `json:"note"`
+	Report_IDs   []primitive.ObjectID `json:"report_ids"`
+	Created_at   time.Time            `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Mute struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Muter_ID   primitive.ObjectID `json:"muter_id"`
+	Muted_ID   primitive.ObjectID `json:"muted_id" validate:"required"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+}
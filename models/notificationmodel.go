@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Notification struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	User_ID      primitive.ObjectID `json:"user_id"`
+	Type         string             `json:"type"`
+	Message      string             `json:"message"`
+	Reference_ID primitive.ObjectID `json:"reference_id"`
+	Read         bool               `json:"read"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+}
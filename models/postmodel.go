@@ -7,11 +7,12 @@ import (
 )
 
 type Post struct {
-    ID            primitive.ObjectID `bson:"_id"`
-    Name          *string            `json:"name" validate:"required"`
-    Description   *string            `json:"description" validate:"required"`
-    Created_at    time.Time          `json:"created_at"`
-    Updated_at    time.Time          `json:"updated_at"`
-    User_id       primitive.ObjectID `json:"user_id"`
+    ID          primitive.ObjectID `bson:"_id"`
+    Name        *string            `json:"name" validate:"required"`
+    Description *string            `json:"description" validate:"required"`
+    Created_at  time.Time          `json:"created_at"`
+    Updated_at  time.Time          `json:"updated_at"`
+    User_id     primitive.ObjectID `json:"user_id"`
+    Hidden      bool               `json:"hidden"`
 }	
 
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusActioned  = "actioned"
+)
+
+type Report struct {
+	ID           primitive.ObjectID  `bson:"_id"`
+	Reporter_ID  primitive.ObjectID  `json:"reporter_id"`
+	Target_type  string              `json:"target_type" validate:"required,oneof=post comment user"`
+	Target_ID    primitive.ObjectID  `json:"target_id" validate:"required"`
+	Reason       string              `json:"reason" validate:"required,oneof=spam harassment hate_speech violence nudity misinformation other"`
+	Details      *string             `json:"details" validate:"omitempty,max=1000"`
+	Status       string              `json:"status"`
+	Action       string              `json:"action"`
+	Moderator_ID *primitive.ObjectID `json:"moderator_id"`
+	Decided_at   *time.Time          `json:"decided_at"`
+	Created_at   time.Time           `json:"created_at"`
+	Updated_at   time.Time           `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SanctionSuspend = "suspend"
+	SanctionBan     = "ban"
+)
+
+type Sanction struct {
+	ID            primitive.ObjectID  `bson:"_id"`
+	User_ID       primitive.ObjectID  `json:"user_id"`
+	Type          string              `json:"type"`
+	Reason        string              `json:"reason"`
+	Until         *time.Time          `json:"until"`
+	Issued_by     primitive.ObjectID  `json:"issued_by"`
+	Lifted_at     *time.Time          `json:"lifted_at"`
+	Lifted_by     *primitive.ObjectID `json:"lifted_by"`
+	Lifted_reason *string             `json:"lifted_reason"`
+	Created_at    time.Time           `json:"created_at"`
+	Updated_at    time.Time           `json:"updated_at"`
+}
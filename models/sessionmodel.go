@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one logged in device. Every token carries the ID of the
+// session it belongs to, and revoking the session signs the device out.
+type Session struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	User_ID            primitive.ObjectID `json:"user_id"`
+	User_agent         string             `json:"user_agent"`
+	Ip_address         string             `json:"ip_address"`
+	Refresh_token_hash string             `json:"-"`
+	Current            bool               `bson:"-" json:"current"`
+	Last_seen_at       time.Time          `json:"last_seen_at"`
+	Expires_at         time.Time          `json:"expires_at"`
+	Revoked_at         *time.Time         `json:"revoked_at"`
+	Created_at         time.Time          `json:"created_at"`
+	Updated_at         time.Time          `json:"updated_at"`
+}
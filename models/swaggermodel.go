@@ -6,12 +6,6 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type Error struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Error   error  `json:"error"`
-}
-
 type UserRegisterInput struct {
 	First_name *string `json:"first_name" validate:"required,min=2,max=100"`
 	Last_name  *string `json:"last_name" validate:"required,min=2,max=100"`
@@ -81,3 +75,241 @@ type LikeList struct {
 type CreateOutput struct {
 	InsertedID string
 }
+
+type FollowInput struct {
+	Following_ID primitive.ObjectID `json:"following_id" validate:"required"`
+}
+
+type FollowList struct {
+	Follows []Follow
+	Page    int `json:"page"`
+	Limit   int `json:"limit"`
+}
+
+type ConversationInput struct {
+	Participant_IDs []primitive.ObjectID `json:"participant_ids" validate:"required,min=1,max=9"`
+	Name            *string              `json:"name" validate:"omitempty,max=100"`
+}
+
+type ConversationOutput struct {
+	ID              primitive.ObjectID   `bson:"_id"`
+	Name            *string              `json:"name"`
+	Participants    []primitive.ObjectID `json:"participants"`
+	Is_group        bool                 `json:"is_group"`
+	Created_by      primitive.ObjectID   `json:"created_by"`
+	Last_message_at time.Time            `json:"last_message_at"`
+	Unread_count    int                  `json:"unread_count"`
+	Created_at      time.Time            `json:"created_at"`
+	Updated_at      time.Time            `json:"updated_at"`
+}
+
+type ConversationList struct {
+	Conversations []ConversationOutput
+	Page          int `json:"page"`
+	Limit         int `json:"limit"`
+}
+
+type MessageInput struct {
+	Body *string `json:"body" validate:"required,max=5000"`
+}
+
+type MessageList struct {
+	Messages    []Message
+	Next_cursor string `json:"next_cursor"`
+	Limit       int    `json:"limit"`
+}
+
+type MessageSettingsInput struct {
+	Dm_followers_only *bool `json:"dm_followers_only" validate:"required"`
+}
+
+type BlockInput struct {
+	Blocked_ID primitive.ObjectID `json:"blocked_id" validate:"required"`
+}
+
+type BlockList struct {
+	Blocks []Block
+	Page   int `json:"page"`
+	Limit  int `json:"limit"`
+}
+
+type MuteInput struct {
+	Muted_ID primitive.ObjectID `json:"muted_id" validate:"required"`
+}
+
+type MuteList struct {
+	Mutes []Mute
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+type ReportInput struct {
+	Target_type string             `json:"target_type" validate:"required,oneof=post comment user"`
+	Target_ID   primitive.ObjectID `json:"target_id" validate:"required"`
+	Reason      string             `json:"reason" validate:"required,oneof=spam harassment hate_speech violence nudity misinformation other"`
+	Details     *string            `json:"details" validate:"omitempty,max=1000"`
+}
+
+type ReportGroup struct {
+	Target_type       string               `json:"target_type"`
+	Target_ID         primitive.ObjectID   `json:"target_id"`
+	Report_count      int                  `json:"report_count"`
+	Reasons           []string             `json:"reasons"`
+	Report_IDs        []primitive.ObjectID `json:"report_ids"`
+	First_reported_at time.Time            `json:"first_reported_at"`
+	Last_reported_at  time.Time            `json:"last_reported_at"`
+}
+
+type ReportQueue struct {
+	Reports []ReportGroup
+	Page    int `json:"page"`
+	Limit   int `json:"limit"`
+}
+
+type ModerationDecisionInput struct {
+	Target_type  string             `json:"target_type" validate:"required,oneof=post comment user"`
+	Target_ID    primitive.ObjectID `json:"target_id" validate:"required"`
+	Action       string             `json:"action" validate:"required,oneof=dismiss hide suspend"`
+	Note         *string            `json:"note" validate:"omitempty,max=1000"`
+	Suspend_days int                `json:"suspend_days" validate:"omitempty,min=1,max=365"`
+}
+
+type NotificationList struct {
+	Notifications []Notification
+	Page          int `json:"page"`
+	Limit         int `json:"limit"`
+}
+
+type RoleInput struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+type SanctionInput struct {
+	Type           string `json:"type" validate:"required,oneof=suspend ban"`
+	Reason         string `json:"reason" validate:"required,max=1000"`
+	Duration_hours int    `json:"duration_hours" validate:"omitempty,min=1,max=87600"`
+}
+
+type LiftSanctionInput struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
+
+type SanctionList struct {
+	Sanctions []Sanction
+	Page      int `json:"page"`
+	Limit     int `json:"limit"`
+}
+
+type VerifyEmailInput struct {
+	Token *string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordInput struct {
+	Email *string `json:"email" validate:"email,required"`
+}
+
+type ResetPasswordInput struct {
+	Token    *string `json:"token" validate:"required"`
+	Password *string `json:"Password" validate:"required"`
+}
+
+type ChangePasswordInput struct {
+	Current_password *string `json:"current_password" validate:"required"`
+	New_password     *string `json:"new_password" validate:"required"`
+}
+
+type ChangeEmailInput struct {
+	Email    *string `json:"email" validate:"email,required"`
+	Password *string `json:"Password" validate:"required"`
+}
+
+type TokenOutput struct {
+	Token         string `json:"token"`
+	Refresh_token string `json:"refresh_token"`
+}
+
+type MFAChallengeOutput struct {
+	Mfa_required bool   `json:"mfa_required"`
+	Mfa_token    string `json:"mfa_token"`
+}
+
+type TwoFactorLoginInput struct {
+	Mfa_token     *string `json:"mfa_token" validate:"required"`
+	Code          string  `json:"code" validate:"omitempty,len=6,numeric"`
+	Recovery_code string  `json:"recovery_code" validate:"omitempty,max=32"`
+}
+
+type TwoFactorSetupOutput struct {
+	Secret           string `json:"secret"`
+	Provisioning_uri string `json:"provisioning_uri"`
+}
+
+type TwoFactorCodeInput struct {
+	Code *string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorDisableInput struct {
+	Password      *string `json:"Password" validate:"required"`
+	Code          string  `json:"code" validate:"omitempty,len=6,numeric"`
+	Recovery_code string  `json:"recovery_code" validate:"omitempty,max=32"`
+}
+
+type RecoveryCodesOutput struct {
+	Recovery_codes []string `json:"recovery_codes"`
+}
+
+type TwoFactorRequiredInput struct {
+	Required bool `json:"required"`
+}
+
+type LockoutList struct {
+	Lockouts []LockoutEvent
+	Page     int `json:"page"`
+	Limit    int `json:"limit"`
+}
+
+type APIKeyInput struct {
+	Name            string   `json:"name" validate:"required,max=100"`
+	Scopes          []string `json:"scopes" validate:"required,min=1,dive,oneof=read:posts write:posts write:comments write:likes read:follows write:follows read:messages write:messages read:notifications"`
+	Expires_in_days int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type APIKeyOutput struct {
+	Key     string `json:"key"`
+	Api_key APIKey `json:"api_key"`
+}
+
+type APIKeyList struct {
+	Api_keys []APIKey
+	Page     int `json:"page"`
+	Limit    int `json:"limit"`
+}
+
+type SessionList struct {
+	Sessions []Session
+	Page     int `json:"page"`
+	Limit    int `json:"limit"`
+}
+
+type RefreshTokenInput struct {
+	Refresh_token *string `json:"refresh_token" validate:"required"`
+}
+
+type AccountDeletionInput struct {
+	Password      *string `json:"Password" validate:"required"`
+	Code          string  `json:"code" validate:"omitempty,len=6,numeric"`
+	Recovery_code string  `json:"recovery_code" validate:"omitempty,max=32"`
+}
+
+type AccountRestoreInput struct {
+	Email         *string `json:"email" validate:"email,required"`
+	Password      *string `json:"Password" validate:"required"`
+	Code          string  `json:"code" validate:"omitempty,len=6,numeric"`
+	Recovery_code string  `json:"recovery_code" validate:"omitempty,max=32"`
+}
+
+type AccountDeletionList struct {
+	Account_deletions []AccountDeletion
+	Page              int `json:"page"`
+	Limit             int `json:"limit"`
+}
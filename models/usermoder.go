@@ -6,17 +6,43 @@ import (
     "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+    RoleUser      = "user"
+    RoleModerator = "moderator"
+    RoleAdmin     = "admin"
+)
+
 //User is the model that governs all notes objects retrived or inserted into the DB
 type User struct {
-    ID            primitive.ObjectID `bson:"_id"`
-    First_name    *string            `json:"first_name" validate:"required,min=2,max=100"`
-    Last_name     *string            `json:"last_name" validate:"required,min=2,max=100"`
-    Password      *string            `json:"Password" validate:"required"`
-    Email         *string            `json:"email" validate:"email,required"`
-    Phone         *string            `json:"phone" validate:"required"`
-    Token         *string            `json:"token"`
-    Refresh_token *string            `json:"refresh_token"`
-    Created_at    time.Time          `json:"created_at"`
-    Updated_at    time.Time          `json:"updated_at"`
-    User_id       string             `json:"user_id"`
+    ID                     primitive.ObjectID `bson:"_id"`
+    First_name             *string            `json:"first_name" validate:"required,min=2,max=100"`
+    Last_name              *string            `json:"last_name" validate:"required,min=2,max=100"`
+    Password               *string            `json:"Password" validate:"required"`
+    Email                  *string            `json:"email" validate:"email,required"`
+    Phone                  *string            `json:"phone" validate:"required"`
+    Token                  *string            `json:"token"`
+    Refresh_token          *string            `json:"refresh_token"`
+    Dm_followers_only      bool               `json:"dm_followers_only"`
+    Role                   string             `json:"role"`
+    Suspended_until        *time.Time         `json:"suspended_until"`
+    Banned                 bool               `json:"banned"`
+    Email_verified         bool               `json:"email_verified"`
+    Verification_nonce     *string            `json:"-"`
+    Verification_sent_at   *time.Time         `json:"verification_sent_at"`
+    Password_reset_nonce   *string            `json:"-"`
+    Password_reset_sent_at *time.Time         `json:"-"`
+    Tokens_valid_after     *time.Time         `json:"-"`
+    Pending_email          *string            `json:"pending_email"`
+    Email_change_nonce     *string            `json:"-"`
+    Totp_secret            *string            `json:"-"`
+    Totp_enabled           bool               `json:"totp_enabled"`
+    Totp_required          bool               `json:"totp_required"`
+    Totp_last_step         int64              `json:"-"`
+    Recovery_codes         []string           `json:"-"`
+    Identities             []Identity         `json:"identities"`
+    Deletion_scheduled_at  *time.Time         `json:"deletion_scheduled_at"`
+    Deletion_started_at    *time.Time         `json:"-"`
+    Created_at             time.Time          `json:"created_at"`
+    Updated_at             time.Time          `json:"updated_at"`
+    User_id                string             `json:"user_id"`
 }
\ No newline at end of file
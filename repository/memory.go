@@ -0,0 +1,1975 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Memory keeps every record in slices, in insertion order, and behaves
+// like the MongoDB repositories. Stored export files are kept by ID in
+// ExportFiles. Tests can fill the slices directly before handing
+// Repositories to the handlers, and read them afterwards.
+type Memory struct {
+	mu                sync.Mutex
+	Users             []models.User
+	Blocks            []models.Block
+	Mutes             []models.Mute
+	Sessions          []models.Session
+	APIKeys           []models.APIKey
+	Throttles         []models.LoginThrottle
+	Lockouts          []models.LockoutEvent
+	OIDCStates        []models.OIDCState
+	Posts             []models.Post
+	Comments          []models.Comment
+	Likes             []models.Like
+	Follows           []models.Follow
+	Conversations     []models.Conversation
+	Messages          []models.Message
+	Notifications     []models.Notification
+	Reports           []models.Report
+	ModerationActions []models.ModerationAction
+	Sanctions         []models.Sanction
+	Deletions         []models.AccountDeletion
+	Exports           []models.DataExport
+	ExportFiles       map[primitive.ObjectID][]byte
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Repositories returns repositories that share the store.
+func (m *Memory) Repositories() Repositories {
+	return Repositories{
+		Users:         memoryUsers{m},
+		Sessions:      memorySessions{m},
+		APIKeys:       memoryAPIKeys{m},
+		Throttles:     memoryThrottles{m},
+		OIDCStates:    memoryOIDCStates{m},
+		Posts:         memoryPosts{m},
+		Comments:      memoryComments{m},
+		Likes:         memoryLikes{m},
+		Follows:       memoryFollows{m},
+		Blocks:        memoryBlocks{m},
+		Mutes:         memoryMutes{m},
+		Conversations: memoryConversations{m},
+		Messages:      memoryMessages{m},
+		Notifications: memoryNotifications{m},
+		Reports:       memoryReports{m},
+		Sanctions:     memorySanctions{m},
+		Deletions:     memoryDeletions{m},
+		Exports:       memoryExports{m},
+	}
+}
+
+// toDocument converts v to the generic document MongoDB would return for it.
+func toDocument(v interface{}) bson.M {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isBanned reports whether userID belongs to a banned user. The caller must
+// hold m.mu.
+func (m *Memory) isBanned(userID primitive.ObjectID) bool {
+	for _, user := range m.Users {
+		if user.ID == userID {
+			return user.Banned
+		}
+	}
+	return false
+}
+
+func mayTouch(owner *primitive.ObjectID, userID primitive.ObjectID) bool {
+	return owner == nil || *owner == userID
+}
+
+// page returns the slice of n items that skip and limit select.
+func page(n int, skip int64, limit int64) (int, int) {
+	start := int(skip)
+	if start > n {
+		start = n
+	}
+	end := n
+	if limit > 0 && start+int(limit) < n {
+		end = start + int(limit)
+	}
+	return start, end
+}
+
+type memoryUsers struct{ m *Memory }
+
+// find returns the index of the first user accepted by match, or -1. The
+// caller holds the lock.
+func (r memoryUsers) find(match func(models.User) bool) int {
+	for i, user := range r.m.Users {
+		if match(user) {
+			return i
+		}
+	}
+	return -1
+}
+
+// clash returns the *DuplicateError the unique indexes would raise when
+// storing user at index i, which is -1 for a new user. The caller holds
+// the lock.
+func (r memoryUsers) clash(user models.User, i int) error {
+	for j, other := range r.m.Users {
+		if j == i {
+			continue
+		}
+		if user.Email != nil && other.Email != nil && *user.Email == *other.Email {
+			return &DuplicateError{Field: "email"}
+		}
+		if user.Phone != nil && other.Phone != nil && *user.Phone == *other.Phone {
+			return &DuplicateError{Field: "phone"}
+		}
+	}
+	return nil
+}
+
+func (r memoryUsers) findBy(match func(models.User) bool) (models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if i := r.find(match); i >= 0 {
+		return r.m.Users[i], nil
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (r memoryUsers) FindByID(ctx context.Context, userID string) (models.User, error) {
+	return r.findBy(func(user models.User) bool { return user.User_id == userID })
+}
+
+func (r memoryUsers) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.findBy(func(user models.User) bool { return user.Email != nil && *user.Email == email })
+}
+
+func (r memoryUsers) FindByPhone(ctx context.Context, phone string) (models.User, error) {
+	return r.findBy(func(user models.User) bool { return user.Phone != nil && *user.Phone == phone })
+}
+
+func (r memoryUsers) FindByIdentity(ctx context.Context, provider string, subject string) (models.User, error) {
+	return r.findBy(func(user models.User) bool {
+		for _, identity := range user.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r memoryUsers) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var users []models.User
+	for _, user := range r.m.Users {
+		if containsID(ids, user.ID) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r memoryUsers) Create(ctx context.Context, user models.User) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.clash(user, -1); err != nil {
+		return err
+	}
+	r.m.Users = append(r.m.Users, user)
+	return nil
+}
+
+// Update works on the stored document, as MongoDB would, so field names
+// and values are the same ones the MongoDB repository is given.
+func (r memoryUsers) Update(ctx context.Context, userID string, match bson.M, change UserChange) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(func(user models.User) bool { return user.User_id == userID })
+	if i < 0 {
+		return ErrNotFound
+	}
+	doc := toDocument(r.m.Users[i])
+	if len(match) > 0 {
+		for field, value := range toDocument(match) {
+			if !reflect.DeepEqual(doc[field], value) {
+				return ErrNotFound
+			}
+		}
+	}
+	if len(change.Set) > 0 {
+		for field, value := range toDocument(change.Set) {
+			doc[field] = value
+		}
+	}
+	for _, field := range change.Unset {
+		delete(doc, field)
+	}
+
+	var user models.User
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := bson.Unmarshal(data, &user); err != nil {
+		return err
+	}
+	if err := r.clash(user, i); err != nil {
+		return err
+	}
+	r.m.Users[i] = user
+	return nil
+}
+
+func (r memoryUsers) AddIdentity(ctx context.Context, userID string, identity models.Identity) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(func(user models.User) bool { return user.User_id == userID })
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.m.Users[i].Identities = append(r.m.Users[i].Identities, identity)
+	r.m.Users[i].Updated_at = time.Now()
+	return nil
+}
+
+func (r memoryUsers) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(func(user models.User) bool { return user.User_id == userID })
+	if i < 0 || r.m.Users[i].Totp_last_step >= step {
+		return false, nil
+	}
+	r.m.Users[i].Totp_last_step = step
+	return true, nil
+}
+
+func (r memoryUsers) UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(func(user models.User) bool { return user.User_id == userID })
+	if i < 0 {
+		return false, nil
+	}
+	codes := r.m.Users[i].Recovery_codes
+	for j, code := range codes {
+		if code == hash {
+			r.m.Users[i].Recovery_codes = append(codes[:j:j], codes[j+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryUsers) RevokeCredentials(ctx context.Context, userID string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now().Truncate(time.Second)
+	i := r.find(func(user models.User) bool { return user.User_id == userID })
+	if i >= 0 {
+		r.m.Users[i].Tokens_valid_after = &now
+		r.m.Users[i].Updated_at = now
+	}
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	for j, session := range r.m.Sessions {
+		if session.User_ID == id && session.Revoked_at == nil {
+			r.m.Sessions[j].Revoked_at = &now
+			r.m.Sessions[j].Updated_at = now
+		}
+	}
+	for j, apiKey := range r.m.APIKeys {
+		if apiKey.User_ID == id && apiKey.Revoked_at == nil {
+			r.m.APIKeys[j].Revoked_at = &now
+			r.m.APIKeys[j].Updated_at = now
+		}
+	}
+	return nil
+}
+
+func (r memoryUsers) ClaimDeletion(ctx context.Context, now time.Time, claimTTL time.Duration) (models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(func(user models.User) bool {
+		if user.Deletion_scheduled_at == nil || user.Deletion_scheduled_at.After(now) {
+			return false
+		}
+		return user.Deletion_started_at == nil || user.Deletion_started_at.Before(now.Add(-claimTTL))
+	})
+	if i < 0 {
+		return models.User{}, ErrNotFound
+	}
+	// like FindOneAndUpdate, the user is returned as it was before the claim
+	user := r.m.Users[i]
+	r.m.Users[i].Deletion_started_at = &now
+	return user, nil
+}
+
+func (r memoryUsers) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if i := r.find(func(user models.User) bool { return user.ID == id }); i >= 0 {
+		r.m.Users = append(r.m.Users[:i], r.m.Users[i+1:]...)
+	}
+	return nil
+}
+
+func (r memoryUsers) HiddenUserIDs(ctx context.Context, viewer *primitive.ObjectID, includeMuted bool) ([]primitive.ObjectID, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	ids := []primitive.ObjectID{}
+	if viewer == nil {
+		return ids, nil
+	}
+	for _, block := range r.m.Blocks {
+		if block.Blocker_ID == *viewer {
+			ids = append(ids, block.Blocked_ID)
+		} else if block.Blocked_ID == *viewer {
+			ids = append(ids, block.Blocker_ID)
+		}
+	}
+	if includeMuted {
+		for _, mute := range r.m.Mutes {
+			if mute.Muter_ID == *viewer {
+				ids = append(ids, mute.Muted_ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r memoryUsers) IsBlocked(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, block := range r.m.Blocks {
+		if (block.Blocker_ID == a && block.Blocked_ID == b) || (block.Blocker_ID == b && block.Blocked_ID == a) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memorySessions struct{ m *Memory }
+
+func isActive(session models.Session, now time.Time) bool {
+	return session.Revoked_at == nil && session.Expires_at.After(now)
+}
+
+func (r memorySessions) Create(ctx context.Context, session models.Session) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Sessions = append(r.m.Sessions, session)
+	return nil
+}
+
+func (r memorySessions) FindActive(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, now time.Time) (models.Session, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, session := range r.m.Sessions {
+		if session.ID == id && session.User_ID == userID && isActive(session, now) {
+			return session, nil
+		}
+	}
+	return models.Session{}, ErrNotFound
+}
+
+func (r memorySessions) ListActive(ctx context.Context, userID primitive.ObjectID, now time.Time, skip int64, limit int64) ([]models.Session, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var sessions []models.Session
+	for _, session := range r.m.Sessions {
+		if session.User_ID == userID && isActive(session, now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Last_seen_at.After(sessions[j].Last_seen_at)
+	})
+	start, end := page(len(sessions), skip, limit)
+	return sessions[start:end], nil
+}
+
+func (r memorySessions) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var sessions []models.Session
+	for _, session := range r.m.Sessions {
+		if session.User_ID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r memorySessions) Rotate(ctx context.Context, id primitive.ObjectID, oldHash string, newHash string, userAgent string, ip string, now time.Time, expiresAt time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, session := range r.m.Sessions {
+		if session.ID == id && session.Refresh_token_hash == oldHash {
+			r.m.Sessions[i].Refresh_token_hash = newHash
+			r.m.Sessions[i].User_agent = userAgent
+			r.m.Sessions[i].Ip_address = ip
+			r.m.Sessions[i].Last_seen_at = now
+			r.m.Sessions[i].Expires_at = expiresAt
+			r.m.Sessions[i].Updated_at = now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memorySessions) Touch(ctx context.Context, id primitive.ObjectID, ip string, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, session := range r.m.Sessions {
+		if session.ID == id {
+			r.m.Sessions[i].Last_seen_at = at
+			r.m.Sessions[i].Ip_address = ip
+		}
+	}
+	return nil
+}
+
+func (r memorySessions) Revoke(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, session := range r.m.Sessions {
+		if session.ID == id && session.User_ID == userID && session.Revoked_at == nil {
+			r.m.Sessions[i].Revoked_at = &at
+			r.m.Sessions[i].Updated_at = at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memorySessions) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.Session
+	for _, session := range r.m.Sessions {
+		if session.User_ID != userID {
+			kept = append(kept, session)
+		}
+	}
+	deleted := int64(len(r.m.Sessions) - len(kept))
+	r.m.Sessions = kept
+	return deleted, nil
+}
+
+type memoryAPIKeys struct{ m *Memory }
+
+func (r memoryAPIKeys) Create(ctx context.Context, apiKey models.APIKey) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.APIKeys = append(r.m.APIKeys, apiKey)
+	return nil
+}
+
+func (r memoryAPIKeys) FindByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, apiKey := range r.m.APIKeys {
+		if apiKey.Key_hash == hash {
+			return apiKey, nil
+		}
+	}
+	return models.APIKey{}, ErrNotFound
+}
+
+func (r memoryAPIKeys) ListByUser(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.APIKey, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var apiKeys []models.APIKey
+	for i := len(r.m.APIKeys) - 1; i >= 0; i-- {
+		if r.m.APIKeys[i].User_ID == userID {
+			apiKeys = append(apiKeys, r.m.APIKeys[i])
+		}
+	}
+	start, end := page(len(apiKeys), skip, limit)
+	return apiKeys[start:end], nil
+}
+
+func (r memoryAPIKeys) Revoke(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, apiKey := range r.m.APIKeys {
+		if apiKey.ID == id && apiKey.User_ID == userID && apiKey.Revoked_at == nil {
+			r.m.APIKeys[i].Revoked_at = &at
+			r.m.APIKeys[i].Updated_at = at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryAPIKeys) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, apiKey := range r.m.APIKeys {
+		if apiKey.ID == id {
+			r.m.APIKeys[i].Last_used_at = &at
+		}
+	}
+	return nil
+}
+
+func (r memoryAPIKeys) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.APIKey
+	for _, apiKey := range r.m.APIKeys {
+		if apiKey.User_ID != userID {
+			kept = append(kept, apiKey)
+		}
+	}
+	deleted := int64(len(r.m.APIKeys) - len(kept))
+	r.m.APIKeys = kept
+	return deleted, nil
+}
+
+type memoryThrottles struct{ m *Memory }
+
+// find returns the index of the throttle of key, or -1. The caller holds
+// the lock.
+func (r memoryThrottles) find(key string) int {
+	for i, throttle := range r.m.Throttles {
+		if throttle.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r memoryThrottles) FindLocked(ctx context.Context, keys []string, now time.Time) ([]models.LoginThrottle, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var throttles []models.LoginThrottle
+	for _, key := range keys {
+		i := r.find(key)
+		if i >= 0 && r.m.Throttles[i].Locked_until != nil && r.m.Throttles[i].Locked_until.After(now) {
+			throttles = append(throttles, r.m.Throttles[i])
+		}
+	}
+	return throttles, nil
+}
+
+func (r memoryThrottles) CountFailure(ctx context.Context, key string, now time.Time, quietSince time.Time) (models.LoginThrottle, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(key)
+	if i >= 0 {
+		throttle := r.m.Throttles[i]
+		locked := throttle.Locked_until != nil && throttle.Locked_until.After(now)
+		if throttle.Last_failure.Before(quietSince) && !locked {
+			r.m.Throttles = append(r.m.Throttles[:i], r.m.Throttles[i+1:]...)
+			i = -1
+		}
+	}
+	if i < 0 {
+		r.m.Throttles = append(r.m.Throttles, models.LoginThrottle{Key: key})
+		i = len(r.m.Throttles) - 1
+	}
+	r.m.Throttles[i].Failures++
+	r.m.Throttles[i].Last_failure = now
+	return r.m.Throttles[i], nil
+}
+
+func (r memoryThrottles) Lock(ctx context.Context, key string, event models.LockoutEvent) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if i := r.find(key); i >= 0 {
+		until := event.Locked_until
+		r.m.Throttles[i].Locked_until = &until
+	}
+	r.m.Lockouts = append(r.m.Lockouts, event)
+	return nil
+}
+
+func (r memoryThrottles) Clear(ctx context.Context, key string) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	i := r.find(key)
+	if i < 0 {
+		return 0, nil
+	}
+	r.m.Throttles = append(r.m.Throttles[:i], r.m.Throttles[i+1:]...)
+	return 1, nil
+}
+
+func (r memoryThrottles) ListLockouts(ctx context.Context, scope string, skip int64, limit int64) ([]models.LockoutEvent, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var lockouts []models.LockoutEvent
+	for i := len(r.m.Lockouts) - 1; i >= 0; i-- {
+		if scope == "" || r.m.Lockouts[i].Scope == scope {
+			lockouts = append(lockouts, r.m.Lockouts[i])
+		}
+	}
+	start, end := page(len(lockouts), skip, limit)
+	return lockouts[start:end], nil
+}
+
+func (r memoryThrottles) DeleteLockouts(ctx context.Context, scope string, subject string) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.LockoutEvent
+	for _, lockout := range r.m.Lockouts {
+		if lockout.Scope != scope || lockout.Subject != subject {
+			kept = append(kept, lockout)
+		}
+	}
+	deleted := int64(len(r.m.Lockouts) - len(kept))
+	r.m.Lockouts = kept
+	return deleted, nil
+}
+
+type memoryOIDCStates struct{ m *Memory }
+
+func (r memoryOIDCStates) Create(ctx context.Context, state models.OIDCState) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.OIDCStates = append(r.m.OIDCStates, state)
+	return nil
+}
+
+func (r memoryOIDCStates) Take(ctx context.Context, state string, provider string, now time.Time) (models.OIDCState, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, stored := range r.m.OIDCStates {
+		if stored.State == state && stored.Provider == provider && stored.Expires_at.After(now) {
+			r.m.OIDCStates = append(r.m.OIDCStates[:i], r.m.OIDCStates[i+1:]...)
+			return stored, nil
+		}
+	}
+	return models.OIDCState{}, ErrNotFound
+}
+
+type memoryPosts struct{ m *Memory }
+
+func (r memoryPosts) Create(ctx context.Context, post models.Post) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Posts = append(r.m.Posts, post)
+	return nil
+}
+
+func (r memoryPosts) FindByID(ctx context.Context, id primitive.ObjectID) (models.Post, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, post := range r.m.Posts {
+		if post.ID == id {
+			return post, nil
+		}
+	}
+	return models.Post{}, ErrNotFound
+}
+
+// visibleOn returns the comments and likes of a post that hidden users did
+// not write and moderators did not hide. The caller holds the lock.
+func (r memoryPosts) visibleOn(postID primitive.ObjectID, hidden []primitive.ObjectID) (primitive.A, primitive.A) {
+	comments := primitive.A{}
+	for _, comment := range r.m.Comments {
+		if comment.Post_ID == postID && !comment.Hidden && !containsID(hidden, comment.User_ID) && !r.m.isBanned(comment.User_ID) {
+			comments = append(comments, toDocument(comment))
+		}
+	}
+	likes := primitive.A{}
+	for _, like := range r.m.Likes {
+		if like.Post_ID == postID && !containsID(hidden, like.User_ID) && !r.m.isBanned(like.User_ID) {
+			likes = append(likes, toDocument(like))
+		}
+	}
+	return comments, likes
+}
+
+func (r memoryPosts) FindVisible(ctx context.Context, id primitive.ObjectID, hidden []primitive.ObjectID) (bson.M, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, post := range r.m.Posts {
+		if post.ID != id || post.Hidden || containsID(hidden, post.User_id) || r.m.isBanned(post.User_id) {
+			continue
+		}
+		comments, likes := r.visibleOn(post.ID, hidden)
+		doc := toDocument(post)
+		doc["comments"] = comments
+		doc["likes"] = likes
+		doc["total_comments"] = int32(len(comments))
+		doc["total_likes"] = int32(len(likes))
+		return doc, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryPosts) ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]bson.M, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var posts []bson.M
+	for _, post := range r.m.Posts {
+		if post.Hidden || containsID(hidden, post.User_id) || r.m.isBanned(post.User_id) {
+			continue
+		}
+		comments, likes := r.visibleOn(post.ID, hidden)
+		full := toDocument(post)
+		posts = append(posts, bson.M{
+			"_id":           full["_id"],
+			"name":          full["name"],
+			"description":   full["description"],
+			"created_at":    full["created_at"],
+			"updated_at":    full["updated_at"],
+			"user_id":       full["user_id"],
+			"like_count":    int32(len(likes)),
+			"comment_count": int32(len(comments)),
+		})
+	}
+	start, end := page(len(posts), skip, limit)
+	return posts[start:end], nil
+}
+
+func (r memoryPosts) Update(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID, name *string, description *string, updatedAt time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, post := range r.m.Posts {
+		if post.ID == id && mayTouch(owner, post.User_id) {
+			r.m.Posts[i].Name = name
+			r.m.Posts[i].Description = description
+			r.m.Posts[i].Updated_at = updatedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryPosts) Delete(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, post := range r.m.Posts {
+		if post.ID == id && mayTouch(owner, post.User_id) {
+			r.m.Posts = append(r.m.Posts[:i], r.m.Posts[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryPosts) Hide(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, post := range r.m.Posts {
+		if post.ID == id {
+			r.m.Posts[i].Hidden = true
+			r.m.Posts[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryPosts) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Post, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var posts []models.Post
+	for _, post := range r.m.Posts {
+		if post.User_id == userID {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (r memoryPosts) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.Post
+	for _, post := range r.m.Posts {
+		if post.User_id != userID {
+			kept = append(kept, post)
+		}
+	}
+	deleted := int64(len(r.m.Posts) - len(kept))
+	r.m.Posts = kept
+	return deleted, nil
+}
+
+type memoryComments struct{ m *Memory }
+
+func (r memoryComments) Create(ctx context.Context, comment models.Comment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Comments = append(r.m.Comments, comment)
+	return nil
+}
+
+func (r memoryComments) FindByID(ctx context.Context, id primitive.ObjectID) (models.Comment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, comment := range r.m.Comments {
+		if comment.ID == id {
+			return comment, nil
+		}
+	}
+	return models.Comment{}, ErrNotFound
+}
+
+func (r memoryComments) FindVisible(ctx context.Context, id primitive.ObjectID) (models.Comment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, comment := range r.m.Comments {
+		if comment.ID == id && !comment.Hidden {
+			return comment, nil
+		}
+	}
+	return models.Comment{}, ErrNotFound
+}
+
+func (r memoryComments) ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]models.Comment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var comments []models.Comment
+	for _, comment := range r.m.Comments {
+		if !comment.Hidden && !containsID(hidden, comment.User_ID) && !r.m.isBanned(comment.User_ID) {
+			comments = append(comments, comment)
+		}
+	}
+	start, end := page(len(comments), skip, limit)
+	return comments[start:end], nil
+}
+
+func (r memoryComments) Update(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID, description *string, updatedAt time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, comment := range r.m.Comments {
+		if comment.ID == id && mayTouch(owner, comment.User_ID) {
+			r.m.Comments[i].Description = description
+			r.m.Comments[i].Updated_at = updatedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryComments) Delete(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, comment := range r.m.Comments {
+		if comment.ID == id && mayTouch(owner, comment.User_ID) {
+			r.m.Comments = append(r.m.Comments[:i], r.m.Comments[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryComments) Hide(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, comment := range r.m.Comments {
+		if comment.ID == id {
+			r.m.Comments[i].Hidden = true
+			r.m.Comments[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryComments) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Comment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var comments []models.Comment
+	for _, comment := range r.m.Comments {
+		if comment.User_ID == userID {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+// deleteComments removes the comments remove accepts and returns how many
+// there were. The caller holds the lock.
+func (r memoryComments) deleteComments(remove func(models.Comment) bool) int64 {
+	var kept []models.Comment
+	for _, comment := range r.m.Comments {
+		if !remove(comment) {
+			kept = append(kept, comment)
+		}
+	}
+	deleted := int64(len(r.m.Comments) - len(kept))
+	r.m.Comments = kept
+	return deleted
+}
+
+func (r memoryComments) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.deleteComments(func(comment models.Comment) bool { return comment.User_ID == userID }), nil
+}
+
+func (r memoryComments) DeleteOnPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.deleteComments(func(comment models.Comment) bool { return containsID(postIDs, comment.Post_ID) }), nil
+}
+
+type memoryLikes struct{ m *Memory }
+
+func (r memoryLikes) Create(ctx context.Context, like models.Like) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Likes = append(r.m.Likes, like)
+	return nil
+}
+
+func (r memoryLikes) FindByID(ctx context.Context, id primitive.ObjectID) (models.Like, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, like := range r.m.Likes {
+		if like.ID == id {
+			return like, nil
+		}
+	}
+	return models.Like{}, ErrNotFound
+}
+
+func (r memoryLikes) ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]models.Like, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var likes []models.Like
+	for _, like := range r.m.Likes {
+		if !containsID(hidden, like.User_ID) && !r.m.isBanned(like.User_ID) {
+			likes = append(likes, like)
+		}
+	}
+	start, end := page(len(likes), skip, limit)
+	return likes[start:end], nil
+}
+
+func (r memoryLikes) Delete(ctx context.Context, id primitive.ObjectID, owner primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, like := range r.m.Likes {
+		if like.ID == id && like.User_ID == owner {
+			r.m.Likes = append(r.m.Likes[:i], r.m.Likes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryLikes) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Like, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var likes []models.Like
+	for _, like := range r.m.Likes {
+		if like.User_ID == userID {
+			likes = append(likes, like)
+		}
+	}
+	return likes, nil
+}
+
+// deleteLikes removes the likes remove accepts and returns how many there
+// were. The caller holds the lock.
+func (r memoryLikes) deleteLikes(remove func(models.Like) bool) int64 {
+	var kept []models.Like
+	for _, like := range r.m.Likes {
+		if !remove(like) {
+			kept = append(kept, like)
+		}
+	}
+	deleted := int64(len(r.m.Likes) - len(kept))
+	r.m.Likes = kept
+	return deleted
+}
+
+func (r memoryLikes) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.deleteLikes(func(like models.Like) bool { return like.User_ID == userID }), nil
+}
+
+func (r memoryLikes) DeleteOnPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.deleteLikes(func(like models.Like) bool { return containsID(postIDs, like.Post_ID) }), nil
+}
+
+type memoryFollows struct{ m *Memory }
+
+func (r memoryFollows) Create(ctx context.Context, follow models.Follow) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Follows = append(r.m.Follows, follow)
+	return nil
+}
+
+func (r memoryFollows) Exists(ctx context.Context, follower primitive.ObjectID, following primitive.ObjectID) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, follow := range r.m.Follows {
+		if follow.Follower_ID == follower && follow.Following_ID == following {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryFollows) Delete(ctx context.Context, id primitive.ObjectID, follower primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, follow := range r.m.Follows {
+		if follow.ID == id && follow.Follower_ID == follower {
+			r.m.Follows = append(r.m.Follows[:i], r.m.Follows[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryFollows) List(ctx context.Context, follower *primitive.ObjectID, following *primitive.ObjectID, skip int64, limit int64) ([]models.Follow, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var follows []models.Follow
+	for _, follow := range r.m.Follows {
+		if mayTouch(follower, follow.Follower_ID) && mayTouch(following, follow.Following_ID) {
+			follows = append(follows, follow)
+		}
+	}
+	start, end := page(len(follows), skip, limit)
+	return follows[start:end], nil
+}
+
+// deleteFollows removes the follows remove accepts and returns how many
+// there were. The caller holds the lock.
+func (r memoryFollows) deleteFollows(remove func(models.Follow) bool) int64 {
+	var kept []models.Follow
+	for _, follow := range r.m.Follows {
+		if !remove(follow) {
+			kept = append(kept, follow)
+		}
+	}
+	deleted := int64(len(r.m.Follows) - len(kept))
+	r.m.Follows = kept
+	return deleted
+}
+
+func (r memoryFollows) DeleteBetween(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.deleteFollows(func(follow models.Follow) bool {
+		return follow.Follower_ID == a && follow.Following_ID == b || follow.Follower_ID == b && follow.Following_ID == a
+	}), nil
+}
+
+func (r memoryFollows) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.deleteFollows(func(follow models.Follow) bool {
+		return follow.Follower_ID == userID || follow.Following_ID == userID
+	}), nil
+}
+
+type memoryBlocks struct{ m *Memory }
+
+func (r memoryBlocks) Create(ctx context.Context, block models.Block) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Blocks = append(r.m.Blocks, block)
+	return nil
+}
+
+func (r memoryBlocks) Exists(ctx context.Context, blocker primitive.ObjectID, blocked primitive.ObjectID) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, block := range r.m.Blocks {
+		if block.Blocker_ID == blocker && block.Blocked_ID == blocked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryBlocks) Delete(ctx context.Context, id primitive.ObjectID, blocker primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, block := range r.m.Blocks {
+		if block.ID == id && block.Blocker_ID == blocker {
+			r.m.Blocks = append(r.m.Blocks[:i], r.m.Blocks[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryBlocks) ListByBlocker(ctx context.Context, blocker primitive.ObjectID, skip int64, limit int64) ([]models.Block, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var blocks []models.Block
+	for _, block := range r.m.Blocks {
+		if block.Blocker_ID == blocker {
+			blocks = append(blocks, block)
+		}
+	}
+	start, end := page(len(blocks), skip, limit)
+	return blocks[start:end], nil
+}
+
+func (r memoryBlocks) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.Block
+	for _, block := range r.m.Blocks {
+		if block.Blocker_ID != userID && block.Blocked_ID != userID {
+			kept = append(kept, block)
+		}
+	}
+	deleted := int64(len(r.m.Blocks) - len(kept))
+	r.m.Blocks = kept
+	return deleted, nil
+}
+
+type memoryMutes struct{ m *Memory }
+
+func (r memoryMutes) Create(ctx context.Context, mute models.Mute) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Mutes = append(r.m.Mutes, mute)
+	return nil
+}
+
+func (r memoryMutes) Exists(ctx context.Context, muter primitive.ObjectID, muted primitive.ObjectID) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, mute := range r.m.Mutes {
+		if mute.Muter_ID == muter && mute.Muted_ID == muted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryMutes) Delete(ctx context.Context, id primitive.ObjectID, muter primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, mute := range r.m.Mutes {
+		if mute.ID == id && mute.Muter_ID == muter {
+			r.m.Mutes = append(r.m.Mutes[:i], r.m.Mutes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryMutes) ListByMuter(ctx context.Context, muter primitive.ObjectID, skip int64, limit int64) ([]models.Mute, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var mutes []models.Mute
+	for _, mute := range r.m.Mutes {
+		if mute.Muter_ID == muter {
+			mutes = append(mutes, mute)
+		}
+	}
+	start, end := page(len(mutes), skip, limit)
+	return mutes[start:end], nil
+}
+
+func (r memoryMutes) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.Mute
+	for _, mute := range r.m.Mutes {
+		if mute.Muter_ID != userID && mute.Muted_ID != userID {
+			kept = append(kept, mute)
+		}
+	}
+	deleted := int64(len(r.m.Mutes) - len(kept))
+	r.m.Mutes = kept
+	return deleted, nil
+}
+
+type memoryConversations struct{ m *Memory }
+
+func (r memoryConversations) Create(ctx context.Context, conversation models.Conversation) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Conversations = append(r.m.Conversations, conversation)
+	return nil
+}
+
+func (r memoryConversations) FindForParticipant(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (models.Conversation, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, conversation := range r.m.Conversations {
+		if conversation.ID == id && containsID(conversation.Participants, userID) {
+			return conversation, nil
+		}
+	}
+	return models.Conversation{}, ErrNotFound
+}
+
+func (r memoryConversations) FindDirect(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (models.Conversation, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, conversation := range r.m.Conversations {
+		participants := conversation.Participants
+		if !conversation.Is_group && len(participants) == 2 && containsID(participants, a) && containsID(participants, b) {
+			return conversation, nil
+		}
+	}
+	return models.Conversation{}, ErrNotFound
+}
+
+func (r memoryConversations) ListByParticipant(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.Conversation, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var conversations []models.Conversation
+	for _, conversation := range r.m.Conversations {
+		if containsID(conversation.Participants, userID) {
+			conversations = append(conversations, conversation)
+		}
+	}
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].Last_message_at.After(conversations[j].Last_message_at)
+	})
+	start, end := page(len(conversations), skip, limit)
+	return conversations[start:end], nil
+}
+
+func (r memoryConversations) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, conversation := range r.m.Conversations {
+		if conversation.ID == id {
+			r.m.Conversations[i].Last_message_at = at
+			r.m.Conversations[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryConversations) RemoveParticipant(ctx context.Context, userID primitive.ObjectID, at time.Time) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var changed int64
+	for i, conversation := range r.m.Conversations {
+		if !containsID(conversation.Participants, userID) {
+			continue
+		}
+		var participants []primitive.ObjectID
+		for _, participant := range conversation.Participants {
+			if participant != userID {
+				participants = append(participants, participant)
+			}
+		}
+		r.m.Conversations[i].Participants = participants
+		r.m.Conversations[i].Updated_at = at
+		changed++
+	}
+	return changed, nil
+}
+
+type memoryMessages struct{ m *Memory }
+
+func (r memoryMessages) Create(ctx context.Context, message models.Message) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Messages = append(r.m.Messages, message)
+	return nil
+}
+
+func (r memoryMessages) List(ctx context.Context, conversationID primitive.ObjectID, before *primitive.ObjectID, limit int64) ([]models.Message, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var messages []models.Message
+	for i := len(r.m.Messages) - 1; i >= 0; i-- {
+		message := r.m.Messages[i]
+		if message.Conversation_ID != conversationID {
+			continue
+		}
+		if before != nil && bytes.Compare(message.ID[:], before[:]) >= 0 {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	start, end := page(len(messages), 0, limit)
+	return messages[start:end], nil
+}
+
+func (r memoryMessages) CountUnread(ctx context.Context, conversationIDs []primitive.ObjectID, reader primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	counts := map[primitive.ObjectID]int{}
+	for _, message := range r.m.Messages {
+		if containsID(conversationIDs, message.Conversation_ID) && message.Sender_ID != reader && !containsID(message.Read_by, reader) {
+			counts[message.Conversation_ID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r memoryMessages) Delete(ctx context.Context, id primitive.ObjectID, conversationID primitive.ObjectID, sender primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, message := range r.m.Messages {
+		if message.ID == id && message.Conversation_ID == conversationID && message.Sender_ID == sender {
+			r.m.Messages = append(r.m.Messages[:i], r.m.Messages[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryMessages) MarkRead(ctx context.Context, conversationID primitive.ObjectID, reader primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, message := range r.m.Messages {
+		if message.Conversation_ID == conversationID && !containsID(message.Read_by, reader) {
+			r.m.Messages[i].Read_by = append(message.Read_by, reader)
+		}
+	}
+	return nil
+}
+
+func (r memoryMessages) ListBySender(ctx context.Context, sender primitive.ObjectID) ([]models.Message, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var messages []models.Message
+	for _, message := range r.m.Messages {
+		if message.Sender_ID == sender {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (r memoryMessages) ListByConversations(ctx context.Context, conversationIDs []primitive.ObjectID) ([]models.Message, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var messages []models.Message
+	for _, message := range r.m.Messages {
+		if containsID(conversationIDs, message.Conversation_ID) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (r memoryMessages) Anonymize(ctx context.Context, sender primitive.ObjectID, body string, at time.Time) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var changed int64
+	for i, message := range r.m.Messages {
+		if message.Sender_ID == sender && (message.Body == nil || *message.Body != body) {
+			r.m.Messages[i].Body = &body
+			r.m.Messages[i].Updated_at = at
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (r memoryMessages) RemoveReader(ctx context.Context, reader primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var changed int64
+	for i, message := range r.m.Messages {
+		if !containsID(message.Read_by, reader) {
+			continue
+		}
+		var readBy []primitive.ObjectID
+		for _, id := range message.Read_by {
+			if id != reader {
+				readBy = append(readBy, id)
+			}
+		}
+		r.m.Messages[i].Read_by = readBy
+		changed++
+	}
+	return changed, nil
+}
+
+type memoryNotifications struct{ m *Memory }
+
+func (r memoryNotifications) Create(ctx context.Context, notification models.Notification) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Notifications = append(r.m.Notifications, notification)
+	return nil
+}
+
+func (r memoryNotifications) List(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, skip int64, limit int64) ([]models.Notification, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var notifications []models.Notification
+	for i := len(r.m.Notifications) - 1; i >= 0; i-- {
+		notification := r.m.Notifications[i]
+		if notification.User_ID == userID && !(unreadOnly && notification.Read) {
+			notifications = append(notifications, notification)
+		}
+	}
+	start, end := page(len(notifications), skip, limit)
+	return notifications[start:end], nil
+}
+
+func (r memoryNotifications) MarkRead(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, notification := range r.m.Notifications {
+		if notification.ID == id && notification.User_ID == userID {
+			r.m.Notifications[i].Read = true
+			r.m.Notifications[i].Updated_at = at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r memoryNotifications) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.Notification
+	for _, notification := range r.m.Notifications {
+		if notification.User_ID != userID {
+			kept = append(kept, notification)
+		}
+	}
+	deleted := int64(len(r.m.Notifications) - len(kept))
+	r.m.Notifications = kept
+	return deleted, nil
+}
+
+type memoryReports struct{ m *Memory }
+
+func (r memoryReports) Create(ctx context.Context, report models.Report) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Reports = append(r.m.Reports, report)
+	return nil
+}
+
+func (r memoryReports) HasOpen(ctx context.Context, reporter primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, report := range r.m.Reports {
+		if report.Reporter_ID == reporter && report.Target_type == targetType && report.Target_ID == targetID && report.Status == models.ReportStatusOpen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryReports) ListByReporter(ctx context.Context, reporter primitive.ObjectID) ([]models.Report, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var reports []models.Report
+	for _, report := range r.m.Reports {
+		if report.Reporter_ID == reporter {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+func (r memoryReports) ListOpen(ctx context.Context, targetType string, targetID primitive.ObjectID) ([]models.Report, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var reports []models.Report
+	for _, report := range r.m.Reports {
+		if report.Target_type == targetType && report.Target_ID == targetID && report.Status == models.ReportStatusOpen {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+func (r memoryReports) Queue(ctx context.Context, targetType string, skip int64, limit int64) ([]models.ReportGroup, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var groups []models.ReportGroup
+	index := map[primitive.ObjectID]int{}
+	for _, report := range r.m.Reports {
+		if report.Status != models.ReportStatusOpen || targetType != "" && report.Target_type != targetType {
+			continue
+		}
+		i, ok := index[report.Target_ID]
+		if !ok {
+			i = len(groups)
+			index[report.Target_ID] = i
+			groups = append(groups, models.ReportGroup{
+				Target_type:       report.Target_type,
+				Target_ID:         report.Target_ID,
+				First_reported_at: report.Created_at,
+			})
+		}
+		group := &groups[i]
+		group.Report_count++
+		group.Report_IDs = append(group.Report_IDs, report.ID)
+		if !containsString(group.Reasons, report.Reason) {
+			group.Reasons = append(group.Reasons, report.Reason)
+		}
+		if report.Created_at.Before(group.First_reported_at) {
+			group.First_reported_at = report.Created_at
+		}
+		if report.Created_at.After(group.Last_reported_at) {
+			group.Last_reported_at = report.Created_at
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Report_count != groups[j].Report_count {
+			return groups[i].Report_count > groups[j].Report_count
+		}
+		return groups[i].First_reported_at.Before(groups[j].First_reported_at)
+	})
+	start, end := page(len(groups), skip, limit)
+	return groups[start:end], nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (r memoryReports) RecordAction(ctx context.Context, action models.ModerationAction) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.ModerationActions = append(r.m.ModerationActions, action)
+	return nil
+}
+
+func (r memoryReports) Decide(ctx context.Context, ids []primitive.ObjectID, status string, action string, moderator primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, report := range r.m.Reports {
+		if containsID(ids, report.ID) {
+			r.m.Reports[i].Status = status
+			r.m.Reports[i].Action = action
+			r.m.Reports[i].Moderator_ID = &moderator
+			r.m.Reports[i].Decided_at = &at
+			r.m.Reports[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryReports) AnonymizeReporter(ctx context.Context, reporter primitive.ObjectID, at time.Time) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var changed int64
+	for i, report := range r.m.Reports {
+		if report.Reporter_ID == reporter {
+			r.m.Reports[i].Reporter_ID = primitive.NilObjectID
+			r.m.Reports[i].Updated_at = at
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+type memorySanctions struct{ m *Memory }
+
+func (r memorySanctions) Create(ctx context.Context, sanction models.Sanction) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Sanctions = append(r.m.Sanctions, sanction)
+	return nil
+}
+
+func (r memorySanctions) LiftAll(ctx context.Context, userID primitive.ObjectID, liftedBy primitive.ObjectID, reason string, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, sanction := range r.m.Sanctions {
+		if sanction.User_ID == userID && sanction.Lifted_at == nil {
+			r.m.Sanctions[i].Lifted_at = &at
+			r.m.Sanctions[i].Lifted_by = &liftedBy
+			r.m.Sanctions[i].Lifted_reason = &reason
+			r.m.Sanctions[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memorySanctions) ListByUser(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.Sanction, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var sanctions []models.Sanction
+	for i := len(r.m.Sanctions) - 1; i >= 0; i-- {
+		if r.m.Sanctions[i].User_ID == userID {
+			sanctions = append(sanctions, r.m.Sanctions[i])
+		}
+	}
+	start, end := page(len(sanctions), skip, limit)
+	return sanctions[start:end], nil
+}
+
+type memoryDeletions struct{ m *Memory }
+
+func (r memoryDeletions) Create(ctx context.Context, deletion models.AccountDeletion) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Deletions = append(r.m.Deletions, deletion)
+	return nil
+}
+
+func (r memoryDeletions) MarkRestored(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, deletion := range r.m.Deletions {
+		if deletion.User_ID == userID && deletion.Status == models.DeletionStatusScheduled {
+			r.m.Deletions[i].Status = models.DeletionStatusRestored
+			r.m.Deletions[i].Restored_at = &at
+			r.m.Deletions[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryDeletions) List(ctx context.Context, status string, skip int64, limit int64) ([]models.AccountDeletion, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var deletions []models.AccountDeletion
+	for i := len(r.m.Deletions) - 1; i >= 0; i-- {
+		if status == "" || r.m.Deletions[i].Status == status {
+			deletions = append(deletions, r.m.Deletions[i])
+		}
+	}
+	start, end := page(len(deletions), skip, limit)
+	return deletions[start:end], nil
+}
+
+func (r memoryDeletions) Complete(ctx context.Context, deletion models.AccountDeletion) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, scheduled := range r.m.Deletions {
+		if scheduled.User_ID == deletion.User_ID && scheduled.Status == models.DeletionStatusScheduled {
+			r.m.Deletions[i].Status = models.DeletionStatusCompleted
+			r.m.Deletions[i].Completed_at = deletion.Completed_at
+			r.m.Deletions[i].Removed = deletion.Removed
+			r.m.Deletions[i].Anonymized = deletion.Anonymized
+			r.m.Deletions[i].Updated_at = deletion.Updated_at
+			return nil
+		}
+	}
+	deletion.Status = models.DeletionStatusCompleted
+	r.m.Deletions = append(r.m.Deletions, deletion)
+	return nil
+}
+
+type memoryExports struct{ m *Memory }
+
+func (r memoryExports) Create(ctx context.Context, export models.DataExport) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.Exports = append(r.m.Exports, export)
+	return nil
+}
+
+func (r memoryExports) findBy(match func(models.DataExport) bool) (models.DataExport, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, export := range r.m.Exports {
+		if match(export) {
+			return export, nil
+		}
+	}
+	return models.DataExport{}, ErrNotFound
+}
+
+func (r memoryExports) FindActive(ctx context.Context, userID primitive.ObjectID) (models.DataExport, error) {
+	return r.findBy(func(export models.DataExport) bool {
+		return export.User_ID == userID && (export.Status == models.ExportStatusPending || export.Status == models.ExportStatusRunning)
+	})
+}
+
+func (r memoryExports) FindByID(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (models.DataExport, error) {
+	return r.findBy(func(export models.DataExport) bool { return export.ID == id && export.User_ID == userID })
+}
+
+func (r memoryExports) Claim(ctx context.Context, now time.Time, claimTTL time.Duration) (models.DataExport, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, export := range r.m.Exports {
+		stale := export.Status == models.ExportStatusRunning && export.Started_at != nil && export.Started_at.Before(now.Add(-claimTTL))
+		if export.Status == models.ExportStatusPending || stale {
+			r.m.Exports[i].Status = models.ExportStatusRunning
+			r.m.Exports[i].Started_at = &now
+			r.m.Exports[i].Updated_at = now
+			return export, nil
+		}
+	}
+	return models.DataExport{}, ErrNotFound
+}
+
+func (r memoryExports) Release(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, export := range r.m.Exports {
+		if export.ID == id {
+			r.m.Exports[i].Status = models.ExportStatusPending
+			r.m.Exports[i].Started_at = nil
+			r.m.Exports[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryExports) Finish(ctx context.Context, finished models.DataExport) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, export := range r.m.Exports {
+		if export.ID != finished.ID {
+			continue
+		}
+		export.Status = finished.Status
+		export.Completed_at = finished.Completed_at
+		export.Updated_at = finished.Updated_at
+		if finished.Status == models.ExportStatusReady {
+			export.File_ID = finished.File_ID
+			export.Size = finished.Size
+			export.Expires_at = finished.Expires_at
+		} else {
+			export.Error = finished.Error
+		}
+		r.m.Exports[i] = export
+	}
+	return nil
+}
+
+func (r memoryExports) ListExpired(ctx context.Context, now time.Time) ([]models.DataExport, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var exports []models.DataExport
+	for _, export := range r.m.Exports {
+		if export.Status == models.ExportStatusReady && export.Expires_at != nil && !export.Expires_at.After(now) {
+			exports = append(exports, export)
+		}
+	}
+	return exports, nil
+}
+
+func (r memoryExports) MarkExpired(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for i, export := range r.m.Exports {
+		if export.ID == id {
+			r.m.Exports[i].Status = models.ExportStatusExpired
+			r.m.Exports[i].File_ID = nil
+			r.m.Exports[i].Updated_at = at
+		}
+	}
+	return nil
+}
+
+func (r memoryExports) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.DataExport, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var exports []models.DataExport
+	for _, export := range r.m.Exports {
+		if export.User_ID == userID {
+			exports = append(exports, export)
+		}
+	}
+	return exports, nil
+}
+
+func (r memoryExports) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var kept []models.DataExport
+	for _, export := range r.m.Exports {
+		if export.User_ID != userID {
+			kept = append(kept, export)
+		}
+	}
+	deleted := int64(len(r.m.Exports) - len(kept))
+	r.m.Exports = kept
+	return deleted, nil
+}
+
+func (r memoryExports) SaveFile(ctx context.Context, name string, source io.Reader) (primitive.ObjectID, error) {
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if r.m.ExportFiles == nil {
+		r.m.ExportFiles = map[primitive.ObjectID][]byte{}
+	}
+	id := primitive.NewObjectID()
+	r.m.ExportFiles[id] = data
+	return id, nil
+}
+
+func (r memoryExports) WriteFile(ctx context.Context, id primitive.ObjectID, w io.Writer) (int64, error) {
+	r.m.mu.Lock()
+	data, ok := r.m.ExportFiles[id]
+	r.m.mu.Unlock()
+
+	if !ok {
+		return 0, ErrNotFound
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+func (r memoryExports) DeleteFile(ctx context.Context, id primitive.ObjectID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	delete(r.m.ExportFiles, id)
+	return nil
+}
@@ -0,0 +1,1411 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"social-media-api/database"
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoRepositories returns repositories backed by the collections of db.
+func NewMongoRepositories(db *mongo.Database) Repositories {
+	return Repositories{
+		Users: &mongoUsers{
+			users:    db.Collection("user"),
+			blocks:   db.Collection("block"),
+			mutes:    db.Collection("mute"),
+			sessions: db.Collection("session"),
+			apiKeys:  db.Collection("api_key"),
+		},
+		Sessions: &mongoSessions{sessions: db.Collection("session")},
+		APIKeys:  &mongoAPIKeys{apiKeys: db.Collection("api_key")},
+		Throttles: &mongoThrottles{
+			throttles: db.Collection("login_throttle"),
+			lockouts:  db.Collection("lockout_event"),
+		},
+		OIDCStates: &mongoOIDCStates{states: db.Collection("oidc_state")},
+		Posts:      &mongoPosts{posts: db.Collection("post")},
+		Comments:   &mongoComments{comments: db.Collection("comment")},
+		Likes:      &mongoLikes{likes: db.Collection("like")},
+		Follows:    &mongoFollows{follows: db.Collection("follow")},
+		Blocks:     &mongoBlocks{blocks: db.Collection("block")},
+		Mutes:      &mongoMutes{mutes: db.Collection("mute")},
+		Conversations: &mongoConversations{
+			conversations: db.Collection("conversation"),
+		},
+		Messages:      &mongoMessages{messages: db.Collection("message")},
+		Notifications: &mongoNotifications{notifications: db.Collection("notification")},
+		Reports: &mongoReports{
+			reports: db.Collection("report"),
+			actions: db.Collection("moderation_action"),
+		},
+		Sanctions: &mongoSanctions{sanctions: db.Collection("sanction")},
+		Deletions: &mongoDeletions{deletions: db.Collection("account_deletion")},
+		Exports: &mongoExports{
+			exports: db.Collection("data_export"),
+			db:      db,
+		},
+	}
+}
+
+// ownedBy matches a document by ID, restricted to owner's documents unless
+// owner is nil.
+func ownedBy(id primitive.ObjectID, owner *primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": id}
+	if owner != nil {
+		filter["user_id"] = *owner
+	}
+	return filter
+}
+
+// findOne decodes the single document matching filter, translating a
+// missing document into ErrNotFound.
+func findOne(ctx context.Context, collection *mongo.Collection, filter bson.M, result interface{}) error {
+	err := collection.FindOne(ctx, filter).Decode(result)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	return err
+}
+
+// findAll decodes every document matching filter into results.
+func findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, results interface{}, opts ...*options.FindOptions) error {
+	cursor, err := collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// deleteMany removes every document matching filter and returns how many
+// there were.
+func deleteMany(ctx context.Context, collection *mongo.Collection, filter bson.M) (int64, error) {
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// oldestFirst sorts by _id, which grows with the creation time.
+var oldestFirst = options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+// pageOf returns options that select a page. newestFirst sorts by _id
+// descending, and false keeps the natural order.
+func pageOf(skip int64, limit int64, newestFirst bool) *options.FindOptions {
+	findOptions := options.Find()
+	if newestFirst {
+		findOptions.SetSort(bson.D{{Key: "_id", Value: -1}})
+	}
+	findOptions.SetSkip(skip)
+	findOptions.SetLimit(limit)
+	return findOptions
+}
+
+// pageStages selects a page in an aggregation. A limit of 0 keeps every
+// document, as it does for Find.
+func pageStages(skip int64, limit int64) mongo.Pipeline {
+	stages := mongo.Pipeline{{{Key: "$skip", Value: skip}}}
+	if limit > 0 {
+		stages = append(stages, bson.D{{Key: "$limit", Value: limit}})
+	}
+	return stages
+}
+
+// exists reports whether any document matches filter.
+func exists(ctx context.Context, collection *mongo.Collection, filter bson.M) (bool, error) {
+	count, err := collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// deleteOne removes the document matching filter, returning ErrNotFound
+// when there is none.
+func deleteOne(ctx context.Context, collection *mongo.Collection, filter bson.M) error {
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// updateOne applies update to the document matching filter, returning
+// ErrNotFound when there is none.
+func updateOne(ctx context.Context, collection *mongo.Collection, filter bson.M, update bson.M) error {
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// updateMany applies update to every document matching filter and returns
+// how many changed.
+func updateMany(ctx context.Context, collection *mongo.Collection, filter bson.M, update bson.M) (int64, error) {
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// duplicateError translates a duplicate key error from the user indexes
+// into a *DuplicateError and passes every other error through.
+func duplicateError(err error) error {
+	if err == nil || !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	switch {
+	case strings.Contains(err.Error(), database.UserEmailIndex):
+		return &DuplicateError{Field: "email"}
+	case strings.Contains(err.Error(), database.UserPhoneIndex):
+		return &DuplicateError{Field: "phone"}
+	}
+	return &DuplicateError{Field: "account"}
+}
+
+type mongoUsers struct {
+	users    *mongo.Collection
+	blocks   *mongo.Collection
+	mutes    *mongo.Collection
+	sessions *mongo.Collection
+	apiKeys  *mongo.Collection
+}
+
+func (r *mongoUsers) FindByID(ctx context.Context, userID string) (models.User, error) {
+	var user models.User
+	err := findOne(ctx, r.users, bson.M{"user_id": userID}, &user)
+	return user, err
+}
+
+func (r *mongoUsers) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := findOne(ctx, r.users, bson.M{"email": email}, &user)
+	return user, err
+}
+
+func (r *mongoUsers) FindByPhone(ctx context.Context, phone string) (models.User, error) {
+	var user models.User
+	err := findOne(ctx, r.users, bson.M{"phone": phone}, &user)
+	return user, err
+}
+
+func (r *mongoUsers) FindByIdentity(ctx context.Context, provider string, subject string) (models.User, error) {
+	var user models.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := findOne(ctx, r.users, filter, &user)
+	return user, err
+}
+
+func (r *mongoUsers) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	var users []models.User
+	err := findAll(ctx, r.users, bson.M{"_id": bson.M{"$in": ids}}, &users)
+	return users, err
+}
+
+func (r *mongoUsers) Create(ctx context.Context, user models.User) error {
+	_, err := r.users.InsertOne(ctx, user)
+	return duplicateError(err)
+}
+
+func (r *mongoUsers) Update(ctx context.Context, userID string, match bson.M, change UserChange) error {
+	filter := bson.M{"user_id": userID}
+	for field, value := range match {
+		filter[field] = value
+	}
+	update := bson.M{}
+	if len(change.Set) > 0 {
+		update["$set"] = change.Set
+	}
+	if len(change.Unset) > 0 {
+		unset := bson.M{}
+		for _, field := range change.Unset {
+			unset[field] = ""
+		}
+		update["$unset"] = unset
+	}
+	result, err := r.users.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return duplicateError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUsers) AddIdentity(ctx context.Context, userID string, identity models.Identity) error {
+	update := bson.M{"$push": bson.M{"identities": identity}, "$set": bson.M{"updated_at": time.Now()}}
+	result, err := r.users.UpdateOne(ctx, bson.M{"user_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUsers) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	filter := bson.M{"user_id": userID, "totp_last_step": bson.M{"$not": bson.M{"$gte": step}}}
+	result, err := r.users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"totp_last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoUsers) UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	filter := bson.M{"user_id": userID, "recovery_codes": hash}
+	result, err := r.users.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"recovery_codes": hash}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoUsers) RevokeCredentials(ctx context.Context, userID string) error {
+	now := time.Now().Truncate(time.Second)
+	update := bson.M{"$set": bson.M{"tokens_valid_after": now, "updated_at": now}}
+	if _, err := r.users.UpdateOne(ctx, bson.M{"user_id": userID}, update); err != nil {
+		return err
+	}
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	revoke := bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}}
+	if _, err := r.sessions.UpdateMany(ctx, bson.M{"user_id": id, "revoked_at": nil}, revoke); err != nil {
+		return err
+	}
+	_, err = r.apiKeys.UpdateMany(ctx, bson.M{"user_id": id, "revoked_at": nil}, revoke)
+	return err
+}
+
+func (r *mongoUsers) ClaimDeletion(ctx context.Context, now time.Time, claimTTL time.Duration) (models.User, error) {
+	filter := bson.M{
+		"deletion_scheduled_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"deletion_started_at": nil},
+			{"deletion_started_at": bson.M{"$lt": now.Add(-claimTTL)}},
+		},
+	}
+	claim := bson.M{"$set": bson.M{"deletion_started_at": now}}
+
+	var user models.User
+	err := r.users.FindOneAndUpdate(ctx, filter, claim).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+func (r *mongoUsers) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.users.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *mongoUsers) HiddenUserIDs(ctx context.Context, viewer *primitive.ObjectID, includeMuted bool) ([]primitive.ObjectID, error) {
+	ids := []primitive.ObjectID{}
+	if viewer == nil {
+		return ids, nil
+	}
+	UID := *viewer
+
+	cursor, err := r.blocks.Find(ctx, bson.M{"$or": []bson.M{{"blocker_id": UID}, {"blocked_id": UID}}})
+	if err != nil {
+		return nil, err
+	}
+	var blocks []models.Block
+	if err = cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		if block.Blocker_ID == UID {
+			ids = append(ids, block.Blocked_ID)
+		} else {
+			ids = append(ids, block.Blocker_ID)
+		}
+	}
+	if !includeMuted {
+		return ids, nil
+	}
+
+	cursor, err = r.mutes.Find(ctx, bson.M{"muter_id": UID})
+	if err != nil {
+		return nil, err
+	}
+	var mutes []models.Mute
+	if err = cursor.All(ctx, &mutes); err != nil {
+		return nil, err
+	}
+	for _, mute := range mutes {
+		ids = append(ids, mute.Muted_ID)
+	}
+	return ids, nil
+}
+
+func (r *mongoUsers) IsBlocked(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (bool, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"blocker_id": a, "blocked_id": b},
+		{"blocker_id": b, "blocked_id": a},
+	}}
+	count, err := r.blocks.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+type mongoSessions struct {
+	sessions *mongo.Collection
+}
+
+// activeSession matches a session that is neither revoked nor expired at now.
+func activeSession(now time.Time) bson.M {
+	return bson.M{"revoked_at": nil, "expires_at": bson.M{"$gt": now}}
+}
+
+func (r *mongoSessions) Create(ctx context.Context, session models.Session) error {
+	_, err := r.sessions.InsertOne(ctx, session)
+	return err
+}
+
+func (r *mongoSessions) FindActive(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, now time.Time) (models.Session, error) {
+	filter := activeSession(now)
+	filter["_id"] = id
+	filter["user_id"] = userID
+
+	var session models.Session
+	err := findOne(ctx, r.sessions, filter, &session)
+	return session, err
+}
+
+func (r *mongoSessions) ListActive(ctx context.Context, userID primitive.ObjectID, now time.Time, skip int64, limit int64) ([]models.Session, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+	findOptions.SetSkip(skip)
+	findOptions.SetLimit(limit)
+
+	filter := activeSession(now)
+	filter["user_id"] = userID
+
+	var sessions []models.Session
+	err := findAll(ctx, r.sessions, filter, &sessions, findOptions)
+	return sessions, err
+}
+
+func (r *mongoSessions) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := findAll(ctx, r.sessions, bson.M{"user_id": userID}, &sessions, oldestFirst)
+	return sessions, err
+}
+
+func (r *mongoSessions) Rotate(ctx context.Context, id primitive.ObjectID, oldHash string, newHash string, userAgent string, ip string, now time.Time, expiresAt time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"refresh_token_hash": newHash,
+		"user_agent":         userAgent,
+		"ip_address":         ip,
+		"last_seen_at":       now,
+		"expires_at":         expiresAt,
+		"updated_at":         now,
+	}}
+	result, err := r.sessions.UpdateOne(ctx, bson.M{"_id": id, "refresh_token_hash": oldHash}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessions) Touch(ctx context.Context, id primitive.ObjectID, ip string, at time.Time) error {
+	_, err := r.sessions.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_seen_at": at, "ip_address": ip}})
+	return err
+}
+
+func (r *mongoSessions) Revoke(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": id, "user_id": userID, "revoked_at": nil}
+	result, err := r.sessions.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": at, "updated_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessions) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.sessions, bson.M{"user_id": userID})
+}
+
+type mongoAPIKeys struct {
+	apiKeys *mongo.Collection
+}
+
+func (r *mongoAPIKeys) Create(ctx context.Context, apiKey models.APIKey) error {
+	_, err := r.apiKeys.InsertOne(ctx, apiKey)
+	return err
+}
+
+func (r *mongoAPIKeys) FindByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var apiKey models.APIKey
+	err := findOne(ctx, r.apiKeys, bson.M{"key_hash": hash}, &apiKey)
+	return apiKey, err
+}
+
+func (r *mongoAPIKeys) ListByUser(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.APIKey, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: -1}})
+	findOptions.SetSkip(skip)
+	findOptions.SetLimit(limit)
+
+	var apiKeys []models.APIKey
+	err := findAll(ctx, r.apiKeys, bson.M{"user_id": userID}, &apiKeys, findOptions)
+	return apiKeys, err
+}
+
+func (r *mongoAPIKeys) Revoke(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": id, "user_id": userID, "revoked_at": nil}
+	result, err := r.apiKeys.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": at, "updated_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAPIKeys) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.apiKeys.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_used_at": at}})
+	return err
+}
+
+func (r *mongoAPIKeys) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.apiKeys, bson.M{"user_id": userID})
+}
+
+type mongoThrottles struct {
+	throttles *mongo.Collection
+	lockouts  *mongo.Collection
+}
+
+func (r *mongoThrottles) FindLocked(ctx context.Context, keys []string, now time.Time) ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	err := findAll(ctx, r.throttles, bson.M{"_id": bson.M{"$in": keys}, "locked_until": bson.M{"$gt": now}}, &throttles)
+	return throttles, err
+}
+
+func (r *mongoThrottles) CountFailure(ctx context.Context, key string, now time.Time, quietSince time.Time) (models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	stale := bson.M{
+		"_id":          key,
+		"last_failure": bson.M{"$lt": quietSince},
+		"locked_until": bson.M{"$not": bson.M{"$gt": now}},
+	}
+	if _, err := r.throttles.DeleteOne(ctx, stale); err != nil {
+		return throttle, err
+	}
+
+	update := bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"last_failure": now}}
+	findOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := r.throttles.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, findOptions).Decode(&throttle)
+	return throttle, err
+}
+
+func (r *mongoThrottles) Lock(ctx context.Context, key string, event models.LockoutEvent) error {
+	if _, err := r.throttles.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"locked_until": event.Locked_until}}); err != nil {
+		return err
+	}
+	_, err := r.lockouts.InsertOne(ctx, event)
+	return err
+}
+
+func (r *mongoThrottles) Clear(ctx context.Context, key string) (int64, error) {
+	return deleteMany(ctx, r.throttles, bson.M{"_id": key})
+}
+
+func (r *mongoThrottles) ListLockouts(ctx context.Context, scope string, skip int64, limit int64) ([]models.LockoutEvent, error) {
+	filter := bson.M{}
+	if scope != "" {
+		filter["scope"] = scope
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: -1}})
+	findOptions.SetSkip(skip)
+	findOptions.SetLimit(limit)
+
+	var lockouts []models.LockoutEvent
+	err := findAll(ctx, r.lockouts, filter, &lockouts, findOptions)
+	return lockouts, err
+}
+
+func (r *mongoThrottles) DeleteLockouts(ctx context.Context, scope string, subject string) (int64, error) {
+	return deleteMany(ctx, r.lockouts, bson.M{"scope": scope, "subject": subject})
+}
+
+type mongoOIDCStates struct {
+	states *mongo.Collection
+}
+
+func (r *mongoOIDCStates) Create(ctx context.Context, state models.OIDCState) error {
+	_, err := r.states.InsertOne(ctx, state)
+	return err
+}
+
+func (r *mongoOIDCStates) Take(ctx context.Context, state string, provider string, now time.Time) (models.OIDCState, error) {
+	var taken models.OIDCState
+	filter := bson.M{"_id": state, "provider": provider, "expires_at": bson.M{"$gt": now}}
+	err := r.states.FindOneAndDelete(ctx, filter).Decode(&taken)
+	if err == mongo.ErrNoDocuments {
+		return taken, ErrNotFound
+	}
+	return taken, err
+}
+
+type mongoPosts struct {
+	posts *mongo.Collection
+}
+
+// matchVisible matches the documents selected by filter and drops those whose
+// user_id belongs to a banned user. The author is looked up by _id, so the
+// cost does not grow with the number of banned users.
+func matchVisible(filter bson.D) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "user"},
+			{Key: "let", Value: bson.D{{Key: "user_id", Value: "$user_id"}}},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{
+					{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$_id", "$$user_id"}}}},
+					{Key: "banned", Value: true},
+				}}},
+				{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "banned_author"},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "banned_author", Value: bson.D{{Key: "$size", Value: 0}}}}}},
+		{{Key: "$project", Value: bson.D{{Key: "banned_author", Value: 0}}}},
+	}
+}
+
+// visibleLookup joins the documents of a collection that belong to a post,
+// leaving out those written by hidden or banned users or hidden by a
+// moderator.
+func visibleLookup(from string, as string, hidden []primitive.ObjectID) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: from},
+		{Key: "let", Value: bson.D{{Key: "post_id", Value: "$_id"}}},
+		{Key: "pipeline", Value: matchVisible(bson.D{
+			{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$post_id", "$$post_id"}}}},
+			{Key: "user_id", Value: bson.D{{Key: "$nin", Value: hidden}}},
+			{Key: "hidden", Value: bson.D{{Key: "$ne", Value: true}}},
+		})},
+		{Key: "as", Value: as},
+	}}}
+}
+
+func (r *mongoPosts) Create(ctx context.Context, post models.Post) error {
+	_, err := r.posts.InsertOne(ctx, post)
+	return err
+}
+
+func (r *mongoPosts) FindByID(ctx context.Context, id primitive.ObjectID) (models.Post, error) {
+	var post models.Post
+	err := findOne(ctx, r.posts, bson.M{"_id": id}, &post)
+	return post, err
+}
+
+func (r *mongoPosts) FindVisible(ctx context.Context, id primitive.ObjectID, hidden []primitive.ObjectID) (bson.M, error) {
+	pipeline := append(matchVisible(bson.D{{Key: "_id", Value: id}, {Key: "user_id", Value: bson.D{{Key: "$nin", Value: hidden}}}, {Key: "hidden", Value: bson.D{{Key: "$ne", Value: true}}}}), mongo.Pipeline{
+		visibleLookup("comment", "comments", hidden),
+		visibleLookup("like", "likes", hidden),
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "total_comments", Value: bson.D{{Key: "$size", Value: "$comments"}}},
+			{Key: "total_likes", Value: bson.D{{Key: "$size", Value: "$likes"}}},
+		}}},
+	}...)
+
+	cursor, err := r.posts.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var posts []bson.M
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, ErrNotFound
+	}
+	return posts[0], nil
+}
+
+func (r *mongoPosts) ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]bson.M, error) {
+	pipeline := append(matchVisible(bson.D{{Key: "user_id", Value: bson.D{{Key: "$nin", Value: hidden}}}, {Key: "hidden", Value: bson.D{{Key: "$ne", Value: true}}}}), mongo.Pipeline{
+		visibleLookup("comment", "comments", hidden),
+		visibleLookup("like", "likes", hidden),
+		{{Key: "$project", Value: bson.D{
+			{Key: "name", Value: 1},
+			{Key: "description", Value: 1},
+			{Key: "created_at", Value: 1},
+			{Key: "updated_at", Value: 1},
+			{Key: "user_id", Value: 1},
+			{Key: "like_count", Value: bson.D{{Key: "$size", Value: "$likes"}}},
+			{Key: "comment_count", Value: bson.D{{Key: "$size", Value: "$comments"}}},
+		}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+	}...)
+
+	cursor, err := r.posts.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var posts []bson.M
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (r *mongoPosts) Update(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID, name *string, description *string, updatedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":        name,
+			"description": description,
+			"updated_at":  updatedAt,
+		},
+	}
+	result, err := r.posts.UpdateOne(ctx, ownedBy(id, owner), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoPosts) Delete(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID) error {
+	result, err := r.posts.DeleteOne(ctx, ownedBy(id, owner))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoPosts) Hide(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.posts.UpdateByID(ctx, id, bson.M{"$set": bson.M{"hidden": true, "updated_at": at}})
+	return err
+}
+
+func (r *mongoPosts) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Post, error) {
+	var posts []models.Post
+	err := findAll(ctx, r.posts, bson.M{"user_id": userID}, &posts, oldestFirst)
+	return posts, err
+}
+
+func (r *mongoPosts) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.posts, bson.M{"user_id": userID})
+}
+
+type mongoComments struct {
+	comments *mongo.Collection
+}
+
+func (r *mongoComments) Create(ctx context.Context, comment models.Comment) error {
+	_, err := r.comments.InsertOne(ctx, comment)
+	return err
+}
+
+func (r *mongoComments) FindByID(ctx context.Context, id primitive.ObjectID) (models.Comment, error) {
+	var comment models.Comment
+	err := findOne(ctx, r.comments, bson.M{"_id": id}, &comment)
+	return comment, err
+}
+
+func (r *mongoComments) FindVisible(ctx context.Context, id primitive.ObjectID) (models.Comment, error) {
+	var comment models.Comment
+	err := findOne(ctx, r.comments, bson.M{"_id": id, "hidden": bson.M{"$ne": true}}, &comment)
+	return comment, err
+}
+
+func (r *mongoComments) ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]models.Comment, error) {
+	pipeline := append(matchVisible(bson.D{{Key: "user_id", Value: bson.D{{Key: "$nin", Value: hidden}}}, {Key: "hidden", Value: bson.D{{Key: "$ne", Value: true}}}}), pageStages(skip, limit)...)
+
+	cursor, err := r.comments.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var comments []models.Comment
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *mongoComments) Update(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID, description *string, updatedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"description": description,
+			"updated_at":  updatedAt,
+		},
+	}
+	result, err := r.comments.UpdateOne(ctx, ownedBy(id, owner), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoComments) Delete(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID) error {
+	result, err := r.comments.DeleteOne(ctx, ownedBy(id, owner))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoComments) Hide(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.comments.UpdateByID(ctx, id, bson.M{"$set": bson.M{"hidden": true, "updated_at": at}})
+	return err
+}
+
+func (r *mongoComments) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := findAll(ctx, r.comments, bson.M{"user_id": userID}, &comments, oldestFirst)
+	return comments, err
+}
+
+func (r *mongoComments) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.comments, bson.M{"user_id": userID})
+}
+
+func (r *mongoComments) DeleteOnPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.comments, bson.M{"post_id": bson.M{"$in": postIDs}})
+}
+
+type mongoLikes struct {
+	likes *mongo.Collection
+}
+
+func (r *mongoLikes) Create(ctx context.Context, like models.Like) error {
+	_, err := r.likes.InsertOne(ctx, like)
+	return err
+}
+
+func (r *mongoLikes) FindByID(ctx context.Context, id primitive.ObjectID) (models.Like, error) {
+	var like models.Like
+	err := findOne(ctx, r.likes, bson.M{"_id": id}, &like)
+	return like, err
+}
+
+func (r *mongoLikes) ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]models.Like, error) {
+	pipeline := append(matchVisible(bson.D{{Key: "user_id", Value: bson.D{{Key: "$nin", Value: hidden}}}}), pageStages(skip, limit)...)
+
+	cursor, err := r.likes.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var likes []models.Like
+	if err = cursor.All(ctx, &likes); err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+func (r *mongoLikes) Delete(ctx context.Context, id primitive.ObjectID, owner primitive.ObjectID) error {
+	result, err := r.likes.DeleteOne(ctx, ownedBy(id, &owner))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoLikes) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Like, error) {
+	var likes []models.Like
+	err := findAll(ctx, r.likes, bson.M{"user_id": userID}, &likes, oldestFirst)
+	return likes, err
+}
+
+func (r *mongoLikes) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.likes, bson.M{"user_id": userID})
+}
+
+func (r *mongoLikes) DeleteOnPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.likes, bson.M{"post_id": bson.M{"$in": postIDs}})
+}
+
+type mongoFollows struct {
+	follows *mongo.Collection
+}
+
+func (r *mongoFollows) Create(ctx context.Context, follow models.Follow) error {
+	_, err := r.follows.InsertOne(ctx, follow)
+	return err
+}
+
+func (r *mongoFollows) Exists(ctx context.Context, follower primitive.ObjectID, following primitive.ObjectID) (bool, error) {
+	return exists(ctx, r.follows, bson.M{"follower_id": follower, "following_id": following})
+}
+
+func (r *mongoFollows) Delete(ctx context.Context, id primitive.ObjectID, follower primitive.ObjectID) error {
+	return deleteOne(ctx, r.follows, bson.M{"_id": id, "follower_id": follower})
+}
+
+func (r *mongoFollows) List(ctx context.Context, follower *primitive.ObjectID, following *primitive.ObjectID, skip int64, limit int64) ([]models.Follow, error) {
+	filter := bson.M{}
+	if follower != nil {
+		filter["follower_id"] = *follower
+	}
+	if following != nil {
+		filter["following_id"] = *following
+	}
+	findOptions := pageOf(skip, limit, false).SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	var follows []models.Follow
+	err := findAll(ctx, r.follows, filter, &follows, findOptions)
+	return follows, err
+}
+
+func (r *mongoFollows) DeleteBetween(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.follows, bson.M{"$or": []bson.M{
+		{"follower_id": a, "following_id": b},
+		{"follower_id": b, "following_id": a},
+	}})
+}
+
+func (r *mongoFollows) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.follows, bson.M{"$or": []bson.M{{"follower_id": userID}, {"following_id": userID}}})
+}
+
+type mongoBlocks struct {
+	blocks *mongo.Collection
+}
+
+func (r *mongoBlocks) Create(ctx context.Context, block models.Block) error {
+	_, err := r.blocks.InsertOne(ctx, block)
+	return err
+}
+
+func (r *mongoBlocks) Exists(ctx context.Context, blocker primitive.ObjectID, blocked primitive.ObjectID) (bool, error) {
+	return exists(ctx, r.blocks, bson.M{"blocker_id": blocker, "blocked_id": blocked})
+}
+
+func (r *mongoBlocks) Delete(ctx context.Context, id primitive.ObjectID, blocker primitive.ObjectID) error {
+	return deleteOne(ctx, r.blocks, bson.M{"_id": id, "blocker_id": blocker})
+}
+
+func (r *mongoBlocks) ListByBlocker(ctx context.Context, blocker primitive.ObjectID, skip int64, limit int64) ([]models.Block, error) {
+	var blocks []models.Block
+	err := findAll(ctx, r.blocks, bson.M{"blocker_id": blocker}, &blocks, pageOf(skip, limit, false))
+	return blocks, err
+}
+
+func (r *mongoBlocks) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.blocks, bson.M{"$or": []bson.M{{"blocker_id": userID}, {"blocked_id": userID}}})
+}
+
+type mongoMutes struct {
+	mutes *mongo.Collection
+}
+
+func (r *mongoMutes) Create(ctx context.Context, mute models.Mute) error {
+	_, err := r.mutes.InsertOne(ctx, mute)
+	return err
+}
+
+func (r *mongoMutes) Exists(ctx context.Context, muter primitive.ObjectID, muted primitive.ObjectID) (bool, error) {
+	return exists(ctx, r.mutes, bson.M{"muter_id": muter, "muted_id": muted})
+}
+
+func (r *mongoMutes) Delete(ctx context.Context, id primitive.ObjectID, muter primitive.ObjectID) error {
+	return deleteOne(ctx, r.mutes, bson.M{"_id": id, "muter_id": muter})
+}
+
+func (r *mongoMutes) ListByMuter(ctx context.Context, muter primitive.ObjectID, skip int64, limit int64) ([]models.Mute, error) {
+	var mutes []models.Mute
+	err := findAll(ctx, r.mutes, bson.M{"muter_id": muter}, &mutes, pageOf(skip, limit, false))
+	return mutes, err
+}
+
+func (r *mongoMutes) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.mutes, bson.M{"$or": []bson.M{{"muter_id": userID}, {"muted_id": userID}}})
+}
+
+type mongoConversations struct {
+	conversations *mongo.Collection
+}
+
+func (r *mongoConversations) Create(ctx context.Context, conversation models.Conversation) error {
+	_, err := r.conversations.InsertOne(ctx, conversation)
+	return err
+}
+
+func (r *mongoConversations) FindForParticipant(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (models.Conversation, error) {
+	var conversation models.Conversation
+	err := findOne(ctx, r.conversations, bson.M{"_id": id, "participants": userID}, &conversation)
+	return conversation, err
+}
+
+func (r *mongoConversations) FindDirect(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (models.Conversation, error) {
+	var conversation models.Conversation
+	filter := bson.M{"is_group": false, "participants": bson.M{"$all": []primitive.ObjectID{a, b}, "$size": 2}}
+	err := findOne(ctx, r.conversations, filter, &conversation)
+	return conversation, err
+}
+
+func (r *mongoConversations) ListByParticipant(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.Conversation, error) {
+	findOptions := pageOf(skip, limit, false).SetSort(bson.D{{Key: "last_message_at", Value: -1}})
+
+	var conversations []models.Conversation
+	err := findAll(ctx, r.conversations, bson.M{"participants": userID}, &conversations, findOptions)
+	return conversations, err
+}
+
+func (r *mongoConversations) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.conversations.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_message_at": at, "updated_at": at}})
+	return err
+}
+
+func (r *mongoConversations) RemoveParticipant(ctx context.Context, userID primitive.ObjectID, at time.Time) (int64, error) {
+	update := bson.M{"$pull": bson.M{"participants": userID}, "$set": bson.M{"updated_at": at}}
+	return updateMany(ctx, r.conversations, bson.M{"participants": userID}, update)
+}
+
+type mongoMessages struct {
+	messages *mongo.Collection
+}
+
+func (r *mongoMessages) Create(ctx context.Context, message models.Message) error {
+	_, err := r.messages.InsertOne(ctx, message)
+	return err
+}
+
+func (r *mongoMessages) List(ctx context.Context, conversationID primitive.ObjectID, before *primitive.ObjectID, limit int64) ([]models.Message, error) {
+	filter := bson.M{"conversation_id": conversationID}
+	if before != nil {
+		filter["_id"] = bson.M{"$lt": *before}
+	}
+
+	var messages []models.Message
+	err := findAll(ctx, r.messages, filter, &messages, pageOf(0, limit, true))
+	return messages, err
+}
+
+func (r *mongoMessages) CountUnread(ctx context.Context, conversationIDs []primitive.ObjectID, reader primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "conversation_id", Value: bson.D{{Key: "$in", Value: conversationIDs}}},
+			{Key: "sender_id", Value: bson.D{{Key: "$ne", Value: reader}}},
+			{Key: "read_by", Value: bson.D{{Key: "$ne", Value: reader}}},
+		}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$conversation_id"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+	}
+	cursor, err := r.messages.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var unread []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	if err = cursor.All(ctx, &unread); err != nil {
+		return nil, err
+	}
+	counts := map[primitive.ObjectID]int{}
+	for _, u := range unread {
+		counts[u.ID] = u.Count
+	}
+	return counts, nil
+}
+
+func (r *mongoMessages) Delete(ctx context.Context, id primitive.ObjectID, conversationID primitive.ObjectID, sender primitive.ObjectID) error {
+	return deleteOne(ctx, r.messages, bson.M{"_id": id, "conversation_id": conversationID, "sender_id": sender})
+}
+
+func (r *mongoMessages) MarkRead(ctx context.Context, conversationID primitive.ObjectID, reader primitive.ObjectID) error {
+	filter := bson.M{"conversation_id": conversationID, "read_by": bson.M{"$ne": reader}}
+	_, err := r.messages.UpdateMany(ctx, filter, bson.M{"$addToSet": bson.M{"read_by": reader}})
+	return err
+}
+
+func (r *mongoMessages) ListBySender(ctx context.Context, sender primitive.ObjectID) ([]models.Message, error) {
+	var messages []models.Message
+	err := findAll(ctx, r.messages, bson.M{"sender_id": sender}, &messages, oldestFirst)
+	return messages, err
+}
+
+func (r *mongoMessages) ListByConversations(ctx context.Context, conversationIDs []primitive.ObjectID) ([]models.Message, error) {
+	var messages []models.Message
+	if len(conversationIDs) == 0 {
+		return messages, nil
+	}
+	err := findAll(ctx, r.messages, bson.M{"conversation_id": bson.M{"$in": conversationIDs}}, &messages, oldestFirst)
+	return messages, err
+}
+
+func (r *mongoMessages) Anonymize(ctx context.Context, sender primitive.ObjectID, body string, at time.Time) (int64, error) {
+	filter := bson.M{"sender_id": sender, "body": bson.M{"$ne": body}}
+	return updateMany(ctx, r.messages, filter, bson.M{"$set": bson.M{"body": body, "updated_at": at}})
+}
+
+func (r *mongoMessages) RemoveReader(ctx context.Context, reader primitive.ObjectID) (int64, error) {
+	return updateMany(ctx, r.messages, bson.M{"read_by": reader}, bson.M{"$pull": bson.M{"read_by": reader}})
+}
+
+type mongoNotifications struct {
+	notifications *mongo.Collection
+}
+
+func (r *mongoNotifications) Create(ctx context.Context, notification models.Notification) error {
+	_, err := r.notifications.InsertOne(ctx, notification)
+	return err
+}
+
+func (r *mongoNotifications) List(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, skip int64, limit int64) ([]models.Notification, error) {
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read"] = false
+	}
+
+	var notifications []models.Notification
+	err := findAll(ctx, r.notifications, filter, &notifications, pageOf(skip, limit, true))
+	return notifications, err
+}
+
+func (r *mongoNotifications) MarkRead(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error {
+	return updateOne(ctx, r.notifications, bson.M{"_id": id, "user_id": userID}, bson.M{"$set": bson.M{"read": true, "updated_at": at}})
+}
+
+func (r *mongoNotifications) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.notifications, bson.M{"user_id": userID})
+}
+
+type mongoReports struct {
+	reports *mongo.Collection
+	actions *mongo.Collection
+}
+
+func (r *mongoReports) Create(ctx context.Context, report models.Report) error {
+	_, err := r.reports.InsertOne(ctx, report)
+	return err
+}
+
+func (r *mongoReports) HasOpen(ctx context.Context, reporter primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"reporter_id": reporter, "target_type": targetType, "target_id": targetID, "status": models.ReportStatusOpen}
+	return exists(ctx, r.reports, filter)
+}
+
+func (r *mongoReports) ListOpen(ctx context.Context, targetType string, targetID primitive.ObjectID) ([]models.Report, error) {
+	var reports []models.Report
+	filter := bson.M{"target_type": targetType, "target_id": targetID, "status": models.ReportStatusOpen}
+	err := findAll(ctx, r.reports, filter, &reports)
+	return reports, err
+}
+
+func (r *mongoReports) ListByReporter(ctx context.Context, reporter primitive.ObjectID) ([]models.Report, error) {
+	var reports []models.Report
+	err := findAll(ctx, r.reports, bson.M{"reporter_id": reporter}, &reports, oldestFirst)
+	return reports, err
+}
+
+func (r *mongoReports) Queue(ctx context.Context, targetType string, skip int64, limit int64) ([]models.ReportGroup, error) {
+	match := bson.D{{Key: "status", Value: models.ReportStatusOpen}}
+	if targetType != "" {
+		match = append(match, bson.E{Key: "target_type", Value: targetType})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "target_type", Value: "$target_type"}, {Key: "target_id", Value: "$target_id"}}},
+			{Key: "report_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "reasons", Value: bson.D{{Key: "$addToSet", Value: "$reason"}}},
+			{Key: "report_ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "first_reported_at", Value: bson.D{{Key: "$min", Value: "$created_at"}}},
+			{Key: "last_reported_at", Value: bson.D{{Key: "$max", Value: "$created_at"}}},
+		}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "target_type", Value: "$_id.target_type"},
+			{Key: "target_id", Value: "$_id.target_id"},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "report_count", Value: -1}, {Key: "first_reported_at", Value: 1}}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.reports.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []models.ReportGroup
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *mongoReports) RecordAction(ctx context.Context, action models.ModerationAction) error {
+	_, err := r.actions.InsertOne(ctx, action)
+	return err
+}
+
+func (r *mongoReports) Decide(ctx context.Context, ids []primitive.ObjectID, status string, action string, moderator primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"status":       status,
+		"action":       action,
+		"moderator_id": moderator,
+		"decided_at":   at,
+		"updated_at":   at,
+	}}
+	_, err := r.reports.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update)
+	return err
+}
+
+func (r *mongoReports) AnonymizeReporter(ctx context.Context, reporter primitive.ObjectID, at time.Time) (int64, error) {
+	update := bson.M{"$set": bson.M{"reporter_id": primitive.NilObjectID, "updated_at": at}}
+	return updateMany(ctx, r.reports, bson.M{"reporter_id": reporter}, update)
+}
+
+type mongoSanctions struct {
+	sanctions *mongo.Collection
+}
+
+func (r *mongoSanctions) Create(ctx context.Context, sanction models.Sanction) error {
+	_, err := r.sanctions.InsertOne(ctx, sanction)
+	return err
+}
+
+func (r *mongoSanctions) LiftAll(ctx context.Context, userID primitive.ObjectID, liftedBy primitive.ObjectID, reason string, at time.Time) error {
+	filter := bson.M{"user_id": userID, "lifted_at": nil}
+	lift := bson.M{"$set": bson.M{"lifted_at": at, "lifted_by": liftedBy, "lifted_reason": reason, "updated_at": at}}
+	_, err := r.sanctions.UpdateMany(ctx, filter, lift)
+	return err
+}
+
+func (r *mongoSanctions) ListByUser(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.Sanction, error) {
+	var sanctions []models.Sanction
+	err := findAll(ctx, r.sanctions, bson.M{"user_id": userID}, &sanctions, pageOf(skip, limit, true))
+	return sanctions, err
+}
+
+type mongoDeletions struct {
+	deletions *mongo.Collection
+}
+
+func (r *mongoDeletions) Create(ctx context.Context, deletion models.AccountDeletion) error {
+	_, err := r.deletions.InsertOne(ctx, deletion)
+	return err
+}
+
+func (r *mongoDeletions) MarkRestored(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"user_id": userID, "status": models.DeletionStatusScheduled}
+	update := bson.M{"$set": bson.M{"status": models.DeletionStatusRestored, "restored_at": at, "updated_at": at}}
+	_, err := r.deletions.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (r *mongoDeletions) List(ctx context.Context, status string, skip int64, limit int64) ([]models.AccountDeletion, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	var deletions []models.AccountDeletion
+	err := findAll(ctx, r.deletions, filter, &deletions, pageOf(skip, limit, true))
+	return deletions, err
+}
+
+func (r *mongoDeletions) Complete(ctx context.Context, deletion models.AccountDeletion) error {
+	filter := bson.M{"user_id": deletion.User_ID, "status": models.DeletionStatusScheduled}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.DeletionStatusCompleted,
+			"completed_at": deletion.Completed_at,
+			"removed":      deletion.Removed,
+			"anonymized":   deletion.Anonymized,
+			"updated_at":   deletion.Updated_at,
+		},
+		"$setOnInsert": bson.M{
+			"_id":           deletion.ID,
+			"requested_at":  deletion.Requested_at,
+			"scheduled_for": deletion.Scheduled_for,
+			"created_at":    deletion.Created_at,
+		},
+	}
+	_, err := r.deletions.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+type mongoExports struct {
+	exports *mongo.Collection
+	db      *mongo.Database
+}
+
+// bucket returns the GridFS bucket the finished ZIP files are stored in,
+// honouring the deadline of ctx.
+func (r *mongoExports) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(r.db, options.GridFSBucket().SetName("data_export_file"))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+func (r *mongoExports) Create(ctx context.Context, export models.DataExport) error {
+	_, err := r.exports.InsertOne(ctx, export)
+	return err
+}
+
+func (r *mongoExports) FindActive(ctx context.Context, userID primitive.ObjectID) (models.DataExport, error) {
+	var export models.DataExport
+	filter := bson.M{"user_id": userID, "status": bson.M{"$in": []string{models.ExportStatusPending, models.ExportStatusRunning}}}
+	err := findOne(ctx, r.exports, filter, &export)
+	return export, err
+}
+
+func (r *mongoExports) FindByID(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (models.DataExport, error) {
+	var export models.DataExport
+	err := findOne(ctx, r.exports, bson.M{"_id": id, "user_id": userID}, &export)
+	return export, err
+}
+
+func (r *mongoExports) Claim(ctx context.Context, now time.Time, claimTTL time.Duration) (models.DataExport, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"status": models.ExportStatusPending},
+		{"status": models.ExportStatusRunning, "started_at": bson.M{"$lt": now.Add(-claimTTL)}},
+	}}
+	claim := bson.M{"$set": bson.M{"status": models.ExportStatusRunning, "started_at": now, "updated_at": now}}
+
+	var export models.DataExport
+	err := r.exports.FindOneAndUpdate(ctx, filter, claim).Decode(&export)
+	if err == mongo.ErrNoDocuments {
+		return export, ErrNotFound
+	}
+	return export, err
+}
+
+func (r *mongoExports) Release(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	release := bson.M{"$set": bson.M{"status": models.ExportStatusPending, "updated_at": at}, "$unset": bson.M{"started_at": ""}}
+	_, err := r.exports.UpdateOne(ctx, bson.M{"_id": id}, release)
+	return err
+}
+
+func (r *mongoExports) Finish(ctx context.Context, export models.DataExport) error {
+	update := bson.M{
+		"status":       export.Status,
+		"completed_at": export.Completed_at,
+		"updated_at":   export.Updated_at,
+	}
+	if export.Status == models.ExportStatusReady {
+		update["file_id"] = export.File_ID
+		update["size"] = export.Size
+		update["expires_at"] = export.Expires_at
+	} else {
+		update["error"] = export.Error
+	}
+	_, err := r.exports.UpdateOne(ctx, bson.M{"_id": export.ID}, bson.M{"$set": update})
+	return err
+}
+
+func (r *mongoExports) ListExpired(ctx context.Context, now time.Time) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := findAll(ctx, r.exports, bson.M{"status": models.ExportStatusReady, "expires_at": bson.M{"$lte": now}}, &exports)
+	return exports, err
+}
+
+func (r *mongoExports) MarkExpired(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.ExportStatusExpired, "updated_at": at},
+		"$unset": bson.M{"file_id": ""},
+	}
+	_, err := r.exports.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *mongoExports) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := findAll(ctx, r.exports, bson.M{"user_id": userID}, &exports)
+	return exports, err
+}
+
+func (r *mongoExports) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return deleteMany(ctx, r.exports, bson.M{"user_id": userID})
+}
+
+func (r *mongoExports) SaveFile(ctx context.Context, name string, source io.Reader) (primitive.ObjectID, error) {
+	bucket, err := r.bucket(ctx)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return bucket.UploadFromStream(name, source)
+}
+
+func (r *mongoExports) WriteFile(ctx context.Context, id primitive.ObjectID, w io.Writer) (int64, error) {
+	bucket, err := r.bucket(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := bucket.DownloadToStream(id, w)
+	if err == gridfs.ErrFileNotFound {
+		return n, ErrNotFound
+	}
+	return n, err
+}
+
+func (r *mongoExports) DeleteFile(ctx context.Context, id primitive.ObjectID) error {
+	bucket, err := r.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	err = bucket.Delete(id)
+	if err == gridfs.ErrFileNotFound {
+		return nil
+	}
+	return err
+}
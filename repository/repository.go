@@ -0,0 +1,405 @@
+// Package repository hides how users and everything they create are stored
+// from the handlers that serve them. Handlers are given the interfaces
+// below; NewMongoRepositories backs them with MongoDB and NewMemory keeps
+// everything in memory for tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"social-media-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned when no document matches, or when the caller may
+// not touch the one that does.
+var ErrNotFound = errors.New("not found")
+
+// DuplicateError is returned when a write would give a user the email or
+// phone number of another user.
+type DuplicateError struct {
+	// Field is email, phone, or account when the clash cannot be told apart.
+	Field string
+}
+
+func (e *DuplicateError) Error() string {
+	return "duplicate " + e.Field
+}
+
+// Repositories bundles the repositories the handlers need.
+type Repositories struct {
+	Users         UserRepository
+	Sessions      SessionRepository
+	APIKeys       APIKeyRepository
+	Throttles     ThrottleRepository
+	OIDCStates    OIDCStateRepository
+	Posts         PostRepository
+	Comments      CommentRepository
+	Likes         LikeRepository
+	Follows       FollowRepository
+	Blocks        BlockRepository
+	Mutes         MuteRepository
+	Conversations ConversationRepository
+	Messages      MessageRepository
+	Notifications NotificationRepository
+	Reports       ReportRepository
+	Sanctions     SanctionRepository
+	Deletions     DeletionRepository
+	Exports       ExportRepository
+}
+
+// UserChange is a partial update of a user. Set maps field names, as
+// stored, to their new values; Unset lists fields to remove.
+type UserChange struct {
+	Set   bson.M
+	Unset []string
+}
+
+// UserRepository stores users and answers questions about the blocks and
+// mutes between them. Users are looked up by their user_id unless a method
+// says otherwise.
+type UserRepository interface {
+	FindByID(ctx context.Context, userID string) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	FindByPhone(ctx context.Context, phone string) (models.User, error)
+	// FindByIdentity returns the user an OpenID Connect identity is linked to.
+	FindByIdentity(ctx context.Context, provider string, subject string) (models.User, error)
+	// FindByIDs returns the users among ids that exist, in no particular order.
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error)
+	// Create stores a new user, returning a *DuplicateError when the email
+	// or phone number is taken.
+	Create(ctx context.Context, user models.User) error
+	// Update applies change to the user if every field in match still has
+	// the given value, where nil also matches a missing field. It returns
+	// ErrNotFound when nothing matched and a *DuplicateError when the
+	// change would take another user's email.
+	Update(ctx context.Context, userID string, match bson.M, change UserChange) error
+	AddIdentity(ctx context.Context, userID string, identity models.Identity) error
+	// UseTOTPStep records that the TOTP code of step was used, reporting
+	// false when it or a later one already was.
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	// UseRecoveryCode removes a recovery code by its hash, reporting false
+	// when the user does not have it.
+	UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error)
+	// RevokeCredentials invalidates every token issued to the user so far
+	// and revokes every session and API key.
+	RevokeCredentials(ctx context.Context, userID string) error
+	// ClaimDeletion marks one user whose deletion is due as being deleted
+	// and returns it. Claims older than claimTTL are taken over, and
+	// ErrNotFound means no deletion is due.
+	ClaimDeletion(ctx context.Context, now time.Time, claimTTL time.Duration) (models.User, error)
+	// Delete removes a user by _id.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// HiddenUserIDs returns the users whose content must not be shown to
+	// viewer. Blocked users are always hidden; muted users only when
+	// includeMuted is set. A nil viewer is anonymous and hides nobody.
+	// Banned users are not listed: the Visible queries leave them out.
+	HiddenUserIDs(ctx context.Context, viewer *primitive.ObjectID, includeMuted bool) ([]primitive.ObjectID, error)
+	// IsBlocked reports whether either user has blocked the other.
+	IsBlocked(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (bool, error)
+}
+
+// SessionRepository stores logged in devices. A session is active until it
+// is revoked or expires.
+type SessionRepository interface {
+	Create(ctx context.Context, session models.Session) error
+	FindActive(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, now time.Time) (models.Session, error)
+	// ListActive returns a page of the user's active sessions, most
+	// recently seen first.
+	ListActive(ctx context.Context, userID primitive.ObjectID, now time.Time, skip int64, limit int64) ([]models.Session, error)
+	// Rotate replaces the refresh token hash of a session, returning
+	// ErrNotFound when the stored hash is no longer oldHash.
+	Rotate(ctx context.Context, id primitive.ObjectID, oldHash string, newHash string, userAgent string, ip string, now time.Time, expiresAt time.Time) error
+	// Touch records that a session was seen at from ip.
+	Touch(ctx context.Context, id primitive.ObjectID, ip string, at time.Time) error
+	// Revoke signs out one of the user's active sessions.
+	Revoke(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error
+	// ListByUser returns every session of the user, oldest first, revoked
+	// and expired ones included.
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// APIKeyRepository stores API keys, which are looked up by the hash of the
+// key.
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey models.APIKey) error
+	FindByHash(ctx context.Context, hash string) (models.APIKey, error)
+	// ListByUser returns a page of the user's keys, newest first, revoked
+	// and expired ones included.
+	ListByUser(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.APIKey, error)
+	// Revoke revokes one of the user's keys that is not revoked yet.
+	Revoke(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error
+	// Touch records that a key was used at.
+	Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// ThrottleRepository counts failed attempts per key and records the
+// lockouts they cause.
+type ThrottleRepository interface {
+	// FindLocked returns the throttles among keys that are locked at now.
+	FindLocked(ctx context.Context, keys []string, now time.Time) ([]models.LoginThrottle, error)
+	// CountFailure counts a failure against key at now and returns the
+	// counter. A counter without failures since quietSince that is not
+	// locked starts over.
+	CountFailure(ctx context.Context, key string, now time.Time, quietSince time.Time) (models.LoginThrottle, error)
+	// Lock locks key until the event's Locked_until and records the event.
+	Lock(ctx context.Context, key string, event models.LockoutEvent) error
+	Clear(ctx context.Context, key string) (int64, error)
+	// ListLockouts returns a page of lockout events, newest first, of one
+	// scope or of every scope when scope is empty.
+	ListLockouts(ctx context.Context, scope string, skip int64, limit int64) ([]models.LockoutEvent, error)
+	DeleteLockouts(ctx context.Context, scope string, subject string) (int64, error)
+}
+
+// OIDCStateRepository stores the OpenID Connect logins that were started
+// and not completed yet.
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state models.OIDCState) error
+	// Take removes and returns the unexpired state of a provider's login,
+	// so each login request completes once.
+	Take(ctx context.Context, state string, provider string, now time.Time) (models.OIDCState, error)
+}
+
+// PostRepository stores posts. An owner of nil matches any owner, which is
+// how moderators edit other users' posts.
+type PostRepository interface {
+	Create(ctx context.Context, post models.Post) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Post, error)
+	// FindVisible returns a post that is not hidden and not written by a
+	// hidden or banned user, with its visible comments and likes and their
+	// totals.
+	FindVisible(ctx context.Context, id primitive.ObjectID, hidden []primitive.ObjectID) (bson.M, error)
+	// ListVisible returns a page of the feed with like and comment counts,
+	// leaving out hidden posts and those of hidden or banned users.
+	ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]bson.M, error)
+	Update(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID, name *string, description *string, updatedAt time.Time) error
+	Delete(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID) error
+	// Hide hides a post from everyone but moderators. Hiding a post that
+	// does not exist is not an error.
+	Hide(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// ListByUser returns every post of a user, oldest first.
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Post, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// CommentRepository stores comments. An owner of nil matches any owner.
+type CommentRepository interface {
+	Create(ctx context.Context, comment models.Comment) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Comment, error)
+	// FindVisible returns a comment unless a moderator hid it.
+	FindVisible(ctx context.Context, id primitive.ObjectID) (models.Comment, error)
+	// ListVisible returns a page of the comments that are not hidden and
+	// not written by hidden or banned users.
+	ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]models.Comment, error)
+	Update(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID, description *string, updatedAt time.Time) error
+	Delete(ctx context.Context, id primitive.ObjectID, owner *primitive.ObjectID) error
+	// Hide hides a comment from everyone but moderators. Hiding a comment
+	// that does not exist is not an error.
+	Hide(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// ListByUser returns every comment of a user, oldest first.
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Comment, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// DeleteOnPosts removes every comment on the posts.
+	DeleteOnPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+// LikeRepository stores likes. Only their owner can remove them.
+type LikeRepository interface {
+	Create(ctx context.Context, like models.Like) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Like, error)
+	// ListVisible returns a page of the likes of users that are neither
+	// hidden nor banned.
+	ListVisible(ctx context.Context, hidden []primitive.ObjectID, skip int64, limit int64) ([]models.Like, error)
+	Delete(ctx context.Context, id primitive.ObjectID, owner primitive.ObjectID) error
+	// ListByUser returns every like of a user, oldest first.
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Like, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// DeleteOnPosts removes every like of the posts.
+	DeleteOnPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+// FollowRepository stores who follows whom.
+type FollowRepository interface {
+	Create(ctx context.Context, follow models.Follow) error
+	// Exists reports whether follower follows following.
+	Exists(ctx context.Context, follower primitive.ObjectID, following primitive.ObjectID) (bool, error)
+	// Delete removes one of follower's follows.
+	Delete(ctx context.Context, id primitive.ObjectID, follower primitive.ObjectID) error
+	// List returns a page of follows, oldest first, made by follower and
+	// of following. A nil follower or following matches any user, and a
+	// limit of 0 returns every follow.
+	List(ctx context.Context, follower *primitive.ObjectID, following *primitive.ObjectID, skip int64, limit int64) ([]models.Follow, error)
+	// DeleteBetween removes the follows between two users, both ways.
+	DeleteBetween(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (int64, error)
+	// DeleteByUser removes the follows the user made or received.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// BlockRepository stores blocks. UserRepository answers whether two users
+// blocked each other.
+type BlockRepository interface {
+	Create(ctx context.Context, block models.Block) error
+	// Exists reports whether blocker blocked blocked.
+	Exists(ctx context.Context, blocker primitive.ObjectID, blocked primitive.ObjectID) (bool, error)
+	// Delete removes one of blocker's blocks.
+	Delete(ctx context.Context, id primitive.ObjectID, blocker primitive.ObjectID) error
+	// ListByBlocker returns a page of the blocks blocker made. A limit of 0
+	// returns every block.
+	ListByBlocker(ctx context.Context, blocker primitive.ObjectID, skip int64, limit int64) ([]models.Block, error)
+	// DeleteByUser removes the blocks the user made or received.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// MuteRepository stores mutes.
+type MuteRepository interface {
+	Create(ctx context.Context, mute models.Mute) error
+	// Exists reports whether muter muted muted.
+	Exists(ctx context.Context, muter primitive.ObjectID, muted primitive.ObjectID) (bool, error)
+	// Delete removes one of muter's mutes.
+	Delete(ctx context.Context, id primitive.ObjectID, muter primitive.ObjectID) error
+	// ListByMuter returns a page of the mutes muter made. A limit of 0
+	// returns every mute.
+	ListByMuter(ctx context.Context, muter primitive.ObjectID, skip int64, limit int64) ([]models.Mute, error)
+	// DeleteByUser removes the mutes the user made or received.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// ConversationRepository stores conversations. Users only see the ones
+// they take part in.
+type ConversationRepository interface {
+	Create(ctx context.Context, conversation models.Conversation) error
+	// FindForParticipant returns a conversation the user takes part in.
+	FindForParticipant(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (models.Conversation, error)
+	// FindDirect returns the one-to-one conversation between two users.
+	FindDirect(ctx context.Context, a primitive.ObjectID, b primitive.ObjectID) (models.Conversation, error)
+	// ListByParticipant returns a page of the user's conversations, most
+	// recently active first. A limit of 0 returns every conversation.
+	ListByParticipant(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.Conversation, error)
+	// Touch records a message sent to a conversation at.
+	Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// RemoveParticipant takes the user out of every conversation and
+	// returns how many there were.
+	RemoveParticipant(ctx context.Context, userID primitive.ObjectID, at time.Time) (int64, error)
+}
+
+// MessageRepository stores the messages of conversations.
+type MessageRepository interface {
+	Create(ctx context.Context, message models.Message) error
+	// List returns up to limit messages of a conversation, newest first,
+	// sent before the message before unless it is nil.
+	List(ctx context.Context, conversationID primitive.ObjectID, before *primitive.ObjectID, limit int64) ([]models.Message, error)
+	// CountUnread counts, per conversation, the messages others sent that
+	// reader has not read.
+	CountUnread(ctx context.Context, conversationIDs []primitive.ObjectID, reader primitive.ObjectID) (map[primitive.ObjectID]int, error)
+	// Delete removes one of sender's messages in a conversation.
+	Delete(ctx context.Context, id primitive.ObjectID, conversationID primitive.ObjectID, sender primitive.ObjectID) error
+	// MarkRead records that reader read every message of a conversation.
+	MarkRead(ctx context.Context, conversationID primitive.ObjectID, reader primitive.ObjectID) error
+	// ListBySender returns every message the user sent, oldest first.
+	ListBySender(ctx context.Context, sender primitive.ObjectID) ([]models.Message, error)
+	// ListByConversations returns every message of the conversations,
+	// oldest first.
+	ListByConversations(ctx context.Context, conversationIDs []primitive.ObjectID) ([]models.Message, error)
+	// Anonymize replaces the body of every message sender sent and
+	// returns how many changed.
+	Anonymize(ctx context.Context, sender primitive.ObjectID, body string, at time.Time) (int64, error)
+	// RemoveReader removes the user's read receipts and returns how many
+	// messages had one.
+	RemoveReader(ctx context.Context, reader primitive.ObjectID) (int64, error)
+}
+
+// NotificationRepository stores the notifications shown to users.
+type NotificationRepository interface {
+	Create(ctx context.Context, notification models.Notification) error
+	// List returns a page of the user's notifications, newest first, or
+	// only the unread ones when unreadOnly is set.
+	List(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, skip int64, limit int64) ([]models.Notification, error)
+	// MarkRead marks one of the user's notifications as read.
+	MarkRead(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, at time.Time) error
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// ReportRepository stores reports about posts, comments and users, and
+// the moderation actions that decide them.
+type ReportRepository interface {
+	Create(ctx context.Context, report models.Report) error
+	// HasOpen reports whether reporter has an open report about a target.
+	HasOpen(ctx context.Context, reporter primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error)
+	// ListOpen returns the open reports about a target.
+	ListOpen(ctx context.Context, targetType string, targetID primitive.ObjectID) ([]models.Report, error)
+	// ListByReporter returns every report the user made, oldest first.
+	ListByReporter(ctx context.Context, reporter primitive.ObjectID) ([]models.Report, error)
+	// Queue returns a page of the open reports grouped per target, most
+	// reported first, of one target type or of every type when targetType
+	// is empty.
+	Queue(ctx context.Context, targetType string, skip int64, limit int64) ([]models.ReportGroup, error)
+	// RecordAction stores a moderation decision.
+	RecordAction(ctx context.Context, action models.ModerationAction) error
+	// Decide closes the reports with status and the moderator's action.
+	Decide(ctx context.Context, ids []primitive.ObjectID, status string, action string, moderator primitive.ObjectID, at time.Time) error
+	// AnonymizeReporter removes the user from the reports they made and
+	// returns how many there were.
+	AnonymizeReporter(ctx context.Context, reporter primitive.ObjectID, at time.Time) (int64, error)
+}
+
+// SanctionRepository stores the suspensions and bans issued to users.
+type SanctionRepository interface {
+	Create(ctx context.Context, sanction models.Sanction) error
+	// LiftAll lifts every sanction of the user that is not lifted yet.
+	LiftAll(ctx context.Context, userID primitive.ObjectID, liftedBy primitive.ObjectID, reason string, at time.Time) error
+	// ListByUser returns a page of the user's sanctions, newest first.
+	ListByUser(ctx context.Context, userID primitive.ObjectID, skip int64, limit int64) ([]models.Sanction, error)
+}
+
+// DeletionRepository stores the audit records of account deletions, which
+// outlive the accounts.
+type DeletionRepository interface {
+	Create(ctx context.Context, deletion models.AccountDeletion) error
+	// MarkRestored marks the user's scheduled deletions as restored.
+	MarkRestored(ctx context.Context, userID primitive.ObjectID, at time.Time) error
+	// List returns a page of deletions, newest first, with one status or
+	// with any when status is empty.
+	List(ctx context.Context, status string, skip int64, limit int64) ([]models.AccountDeletion, error)
+	// Complete records a finished deletion on the user's scheduled record,
+	// or stores deletion when there is none.
+	Complete(ctx context.Context, deletion models.AccountDeletion) error
+}
+
+// ExportRepository stores data export requests and the ZIP files they
+// produce.
+type ExportRepository interface {
+	Create(ctx context.Context, export models.DataExport) error
+	// FindActive returns the user's export that is pending or running.
+	FindActive(ctx context.Context, userID primitive.ObjectID) (models.DataExport, error)
+	FindByID(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (models.DataExport, error)
+	// Claim marks one pending export as running at now and returns it.
+	// Exports running for longer than claimTTL are taken over, and
+	// ErrNotFound means none is waiting.
+	Claim(ctx context.Context, now time.Time, claimTTL time.Duration) (models.DataExport, error)
+	// Release hands a running export back so it is built again.
+	Release(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// Finish stores the outcome of a build: the status, error, file,
+	// size, expiry and completion time of export.
+	Finish(ctx context.Context, export models.DataExport) error
+	// ListExpired returns the ready exports that expired at now.
+	ListExpired(ctx context.Context, now time.Time) ([]models.DataExport, error)
+	// MarkExpired marks an export as expired and forgets its file.
+	MarkExpired(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// ListByUser returns every export of a user.
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.DataExport, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// SaveFile stores the contents of r as a file and returns its ID.
+	SaveFile(ctx context.Context, name string, r io.Reader) (primitive.ObjectID, error)
+	// WriteFile copies a stored file to w.
+	WriteFile(ctx context.Context, id primitive.ObjectID, w io.Writer) (int64, error)
+	// DeleteFile removes a stored file. Removing a missing file is not an
+	// error.
+	DeleteFile(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	admin := controller.NewAdminHandler(repos)
+	incomingRoutes.PUT("/admin/users/:id/role", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.SetUserRole())
+	incomingRoutes.PUT("/admin/users/:id/2fa-required", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.SetTwoFactorRequired())
+	incomingRoutes.POST("/admin/users/:id/sanctions", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.CreateSanction())
+	incomingRoutes.DELETE("/admin/users/:id/sanctions", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.LiftSanctions())
+	incomingRoutes.GET("/admin/users/:id/sanctions", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.GetSanctionList())
+	incomingRoutes.GET("/admin/lockouts", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.GetLockoutList())
+	incomingRoutes.GET("/admin/account-deletions", auth.Authentication(), middleware.RequireRole(models.RoleAdmin), admin.GetAccountDeletionList())
+}
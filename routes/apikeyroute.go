@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func APIKeyRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	apiKeys := controller.NewAPIKeyHandler(repos)
+	incomingRoutes.POST("/users/me/api-keys", auth.Authentication(), apiKeys.CreateAPIKey())
+	incomingRoutes.GET("/users/me/api-keys", auth.Authentication(), apiKeys.GetAPIKeyList())
+	incomingRoutes.DELETE("/users/me/api-keys/:id", auth.Authentication(), apiKeys.RevokeAPIKey())
+}
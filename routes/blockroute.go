@@ -0,0 +1,21 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func BlockRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	blocks := controller.NewBlockHandler(repos)
+	mutes := controller.NewMuteHandler(repos)
+	incomingRoutes.POST("/blocks", auth.Authentication(), blocks.CreateBlock())
+	incomingRoutes.GET("/blocks", auth.Authentication(), blocks.GetBlockList())
+	incomingRoutes.DELETE("/blocks/:id", auth.Authentication(), blocks.DeleteBlock())
+	incomingRoutes.POST("/mutes", auth.Authentication(), mutes.CreateMute())
+	incomingRoutes.GET("/mutes", auth.Authentication(), mutes.GetMuteList())
+	incomingRoutes.DELETE("/mutes/:id", auth.Authentication(), mutes.DeleteMute())
+}
@@ -3,14 +3,18 @@ package routes
 import (
 	controller "social-media-api/controllers"
 	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CommentRoutes(incomingRoutes *gin.Engine) {
-    incomingRoutes.POST("/comments", middleware.Authentication(), controller.CreateComment())
-	incomingRoutes.GET("/comments/:id", middleware.Authentication(), controller.GetCommentByID())
-	incomingRoutes.GET("/comments", middleware.Authentication(), controller.GetCommentList())
-    incomingRoutes.PUT("/comments/:id", middleware.Authentication(), controller.UpdateComment())
-	incomingRoutes.DELETE("/comments/:id", middleware.Authentication(), controller.DeleteComment())
+func CommentRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	comments := controller.NewCommentHandler(repos)
+    incomingRoutes.POST("/comments", auth.Authentication(models.ScopeWriteComments), middleware.RequireVerifiedEmail("comment"), comments.CreateComment())
+	incomingRoutes.GET("/comments/:id", auth.Authentication(models.ScopeReadPosts), comments.GetCommentByID())
+	incomingRoutes.GET("/comments", auth.Authentication(models.ScopeReadPosts), comments.GetCommentList())
+    incomingRoutes.PUT("/comments/:id", auth.Authentication(models.ScopeWriteComments), comments.UpdateComment())
+	incomingRoutes.DELETE("/comments/:id", auth.Authentication(models.ScopeWriteComments), comments.DeleteComment())
 }
\ No newline at end of file
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ConversationRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	conversations := controller.NewConversationHandler(repos)
+	incomingRoutes.POST("/conversations", auth.Authentication(models.ScopeWriteMessages), middleware.RequireVerifiedEmail("message"), conversations.CreateConversation())
+	incomingRoutes.GET("/conversations", auth.Authentication(models.ScopeReadMessages), conversations.GetConversationList())
+	incomingRoutes.PUT("/conversations/settings", auth.Authentication(), conversations.UpdateMessageSettings())
+	incomingRoutes.GET("/conversations/:id/messages", auth.Authentication(models.ScopeReadMessages), conversations.GetMessageList())
+	incomingRoutes.POST("/conversations/:id/messages", auth.Authentication(models.ScopeWriteMessages), middleware.RequireVerifiedEmail("message"), conversations.CreateMessage())
+	incomingRoutes.DELETE("/conversations/:id/messages/:messageId", auth.Authentication(models.ScopeWriteMessages), conversations.DeleteMessage())
+	incomingRoutes.POST("/conversations/:id/read", auth.Authentication(models.ScopeReadMessages), conversations.MarkConversationRead())
+}
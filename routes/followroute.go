@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func FollowRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	follows := controller.NewFollowHandler(repos)
+	incomingRoutes.POST("/follows", auth.Authentication(models.ScopeWriteFollows), middleware.RequireVerifiedEmail("follow"), follows.CreateFollow())
+	incomingRoutes.GET("/follows", auth.Authentication(models.ScopeReadFollows), follows.GetFollowList())
+	incomingRoutes.DELETE("/follows/:id", auth.Authentication(models.ScopeWriteFollows), follows.DeleteFollow())
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/healthz", controller.Liveness())
+	incomingRoutes.GET("/readyz", controller.Readiness())
+}
@@ -3,13 +3,17 @@ package routes
 import (
 	controller "social-media-api/controllers"
 	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func LikeRoutes(incomingRoutes *gin.Engine) {
-    incomingRoutes.POST("/likes", middleware.Authentication(), controller.CreateLike())
-    incomingRoutes.GET("/likes/:id", middleware.Authentication(), controller.GetLikeByID())
-	incomingRoutes.DELETE("/likes/:id", middleware.Authentication(), controller.DeleteLike())
-	incomingRoutes.GET("/likes", middleware.Authentication(), controller.GetLikeList())
+func LikeRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	likes := controller.NewLikeHandler(repos)
+    incomingRoutes.POST("/likes", auth.Authentication(models.ScopeWriteLikes), middleware.RequireVerifiedEmail("like"), likes.CreateLike())
+    incomingRoutes.GET("/likes/:id", auth.Authentication(models.ScopeReadPosts), likes.GetLikeByID())
+	incomingRoutes.DELETE("/likes/:id", auth.Authentication(models.ScopeWriteLikes), likes.DeleteLike())
+	incomingRoutes.GET("/likes", auth.Authentication(models.ScopeReadPosts), likes.GetLikeList())
 }
\ No newline at end of file
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func MetricsRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
@@ -3,14 +3,18 @@ package routes
 import (
 	controller "social-media-api/controllers"
 	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func PostRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/posts/:id", controller.GetPostByID())
-	incomingRoutes.GET("/posts", controller.ListPosts())
-	incomingRoutes.POST("/posts" ,middleware.Authentication(), controller.CreatePost())
-    incomingRoutes.PUT("/posts/:id" ,middleware.Authentication(), controller.UpdatePost())
-	incomingRoutes.DELETE("/posts/:id" ,middleware.Authentication(), controller.DeletePost())
+func PostRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	posts := controller.NewPostHandler(repos)
+	incomingRoutes.GET("/posts/:id", auth.OptionalAuthentication(models.ScopeReadPosts), posts.GetPostByID())
+	incomingRoutes.GET("/posts", auth.OptionalAuthentication(models.ScopeReadPosts), posts.ListPosts())
+	incomingRoutes.POST("/posts" ,auth.Authentication(models.ScopeWritePosts), middleware.RequireVerifiedEmail("post"), posts.CreatePost())
+    incomingRoutes.PUT("/posts/:id" ,auth.Authentication(models.ScopeWritePosts), posts.UpdatePost())
+	incomingRoutes.DELETE("/posts/:id" ,auth.Authentication(models.ScopeWritePosts), posts.DeletePost())
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/models"
+	"social-media-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(incomingRoutes *gin.Engine, repos repository.Repositories) {
+	auth := middleware.NewAuth(repos)
+	reports := controller.NewReportHandler(repos)
+	notifications := controller.NewNotificationHandler(repos)
+	incomingRoutes.POST("/reports", auth.Authentication(), middleware.RequireVerifiedEmail("report"), reports.CreateReport())
+	incomingRoutes.GET("/moderation/reports", auth.Authentication(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), reports.GetReportQueue())
+	incomingRoutes.POST("/moderation/reports/decision", auth.Authentication(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), reports.DecideReports())
+	incomingRoutes.GET("/notifications", auth.Authentication(models.ScopeReadNotifications), notifications.GetNotificationList())
+	incomingRoutes.PUT("/notifications/:id/read", auth.Authentication(models.ScopeReadNotifications), notifications.MarkNotificationRead())
+}
@@ -2,11 +2,38 @@ package routes
 
 import (
 	controller "social-media-api/controllers"
+	middleware "social-media-api/middleware"
+	"social-media-api/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(incomingRoutes *gin.Engine){
-    incomingRoutes.POST("/users/signup", controller.SignUp())
-    incomingRoutes.POST("/users/login", controller.Login())
+func AuthRoutes(incomingRoutes *gin.Engine, repos repository.Repositories){
+    auth := middleware.NewAuth(repos)
+    accounts := controller.NewAccountHandler(repos)
+    exports := controller.NewExportHandler(repos)
+    incomingRoutes.POST("/users/signup", accounts.SignUp())
+    incomingRoutes.POST("/users/login", accounts.Login())
+    incomingRoutes.POST("/users/login/2fa", accounts.LoginTwoFactor())
+    incomingRoutes.POST("/users/token/refresh", accounts.RefreshToken())
+    incomingRoutes.GET("/auth/oidc/:provider/login", accounts.OIDCLogin())
+    incomingRoutes.GET("/auth/oidc/:provider/callback", accounts.OIDCCallback())
+    incomingRoutes.POST("/users/verify-email", accounts.VerifyEmail())
+    incomingRoutes.POST("/users/verify-email/resend", auth.Authentication(), accounts.ResendVerificationEmail())
+    incomingRoutes.POST("/users/password/forgot", accounts.ForgotPassword())
+    incomingRoutes.POST("/users/password/reset", accounts.ResetPassword())
+    incomingRoutes.PUT("/users/me/password", auth.Authentication(), accounts.ChangePassword())
+    incomingRoutes.PUT("/users/me/email", auth.Authentication(), accounts.ChangeEmail())
+    incomingRoutes.POST("/users/me/email/confirm", accounts.ConfirmEmailChange())
+    incomingRoutes.POST("/users/me/2fa/setup", auth.Authentication(), accounts.SetupTwoFactor())
+    incomingRoutes.POST("/users/me/2fa/enable", auth.Authentication(), accounts.EnableTwoFactor())
+    incomingRoutes.POST("/users/me/2fa/recovery-codes", auth.Authentication(), accounts.RegenerateRecoveryCodes())
+    incomingRoutes.DELETE("/users/me/2fa", auth.Authentication(), accounts.DisableTwoFactor())
+    incomingRoutes.DELETE("/users/me", auth.Authentication(), accounts.DeleteAccount())
+    incomingRoutes.POST("/users/restore", accounts.RestoreAccount())
+    incomingRoutes.POST("/users/me/exports", auth.Authentication(), exports.RequestDataExport())
+    incomingRoutes.GET("/users/me/exports/:id", auth.Authentication(), exports.GetDataExport())
+    incomingRoutes.GET("/users/exports/download", exports.DownloadDataExport())
+    incomingRoutes.GET("/users/me/sessions", auth.Authentication(), accounts.GetSessionList())
+    incomingRoutes.DELETE("/users/me/sessions/:id", auth.Authentication(), accounts.RevokeSession())
 }
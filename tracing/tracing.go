@@ -0,0 +1,85 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are started for
+// every HTTP request and every MongoDB command, and trace context is read
+// from and passed on in W3C traceparent headers.
+//
+// Spans are exported with OTLP over HTTP when OTEL_TRACES_EXPORTER is
+// "otlp" or an OTLP endpoint is configured; the exporter itself reads the
+// standard OTEL_EXPORTER_OTLP_* variables (endpoint, headers, timeout,
+// compression). OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES describe
+// the service and OTEL_TRACES_SAMPLER picks the sampler. Without an
+// exporter spans are still created, so trace IDs reach the logs.
+//
+// To look at spans in process, install a provider with a span recorder
+// from go.opentelemetry.io/otel/sdk/trace/tracetest:
+//
+//	recorder := tracetest.NewSpanRecorder()
+//	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+//	// serve a request, then inspect recorder.Ended()
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer the API's spans come from.
+const InstrumentationName = "social-media-api"
+
+// Tracer returns the tracer of the API. It follows the global provider,
+// so a provider installed later is used too.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// exportEnabled reports whether the environment asks for OTLP export.
+func exportEnabled() bool {
+	switch os.Getenv("OTEL_TRACES_EXPORTER") {
+	case "otlp":
+		return true
+	case "none":
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// function flushes buffered spans and must be called before exiting.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(InstrumentationName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the defaults
+	fromEnv, err := resource.New(ctx, resource.WithFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	if res, err = resource.Merge(res, fromEnv); err != nil {
+		return nil, err
+	}
+
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if exportEnabled() {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}